@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// Let the janitor adjust the cache's size limit between min and max items to
+// the workload, instead of using a fixed CacheSize. After each run, the limit
+// is raised by a tenth while doing so improves the hit ratio, and lowered
+// otherwise, until lowering it costs hits: memory is only spent where it pays
+// off. If
+// maxHeap is not 0 and the Go heap (runtime.MemStats.HeapAlloc) is larger
+// than maxHeap bytes, the limit is lowered regardless. CacheSize, if set, is
+// the initial limit; otherwise it is max. Requires a CleanupInterval.
+//
+// The current limit is returned by EffectiveCacheSize.
+func AdaptiveSize(min, max int, maxHeap uint64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.SizeMin = min
+		m.SizeMax = max
+		m.SizeMaxHeap = maxHeap
+		return nil
+	}
+}
+
+// Returns the number of bytes allocated on the Go heap; replaced by tests.
+var heapAlloc = func() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// The smallest change in the hit ratio between two runs of the janitor that
+// is considered an improvement.
+const sizeRatioEpsilon = 0.005
+
+// A sizer adjusts the size limit of a cache by hill climbing on its hit ratio.
+// It is only used by the janitor goroutine, except for size.
+type sizer struct {
+	size   int64 // accessed atomically
+	grow   bool
+	hits   uint64
+	misses uint64
+	ratio  float64
+}
+
+func newSizer(o *CacheOptions) *sizer {
+	size := o.SizeMax
+	if o.CacheSize > 0 {
+		size = clampSize(o.CacheSize, o.SizeMin, o.SizeMax)
+	}
+	return &sizer{size: int64(size), grow: true}
+}
+
+func clampSize(n, min, max int) int {
+	if n < min {
+		return min
+	} else if n > max {
+		return max
+	}
+	return n
+}
+
+// Returns the current size limit of the cache: the limit chosen by
+// AdaptiveSize if it is used, otherwise CacheSize.
+func (c *cache) EffectiveCacheSize() int {
+	if c.sizer != nil {
+		return int(atomic.LoadInt64(&c.sizer.size))
+	}
+	return c.CacheSize
+}
+
+// Choose a new size limit based on the hit ratio since the last call.
+func (s *sizer) adjust(c *cache) {
+	hits, misses := atomic.LoadUint64(&c.stats.hits), atomic.LoadUint64(&c.stats.misses)
+	dh, dm := hits-s.hits, misses-s.misses
+	s.hits, s.misses = hits, misses
+	cur := int(atomic.LoadInt64(&s.size))
+	next := cur
+	step := cur / 10
+	if step < 1 {
+		step = 1
+	}
+	if c.SizeMaxHeap > 0 && heapAlloc() > c.SizeMaxHeap {
+		next = cur - step
+		s.grow = false
+	} else if dh+dm > 0 {
+		ratio := float64(dh) / float64(dh+dm)
+		if s.grow {
+			// Keep growing only while it pays off.
+			s.grow = ratio >= s.ratio+sizeRatioEpsilon
+		} else {
+			// Keep shrinking until it costs hits.
+			s.grow = ratio < s.ratio-sizeRatioEpsilon
+		}
+		s.ratio = ratio
+		switch {
+		case !s.grow:
+			next = cur - step
+		case c.itemCount() >= cur:
+			// A cache that is not full would not benefit from a higher
+			// limit.
+			next = cur + step
+		}
+	}
+	next = clampSize(next, c.SizeMin, c.SizeMax)
+	if next != cur {
+		atomic.StoreInt64(&s.size, int64(next))
+		c.debug("cache: size limit changed", "size", next, "hit_ratio", s.ratio)
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestAdaptiveSize(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(50), AdaptiveSize(10, 100, 0))
+	for i := 0; i < 50; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	period := func(hits, misses int) int {
+		for i := 0; i < hits; i++ {
+			tc.Get("0")
+		}
+		for i := 0; i < misses; i++ {
+			tc.Get("missing")
+		}
+		tc.sizer.adjust(tc.cache)
+		return tc.EffectiveCacheSize()
+	}
+	for i, step := range []struct {
+		hits, misses, want int
+	}{
+		{9, 1, 55}, // the hit ratio improved: grow
+		{9, 1, 50}, // growing didn't help: shrink
+		{9, 1, 45}, // shrinking didn't hurt: keep shrinking
+		{5, 5, 49}, // shrinking hurt: grow again
+	} {
+		if n := period(step.hits, step.misses); n != step.want {
+			t.Errorf("size after period %d is %d; want %d", i+1, n, step.want)
+		}
+	}
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 49 {
+		t.Errorf("DeleteLRU left %d items; want the effective size 49", n)
+	}
+}
+
+func TestAdaptiveSizeMemoryPressure(t *testing.T) {
+	defer func(f func() uint64) { heapAlloc = f }(heapAlloc)
+	heapAlloc = func() uint64 { return 1 << 30 }
+	tc := New(Expiration(DefaultExpiration), AdaptiveSize(10, 100, 1<<20))
+	for i := 0; i < 100; i++ {
+		tc.Get("missing")
+		tc.sizer.adjust(tc.cache)
+	}
+	if n := tc.EffectiveCacheSize(); n != 10 {
+		t.Errorf("size under memory pressure is %d; want the minimum 10", n)
+	}
+	if _, err := NewWithError(AdaptiveSize(10, 5, 0)); err == nil {
+		t.Error("AdaptiveSize with min > max accepted")
+	}
+}
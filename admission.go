@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+// An Admitter decides whether items that were added to a cache that is over
+// its size limit are worth keeping at the expense of existing items. Without
+// one, new items are always kept, and a single scan over many keys that are
+// never used again can evict every frequently used item in the cache.
+type Admitter interface {
+	// Record a request for k. This is called on every Get, whether or not
+	// k is found.
+	Record(k string)
+	// Reports whether candidate, an item that has not been retrieved since
+	// it was set, should be kept in the cache at the expense of victim, the
+	// item the eviction policy would otherwise remove.
+	Admit(candidate, victim string) bool
+}
+
+// Set the admission policy consulted when the cache is over its size limit.
+// By default, every new item is admitted.
+func AdmissionPolicy(a Admitter) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Admission = a
+		return nil
+	}
+}
+
+// Reorder candidates, which have already been sorted by the eviction policy,
+// so that newly added items (those with no hits) that a loses to are evicted
+// before the existing items they would otherwise have replaced.
+func admit(a Admitter, candidates []Candidate) {
+	var residents, newcomers []Candidate
+	for _, v := range candidates {
+		if v.Item.Hits == 0 {
+			newcomers = append(newcomers, v)
+		} else {
+			residents = append(residents, v)
+		}
+	}
+	if len(residents) == 0 || len(newcomers) == 0 {
+		return
+	}
+	var (
+		out      = candidates[:0]
+		admitted []Candidate
+		i, j     int
+	)
+	for ; i < len(residents) && j < len(newcomers); j++ {
+		if a.Admit(newcomers[j].Key, residents[i].Key) {
+			out = append(out, residents[i])
+			admitted = append(admitted, newcomers[j])
+			i++
+		} else {
+			out = append(out, newcomers[j])
+		}
+	}
+	out = append(out, residents[i:]...)
+	out = append(out, newcomers[j:]...)
+	out = append(out, admitted...)
+}
+
+const (
+	sketchDepth   = 4
+	sketchMaxFreq = 15
+)
+
+// A TinyLFU admission policy. Request frequencies are estimated with a
+// count-min sketch of small saturating counters, which are halved
+// periodically so that the sketch favors recent popularity.
+type tinyLFU struct {
+	mu        sync.Mutex
+	seed      maphash.Seed
+	counters  []uint8
+	width     uint64
+	additions int
+	resetAt   int
+}
+
+// Returns a TinyLFU admission policy which tracks the request frequencies of
+// about size distinct keys. A new item is only admitted if it has been
+// requested more often, recently, than the item that would be evicted to make
+// room for it. size should be at least the cache's size limit; larger values
+// use more memory but make the frequency estimates more accurate.
+func TinyLFU(size int) Admitter {
+	if size < 16 {
+		size = 16
+	}
+	width := uint64(1)
+	for width < uint64(size) {
+		width <<= 1
+	}
+	return &tinyLFU{
+		seed:     maphash.MakeSeed(),
+		counters: make([]uint8, sketchDepth*width),
+		width:    width,
+		resetAt:  10 * size,
+	}
+}
+
+// Returns a copy of the sketch for a clone of the cache (see Clone), which
+// then counts its requests separately.
+func (t *tinyLFU) copyState() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &tinyLFU{
+		seed:      t.seed,
+		counters:  append([]uint8(nil), t.counters...),
+		width:     t.width,
+		additions: t.additions,
+		resetAt:   t.resetAt,
+	}
+}
+
+// Returns the index of k's counter in each row of the sketch.
+func (t *tinyLFU) indexes(k string) [sketchDepth]uint64 {
+	var (
+		h   = maphash.String(t.seed, k)
+		h1  = h & 0xffffffff
+		h2  = h >> 32
+		res [sketchDepth]uint64
+	)
+	for i := range res {
+		res[i] = uint64(i)*t.width + (h1+uint64(i)*h2)&(t.width-1)
+	}
+	return res
+}
+
+func (t *tinyLFU) Record(k string) {
+	idx := t.indexes(k)
+	t.mu.Lock()
+	for _, i := range idx {
+		if t.counters[i] < sketchMaxFreq {
+			t.counters[i]++
+		}
+	}
+	t.additions++
+	if t.additions >= t.resetAt {
+		for i := range t.counters {
+			t.counters[i] /= 2
+		}
+		t.additions /= 2
+	}
+	t.mu.Unlock()
+}
+
+// Returns the estimated number of recent requests for k. Must be called with
+// t.mu held.
+func (t *tinyLFU) estimate(k string) uint8 {
+	min := uint8(sketchMaxFreq)
+	for _, i := range t.indexes(k) {
+		if t.counters[i] < min {
+			min = t.counters[i]
+		}
+	}
+	return min
+}
+
+func (t *tinyLFU) Admit(candidate, victim string) bool {
+	t.mu.Lock()
+	ok := t.estimate(candidate) > t.estimate(victim)
+	t.mu.Unlock()
+	return ok
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTinyLFUEstimate(t *testing.T) {
+	a := TinyLFU(100).(*tinyLFU)
+	for i := 0; i < 5; i++ {
+		a.Record("hot")
+	}
+	a.Record("cold")
+	if !a.Admit("hot", "cold") {
+		t.Error("hot was not admitted over cold")
+	}
+	if a.Admit("cold", "hot") {
+		t.Error("cold was admitted over hot")
+	}
+	if a.Admit("unseen", "cold") {
+		t.Error("unseen was admitted over cold")
+	}
+}
+
+func TestTinyLFUReset(t *testing.T) {
+	a := TinyLFU(16).(*tinyLFU)
+	for i := 0; i < 20; i++ {
+		a.Record("hot")
+	}
+	for i := 0; i < a.resetAt; i++ {
+		a.Record(strconv.Itoa(i))
+	}
+	a.mu.Lock()
+	n := a.estimate("hot")
+	a.mu.Unlock()
+	if n >= sketchMaxFreq {
+		t.Error("Counters were not halved:", n)
+	}
+}
+
+func TestAdmissionPolicy(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), AdmissionPolicy(TinyLFU(1000)))
+	for i := 0; i < 10; i++ {
+		k := "hot" + strconv.Itoa(i)
+		tc.Set(k, i, DefaultExpiration)
+		for j := 0; j < 3; j++ {
+			tc.Get(k)
+		}
+	}
+	// A scan over keys that are only ever requested once
+	for i := 0; i < 100; i++ {
+		k := "scan" + strconv.Itoa(i)
+		if _, found := tc.Get(k); !found {
+			tc.Set(k, i, DefaultExpiration)
+		}
+	}
+
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 10 {
+		t.Error("ItemCount is not 10:", n)
+	}
+	for i := 0; i < 10; i++ {
+		k := "hot" + strconv.Itoa(i)
+		if _, found := tc.Get(k); !found {
+			t.Error(k, "was evicted by the scan")
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Append suffix to the value of k, which must be a []byte or a string, like
+// Redis' APPEND. If k is not in the cache (or has expired), it is set to a
+// copy of suffix, as a []byte, with the expiration d; otherwise its expiration
+// is unchanged. Returns the length of the new value.
+//
+// Values are never modified in place, so slices returned by earlier calls to
+// Get remain valid. Concurrent calls to Append for the same key do not lose
+// data.
+func (c *cache) Append(k string, suffix []byte, d time.Duration) (int, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, found := c.getItem(k)
+	if !found || c.expired(v) {
+		c.set(k, append([]byte(nil), suffix...), d)
+		return len(suffix), nil
+	}
+	var n int
+	switch x := v.Object.(type) {
+	case []byte:
+		b := make([]byte, len(x)+len(suffix))
+		copy(b, x)
+		copy(b[len(x):], suffix)
+		v.Object, n = b, len(b)
+	case string:
+		s := x + string(suffix)
+		v.Object, n = s, len(s)
+	default:
+		return 0, fmt.Errorf("The value for %s is not a []byte or string", k)
+	}
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
+	}
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.notify(EventSet, k, v.Object)
+	return n, nil
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	if n, err := tc.Append("b", []byte("foo"), DefaultExpiration); err != nil || n != 3 {
+		t.Error("Append returned", n, err)
+	}
+	x, _ := tc.Get("b")
+	if n, err := tc.Append("b", []byte("bar"), DefaultExpiration); err != nil || n != 6 {
+		t.Error("Append returned", n, err)
+	}
+	if string(x.([]byte)) != "foo" {
+		t.Error("the earlier value was modified:", string(x.([]byte)))
+	}
+	if x, _ := tc.Get("b"); string(x.([]byte)) != "foobar" {
+		t.Error("b is", x)
+	}
+
+	tc.Set("s", "foo", DefaultExpiration)
+	tc.Append("s", []byte("bar"), DefaultExpiration)
+	if x, _ := tc.Get("s"); x != "foobar" {
+		t.Error("s is", x)
+	}
+
+	tc.Set("i", 1, DefaultExpiration)
+	if _, err := tc.Append("i", []byte("bar"), DefaultExpiration); err == nil {
+		t.Error("appending to an int did not fail")
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tc.Append("b", []byte("x"), DefaultExpiration)
+		}()
+	}
+	wg.Wait()
+	if x, _ := tc.Get("b"); len(x.([]byte)) != 50 {
+		t.Error("b has length", len(x.([]byte)), "instead of 50")
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// Keep a counting Bloom filter of the keys in the cache, sized for about n
+// keys with a false positive rate of about 1%, so that MayContain can reject
+// most keys that are not in the cache without looking them up. The filter
+// takes about 40 bytes per key, and is updated whenever an item is added or
+// deleted. With more than n keys, the false positive rate rises, but there
+// are no false negatives.
+func BloomFilter(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.BloomFilter = n
+		return nil
+	}
+}
+
+// The number of hash functions of the filter, which is optimal for a false
+// positive rate of 1%.
+const bloomHashes = 7
+
+// A counting Bloom filter. Counters are updated atomically; a counter that
+// underflows, which can only happen around a Flush, wraps around, so that
+// it errs towards false positives.
+type bloomFilter struct {
+	seed     maphash.Seed
+	counters []uint32
+}
+
+func newBloomFilter(n int) *bloomFilter {
+	// m = -n ln(p) / ln(2)^2 for a false positive rate p of 1%.
+	m := int(math.Ceil(float64(n) * 9.6))
+	if m < 64 {
+		m = 64
+	}
+	return &bloomFilter{
+		seed:     maphash.MakeSeed(),
+		counters: make([]uint32, m),
+	}
+}
+
+// Calls fn with the index of each of the counters of k.
+func (f *bloomFilter) each(k string, fn func(i int) bool) {
+	// Double hashing: h1 + i*h2 gives the indexes of all the hash
+	// functions from a single hash.
+	h := maphash.String(f.seed, k)
+	h1, h2 := h&0xffffffff, h>>32|1
+	m := uint64(len(f.counters))
+	for i := uint64(0); i < bloomHashes; i++ {
+		if !fn(int((h1 + i*h2) % m)) {
+			return
+		}
+	}
+}
+
+func (f *bloomFilter) add(k string) {
+	f.each(k, func(i int) bool {
+		atomic.AddUint32(&f.counters[i], 1)
+		return true
+	})
+}
+
+func (f *bloomFilter) remove(k string) {
+	f.each(k, func(i int) bool {
+		atomic.AddUint32(&f.counters[i], ^uint32(0))
+		return true
+	})
+}
+
+func (f *bloomFilter) mayContain(k string) bool {
+	found := true
+	f.each(k, func(i int) bool {
+		found = atomic.LoadUint32(&f.counters[i]) != 0
+		return found
+	})
+	return found
+}
+
+func (f *bloomFilter) reset() {
+	for i := range f.counters {
+		atomic.StoreUint32(&f.counters[i], 0)
+	}
+}
+
+// Reports whether k may be in the cache. If it returns false, k is definitely
+// not in the cache, except for a brief moment while it is being added
+// concurrently; if it returns true, k is probably in it, but may also have
+// been deleted or have expired. Without BloomFilter, MayContain always returns
+// true.
+//
+// Checking the filter is cheaper than a Get, and does not count as a miss,
+// which makes it suitable for rejecting lookups of keys that are very likely
+// absent.
+func (c *cache) MayContain(k string) bool {
+	if c.bloom == nil {
+		return true
+	}
+	return c.bloom.mayContain(k)
+}
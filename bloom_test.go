@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), BloomFilter(1000))
+	for i := 0; i < 1000; i++ {
+		tc.Set(fmt.Sprint("in", i), i, DefaultExpiration)
+	}
+	for i := 0; i < 1000; i++ {
+		if !tc.MayContain(fmt.Sprint("in", i)) {
+			t.Fatalf("false negative for in%d", i)
+		}
+	}
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if tc.MayContain(fmt.Sprint("out", i)) {
+			positives++
+		}
+	}
+	if positives > 300 {
+		t.Errorf("%d false positives out of 10000; want about 100", positives)
+	}
+
+	tc.Delete("in1")
+	tc.Set("in2", 2, DefaultExpiration)
+	if !tc.MayContain("in2") {
+		t.Error("replacing an item removed it from the filter")
+	}
+	tc.Flush()
+	if tc.MayContain("in2") {
+		t.Error("filter was not reset by Flush")
+	}
+
+	if !New(Expiration(DefaultExpiration)).MayContain("x") {
+		t.Error("MayContain without a filter returned false")
+	}
+}
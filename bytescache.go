@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// A BytesCache is a cache for []byte values that stores keys and values in a
+// few large chunks of memory instead of as individual objects, and indexes
+// them in a map holding no pointers. A big Cache holding millions of values
+// makes every garbage collection scan all of them; a BytesCache holding the
+// same data gives the collector almost nothing to scan.
+//
+// In exchange, a BytesCache is much simpler than a Cache: its total size is
+// fixed when it is created, and when it is full the oldest items are evicted,
+// a chunk at a time, regardless of how they have been used. Items whose keys
+// have the same 64-bit hash replace each other. A BytesCache is safe for
+// concurrent use.
+//
+// With the OffHeap option, the chunks are allocated outside the Go heap
+// altogether, and must be released with Close.
+type BytesCache struct {
+	mu         sync.RWMutex
+	seed       maphash.Seed
+	index      map[uint64]bytesEntry
+	chunks     [][]byte
+	gens       []uint64
+	chunkSize  int
+	cur        int
+	expiration time.Duration
+	clock      Clock
+	offHeap    bool
+	closed     bool
+}
+
+// The location of an item in the chunks of a BytesCache. The item is stale if
+// its chunk has been reused since, i.e. if gen is not the chunk's current
+// generation.
+type bytesEntry struct {
+	gen        uint64
+	expiration int64
+	chunk      uint32
+	off        uint32
+	klen       uint32
+	vlen       uint32
+}
+
+// The largest chunk a BytesCache stores its items in. Larger items are not
+// stored.
+const bytesChunkSize = 1 << 20
+
+// Returns a BytesCache holding up to about maxBytes bytes of keys and values,
+// in chunks of 1 MB (or a single chunk, if maxBytes is less.) Chunks are only
+// allocated when they are first needed. Of the options, only Expiration,
+// WithClock and OffHeap are used; the others are ignored.
+func NewBytesCache(maxBytes int, options ...CacheOption) (*BytesCache, error) {
+	opts := GetDefaultOptions()
+	for _, opt := range options {
+		if err := opt(opts); err != nil {
+			return nil, err
+		}
+	}
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("BytesCache size must be positive: %d", maxBytes)
+	}
+	size := bytesChunkSize
+	if maxBytes < size {
+		size = maxBytes
+	}
+	n := (maxBytes + size - 1) / size
+	return &BytesCache{
+		seed:       maphash.MakeSeed(),
+		index:      make(map[uint64]bytesEntry),
+		chunks:     make([][]byte, n),
+		gens:       make([]uint64, n),
+		chunkSize:  size,
+		expiration: opts.Expiration,
+		clock:      opts.Clock,
+		offHeap:    opts.OffHeap,
+	}, nil
+}
+
+// Allocate memory outside the Go heap (with mmap, where it is available) for
+// the data of a BytesCache or OffHeapCache, so that it is neither scanned nor
+// counted by the garbage collector. The cache must then be closed to release
+// the memory. Other caches ignore this option.
+func OffHeap(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.OffHeap = b
+		return nil
+	}
+}
+
+func (b *BytesCache) alloc() ([]byte, error) {
+	if b.offHeap {
+		c, err := mmap(b.chunkSize)
+		return c[:0], err
+	}
+	return make([]byte, 0, b.chunkSize), nil
+}
+
+func (b *BytesCache) now() int64 {
+	if b.clock != nil {
+		return b.clock.Now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+// Add an item to the cache, replacing any existing item. As with Cache.Set,
+// d may be DefaultExpiration or NoExpiration. The value is copied. Returns
+// false, leaving the cache unchanged, if the key and value together are
+// larger than a chunk, memory for them could not be allocated, or the cache
+// is closed.
+func (b *BytesCache) Set(k string, v []byte, d time.Duration) bool {
+	size := len(k) + len(v)
+	if size > b.chunkSize {
+		return false
+	}
+	if d == DefaultExpiration {
+		d = b.expiration
+	}
+	var e int64
+	if d > 0 {
+		e = b.now() + int64(d)
+	}
+	h := maphash.String(b.seed, k)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return false
+	}
+	c := b.chunks[b.cur]
+	if len(c)+size > b.chunkSize {
+		// Move on to the next chunk, evicting the items it holds.
+		b.cur = (b.cur + 1) % len(b.chunks)
+		b.gens[b.cur]++
+		c = b.chunks[b.cur][:0]
+	}
+	if c == nil {
+		var err error
+		if c, err = b.alloc(); err != nil {
+			return false
+		}
+	}
+	off := len(c)
+	c = append(c, k...)
+	c = append(c, v...)
+	b.chunks[b.cur] = c
+	b.index[h] = bytesEntry{
+		gen:        b.gens[b.cur],
+		expiration: e,
+		chunk:      uint32(b.cur),
+		off:        uint32(off),
+		klen:       uint32(len(k)),
+		vlen:       uint32(len(v)),
+	}
+	return true
+}
+
+// Returns the entry for k, if it is present, current and unexpired. Must be
+// called with b.mu held.
+func (b *BytesCache) entry(k string, h uint64) (bytesEntry, bool) {
+	e, found := b.index[h]
+	if !found || b.closed || e.gen != b.gens[e.chunk] {
+		return bytesEntry{}, false
+	}
+	if e.expiration > 0 && b.now() > e.expiration {
+		return bytesEntry{}, false
+	}
+	c := b.chunks[e.chunk]
+	if string(c[e.off:e.off+e.klen]) != k {
+		return bytesEntry{}, false
+	}
+	return e, true
+}
+
+// Get an item from the cache. Returns a copy of the value, and a bool
+// indicating whether the key was found.
+func (b *BytesCache) Get(k string) ([]byte, bool) {
+	return b.AppendGet(nil, k)
+}
+
+// Like Get, but appends the value to dst and returns the result, to avoid an
+// allocation if dst has enough room.
+func (b *BytesCache) AppendGet(dst []byte, k string) ([]byte, bool) {
+	h := maphash.String(b.seed, k)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	e, found := b.entry(k, h)
+	if !found {
+		return dst, false
+	}
+	start := e.off + e.klen
+	return append(dst, b.chunks[e.chunk][start:start+e.vlen]...), true
+}
+
+// Delete an item from the cache. Its space is reclaimed when its chunk is
+// reused.
+func (b *BytesCache) Delete(k string) {
+	h := maphash.String(b.seed, k)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, found := b.entry(k, h); found {
+		delete(b.index, h)
+	}
+}
+
+// Returns the number of items in the cache. This may include items that have
+// expired or been evicted but not yet been removed from the index.
+func (b *BytesCache) ItemCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.index)
+}
+
+// Delete all items from the cache, keeping the memory allocated for them.
+func (b *BytesCache) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.index = make(map[uint64]bytesEntry)
+	for i := range b.chunks {
+		if b.chunks[i] != nil {
+			b.chunks[i] = b.chunks[i][:0]
+		}
+		b.gens[i]++
+	}
+	b.cur = 0
+}
+
+// Release the memory of the cache. Afterwards, the cache is empty and Set
+// does nothing. Only needed with the OffHeap option, but safe to call in any
+// case.
+func (b *BytesCache) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.closed = true
+	b.index = nil
+	var err error
+	for i, c := range b.chunks {
+		if c != nil && b.offHeap {
+			if uerr := munmap(c); err == nil {
+				err = uerr
+			}
+		}
+		b.chunks[i] = nil
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBytesCache(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	b, err := NewBytesCache(1<<20, Expiration(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := []byte("bar")
+	b.Set("foo", v, DefaultExpiration)
+	v[0] = 'c'
+	if x, found := b.Get("foo"); !found || string(x) != "bar" {
+		t.Errorf("got %q, %v; want bar", x, found)
+	}
+	b.Set("foo", []byte("baz"), NoExpiration)
+	b.Set("qux", []byte("quux"), DefaultExpiration)
+	if x, found := b.AppendGet([]byte("x:"), "foo"); !found || string(x) != "x:baz" {
+		t.Errorf("got %q, %v; want x:baz", x, found)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, found := b.Get("qux"); found {
+		t.Error("expired item was found")
+	}
+	if _, found := b.Get("foo"); !found {
+		t.Error("item without expiration was not found")
+	}
+	b.Delete("foo")
+	if _, found := b.Get("foo"); found {
+		t.Error("deleted item was found")
+	}
+	if b.Set("big", make([]byte, 2<<20), DefaultExpiration) {
+		t.Error("item larger than a chunk was stored")
+	}
+	b.Set("a", []byte("1"), DefaultExpiration)
+	b.Flush()
+	if _, found := b.Get("a"); found || b.ItemCount() != 0 {
+		t.Error("item was found after Flush")
+	}
+}
+
+func TestBytesCacheEviction(t *testing.T) {
+	b, _ := NewBytesCache(3 << 20)
+	v := bytes.Repeat([]byte("x"), 1000)
+	// Fill the cache about twice over.
+	n := 6 << 10
+	for i := 0; i < n; i++ {
+		b.Set(strconv.Itoa(i), v, DefaultExpiration)
+	}
+	if _, found := b.Get("0"); found {
+		t.Error("oldest item was not evicted")
+	}
+	if x, found := b.Get(strconv.Itoa(n - 1)); !found || !bytes.Equal(x, v) {
+		t.Error("newest item was not found")
+	}
+	found := 0
+	for i := 0; i < n; i++ {
+		if _, ok := b.Get(strconv.Itoa(i)); ok {
+			found++
+		}
+	}
+	if found < 2000 || found > 3200 {
+		t.Errorf("%d items found in a 3 MB cache of 1 KB items", found)
+	}
+	if _, err := NewBytesCache(0); err == nil {
+		t.Error("zero size accepted")
+	}
+}
@@ -1,12 +1,15 @@
 package cache
 
 import (
-	"encoding/gob"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,6 +17,17 @@ type Item struct {
 	Object     interface{}
 	Expiration int64
 	Accessed   int64
+	Created    int64
+	Hits       int64
+	Cost       int64
+	Tags       []string
+	Version    uint64
+	Priority   Priority
+
+	// Shared by the copies of the item in the cache, so that Get can update
+	// Accessed and Hits without storing a new copy. Only set once the item
+	// has been retrieved; see touch.
+	access *itemAccess
 }
 
 // Returns true if the item has expired.
@@ -29,6 +43,21 @@ func (item Item) LastAccessed() time.Time {
 	return time.Unix(0, item.Accessed)
 }
 
+// Returns the time at which the item was set, or the zero time if creation
+// times aren't tracked (see GetItem).
+func (item Item) CreatedAt() time.Time {
+	if item.Created == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, item.Created)
+}
+
+// Returns the estimated size of the item's value in bytes, as used for
+// MaxBytes (see Sized), not counting its key or the overhead of the item.
+func (item Item) Size() int64 {
+	return estimateSize("", item.Object) - itemOverhead
+}
+
 const (
 	// For use with functions that take an expiration time.
 	NoExpiration time.Duration = -1
@@ -44,9 +73,35 @@ type Cache struct {
 }
 
 type cache struct {
-	items   sync.Map
-	mu      sync.RWMutex
-	janitor *janitor
+	stats     cacheStats
+	version   uint64
+	count     int64 // maintained by store and remove
+	items     sync.Map
+	mu        sync.RWMutex
+	janitor   *janitor
+	persister *persister
+	wal       *wal
+	callbacks *callbackPool
+	chain     *chain
+	hotKeys   *hotKeys
+	window    *hitWindow
+	sizer     *sizer
+	memory    *memWatcher
+	closed    uint32
+	nsLimits  uint32 // set once a Namespace has a size limit
+	tags      tagIndex
+	events    eventHub
+	leases    leaseTable
+	pins      pinSet
+	refreshes refreshTable
+	snap      sync.RWMutex // see AtomicSnapshots
+	txnMu     sync.RWMutex // see loadItem
+	txns      uint32       // set once a Txn commits
+	indexes   map[string]*secondaryIndex
+	ordered   *keyList
+	bloom     *bloomFilter
+	// name -> *Namespace
+	namespaces sync.Map
 	*CacheOptions
 }
 
@@ -54,104 +109,186 @@ type cache struct {
 // (DefaultExpiration), the cache's default expiration time is used. If it is -1
 // (NoExpiration), the item never expires.
 func (c *cache) Set(k string, x interface{}, d time.Duration) {
+	if c.chain != nil {
+		c.chain.set(k, x, d)
+		return
+	}
+	c.doSet(k, x, d)
+}
+
+func (c *cache) doSet(k string, x interface{}, d time.Duration) {
+	c.doSetItem(k, x, d, Item{})
+}
+
+// Like Set, but the new item also gets the Cost, Priority and Tags of extra,
+// and its Accessed time if that is set. Used by SetWithCost and the like,
+// which go through the middleware like Set.
+func (c *cache) setItem(k string, x interface{}, d time.Duration, extra Item) {
+	if c.chain != nil {
+		c.chain.setTo(func(k string, x interface{}, d time.Duration) {
+			c.doSetItem(k, x, d, extra)
+		})(k, x, d)
+		return
+	}
+	c.doSetItem(k, x, d, extra)
+}
+
+func (c *cache) doSetItem(k string, x interface{}, d time.Duration, extra Item) {
+	if c.isClosed() {
+		return
+	}
+	if c.checkSize(k, x) != nil {
+		return
+	}
+	c.makeRoom(k)
+	if c.hotKeys != nil {
+		c.hotKeys.record(k)
+	}
+	x = c.copyIn(x)
 	// "Inlining" of set
 	var (
 		now time.Time
 		e   int64
 	)
 	if d == DefaultExpiration {
-		d = c.Expiration
+		d = c.defaultExpiration(k)
 	}
 	if d > 0 {
-		now = time.Now()
+		now = c.now()
 		e = now.Add(d).UnixNano()
 	}
-	if c.CacheSize > 0 {
+	item := Item{
+		Object:     x,
+		Expiration: e,
+		Version:    c.nextVersion(),
+		Cost:       extra.Cost,
+		Priority:   extra.Priority,
+		Tags:       extra.Tags,
+	}
+	if c.lru() {
 		if d <= 0 {
 			// d <= 0 means we didn't set now above
-			now = time.Now()
+			now = c.now()
 		}
-		c.items.Store(k, Item{
-			Object:     x,
-			Expiration: e,
-			Accessed:   now.UnixNano(),
-		})
-		// TODO: Calls to mu.Unlock are currently not deferred because
-		// defer adds ~200 ns (as of go1.)
-	} else {
-		c.items.Store(k, Item{
-			Object:     x,
-			Expiration: e,
-		})
+		item.Accessed = now.UnixNano()
+		item.Created = now.UnixNano()
+	}
+	if extra.Accessed != 0 {
+		item.Accessed = extra.Accessed
 	}
+	c.store(k, item)
+	c.notify(EventSet, k, x)
 }
 
 func (c *cache) SetMulti(items map[string]interface{}, d time.Duration) {
+	if c.isClosed() {
+		return
+	}
+	if c.anyTooLarge(items) {
+		kept := make(map[string]interface{}, len(items))
+		for k, v := range items {
+			if c.checkSize(k, v) == nil {
+				kept[k] = v
+			}
+		}
+		items = kept
+	}
 	// "Inlining" of set
-	var (
-		now time.Time
-		e   int64
-	)
-	if d == DefaultExpiration {
+	if d == DefaultExpiration && len(c.PrefixExpirations) == 0 {
 		d = c.Expiration
 	}
-	if d > 0 {
-		now = time.Now()
-		e = now.Add(d).UnixNano()
-	}
-	if c.CacheSize > 0 {
-		if d <= 0 {
-			// d <= 0 means we didn't set now above
-			now = time.Now()
+	if c.HardCacheSize > 0 {
+		added := 0
+		for k := range items {
+			if _, found := c.items.Load(k); !found {
+				added++
+			}
 		}
-
-		for k, v := range items {
-			c.items.Store(k, Item{
-				Object:     v,
-				Expiration: e,
-				Accessed:   now.UnixNano(),
-			})
+		c.evictMany(c.reserve(added))
+	}
+	now := c.now()
+	lru := c.lru()
+	var replaced []interface{}
+	// With AtomicSnapshots, the items are stored together, between two
+	// snapshots.
+	c.beginWrite()
+	for k, v := range items {
+		dk := d
+		if dk == DefaultExpiration {
+			// The items may have different default expirations.
+			dk = c.defaultExpiration(k)
 		}
-		// TODO: Calls to mu.Unlock are currently not deferred because
-		// defer adds ~200 ns (as of go1.)
-
-	} else {
-
+		item := Item{
+			Object:  c.copyIn(v),
+			Version: c.nextVersion(),
+		}
+		if dk > 0 {
+			item.Expiration = now.Add(dk).UnixNano()
+		}
+		if lru {
+			item.Accessed = now.UnixNano()
+			item.Created = now.UnixNano()
+		}
+		if old, loaded := c.storeItem(k, item); loaded && c.DisposeValues {
+			replaced = append(replaced, old, item.Object)
+		}
+	}
+	c.endWrite()
+	for i := 0; i < len(replaced); i += 2 {
+		disposeReplaced(replaced[i], replaced[i+1])
+	}
+	if c.events.inUse() {
 		for k, v := range items {
-			c.items.Store(k, Item{
-				Object:     v,
-				Expiration: e,
-			})
+			c.notify(EventSet, k, v)
 		}
 	}
 }
 
 func (c *cache) set(k string, x interface{}, d time.Duration) {
+	if c.isClosed() {
+		return
+	}
+	if c.checkSize(k, x) != nil {
+		return
+	}
+	c.makeRoom(k)
+	x = c.copyIn(x)
+	c.put(k, x, d)
+	c.notify(EventSet, k, x)
+}
+
+// Store x, which has already been copied in, under k with the expiration d.
+// Unlike set, put neither makes room for a new item nor notifies anyone, so
+// it may be called while holding c.mu.
+func (c *cache) put(k string, x interface{}, d time.Duration) {
 	var (
 		now time.Time
 		e   int64
 	)
 	if d == DefaultExpiration {
-		d = c.Expiration
+		d = c.defaultExpiration(k)
 	}
 	if d > 0 {
-		now = time.Now()
+		now = c.now()
 		e = now.Add(d).UnixNano()
 	}
-	if c.CacheSize > 0 {
+	if c.lru() {
 		if d <= 0 {
 			// d <= 0 means we didn't set now above
-			now = time.Now()
+			now = c.now()
 		}
-		c.items.Store(k, Item{
+		c.store(k, Item{
 			Object:     x,
 			Expiration: e,
+			Version:    c.nextVersion(),
 			Accessed:   now.UnixNano(),
+			Created:    now.UnixNano(),
 		})
 	} else {
-		c.items.Store(k, Item{
+		c.store(k, Item{
 			Object:     x,
 			Expiration: e,
+			Version:    c.nextVersion(),
 		})
 	}
 }
@@ -165,6 +302,12 @@ func (c *cache) SetDefault(k string, x interface{}) {
 // Add an item to the cache only if an item doesn't already exist for the given
 // key, or if the existing item has expired. Returns an error otherwise.
 func (c *cache) Add(k string, x interface{}, d time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	if err := c.checkSize(k, x); err != nil {
+		return err
+	}
 	_, found := c.getItem(k)
 	if found {
 		return fmt.Errorf("Item %s already exists", k)
@@ -176,6 +319,12 @@ func (c *cache) Add(k string, x interface{}, d time.Duration) error {
 // Set a new value for the cache key only if it already exists, and the existing
 // item hasn't expired. Returns an error otherwise.
 func (c *cache) Replace(k string, x interface{}, d time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	if err := c.checkSize(k, x); err != nil {
+		return err
+	}
 	_, found := c.get(k)
 	if !found {
 		return fmt.Errorf("Item %s doesn't exist", k)
@@ -187,26 +336,49 @@ func (c *cache) Replace(k string, x interface{}, d time.Duration) error {
 // Get an item from the cache. Returns the item or nil, and a bool indicating
 // whether the key was found.
 func (c *cache) Get(k string) (interface{}, bool) {
+	if c.chain != nil {
+		return c.chain.get(k)
+	}
+	return c.doGet(k)
+}
+
+func (c *cache) doGet(k string) (interface{}, bool) {
+	if c.isClosed() {
+		return nil, false
+	}
+	if c.Admission != nil {
+		c.Admission.Record(k)
+	}
+	if c.hotKeys != nil {
+		c.hotKeys.record(k)
+	}
 	// "Inlining" of get and Expired
 	item, found := c.getItem(k)
 	if !found {
+		c.miss()
 		return nil, false
 	}
 	var now int64
 	if item.Expiration > 0 {
-		now = time.Now().UnixNano()
+		now = c.now().UnixNano()
 		if now > item.Expiration {
+			c.miss()
 			return nil, false
 		}
 	}
-	if c.CacheSize > 0 {
+	if t, ok := c.Policy.(AccessTracker); ok {
+		t.Access(k)
+	} else if c.lru() {
 		if now == 0 {
-			now = time.Now().UnixNano()
+			now = c.now().UnixNano()
 		}
-		item.Accessed = now
-		c.items.Store(k, item)
+		c.touch(k, &item, now)
 	}
-	return item.Object, true
+	c.hit()
+	if item.Expiration > 0 && c.refreshes.inUse() {
+		c.refreshAhead(k, item, now)
+	}
+	return c.copyOut(item.Object), true
 }
 
 // If LRU functionality is being used (and get implies updating item.Accessed,)
@@ -219,27 +391,152 @@ func (c *cache) get(k string) (interface{}, bool) {
 	// "Inlining" of Expired
 	var now int64
 	if item.Expiration > 0 {
-		now = time.Now().UnixNano()
+		now = c.now().UnixNano()
 		if now > item.Expiration {
 			return nil, false
 		}
 	}
-	if c.CacheSize > 0 {
+	if t, ok := c.Policy.(AccessTracker); ok {
+		t.Access(k)
+	} else if c.lru() {
 		if now == 0 {
-			now = time.Now().UnixNano()
+			now = c.now().UnixNano()
 		}
-		item.Accessed = now
-		c.items.Store(k, item)
+		c.touch(k, &item, now)
 	}
 	return item.Object, true
 }
 
+// Reports whether k is in the cache and hasn't expired. Unlike Get, Has does
+// not record an access: it leaves the item's Accessed time, Hits and place in
+// the eviction order alone, and does not count as a hit or a miss.
+func (c *cache) Has(k string) bool {
+	if c.isClosed() {
+		return false
+	}
+	tmp, found := c.loadItem(k)
+	if !found {
+		return false
+	}
+	// "Inlining" of Expired
+	e := tmp.(Item).Expiration
+	return e == 0 || c.now().UnixNano() <= e
+}
+
+// Like Get, but returns the whole item, with its metadata: its expiration,
+// version, tags, cost and priority, and, in a cache with a size limit or with
+// TrackAccess (where they are tracked), the time it was created and last
+// accessed and the number of times it was retrieved. Like Get, GetItem
+// records an access; use Peek to read a value without one.
+func (c *cache) GetItem(k string) (Item, bool) {
+	item, found := c.lookup(k)
+	if !found {
+		return Item{}, false
+	}
+	item.Object = c.copyOut(item.Object)
+	item.Tags = append([]string(nil), item.Tags...)
+	item.access = nil
+	return item, true
+}
+
+// Like Get, but without recording an access, like Has: the item's Accessed
+// time, Hits and place in the eviction order are left alone, and Peek does
+// not count as a hit or a miss. This suits metrics and debugging readers,
+// which must not change how the cache treats its production traffic.
+func (c *cache) Peek(k string) (interface{}, bool) {
+	if c.isClosed() {
+		return nil, false
+	}
+	tmp, found := c.loadItem(k)
+	if !found {
+		return nil, false
+	}
+	item := tmp.(Item)
+	// "Inlining" of Expired
+	if item.Expiration > 0 && c.now().UnixNano() > item.Expiration {
+		return nil, false
+	}
+	return c.copyOut(item.Object), true
+}
+
+// Returns true if the cache is bounded, and the Accessed times of its items
+// must therefore be kept up to date, or TrackAccess asks for them.
+func (c *cache) lru() bool {
+	return c.CacheSize > 0 || c.sizer != nil || c.MaxBytes > 0 || c.MaxCost > 0 || c.HardCacheSize > 0 ||
+		len(c.Quotas) > 0 || atomic.LoadUint32(&c.nsLimits) == 1 || c.MemoryLimit > 0 || c.TrackAccess
+}
+
 func (c *cache) getItem(k string) (Item, bool) {
-	tmp, found := c.items.Load(k)
+	tmp, found := c.loadItem(k)
 	if !found {
 		return Item{}, false
 	}
-	return tmp.(Item), true
+	return itemOf(tmp), true
+}
+
+// The Accessed time and Hits of an item, updated atomically.
+type itemAccess struct {
+	accessed int64
+	hits     int64
+}
+
+// Returns the Item stored as value in c.items, with its current Accessed time
+// and Hits.
+func itemOf(value interface{}) Item {
+	v := value.(Item)
+	if v.access != nil {
+		v.Accessed = atomic.LoadInt64(&v.access.accessed)
+		v.Hits = atomic.LoadInt64(&v.access.hits)
+	}
+	return v
+}
+
+// Record that item, stored under k, was retrieved at now, unless it was
+// already retrieved less than AccessedResolution before. The first time, the
+// item is stored again with an itemAccess; afterwards, it is updated in place,
+// without allocating.
+func (c *cache) touch(k string, item *Item, now int64) {
+	if now-item.Accessed < int64(c.AccessedResolution) {
+		return
+	}
+	item.Accessed = now
+	if a := item.access; a != nil {
+		atomic.StoreInt64(&a.accessed, now)
+		item.Hits = atomic.AddInt64(&a.hits, 1)
+		return
+	}
+	item.Hits++
+	item.access = &itemAccess{accessed: now, hits: item.Hits}
+	c.store(k, *item)
+}
+
+// Look up k like Get does, recording the access, and return the whole item.
+func (c *cache) lookup(k string) (Item, bool) {
+	if c.isClosed() {
+		return Item{}, false
+	}
+	if c.Admission != nil {
+		c.Admission.Record(k)
+	}
+	if c.hotKeys != nil {
+		c.hotKeys.record(k)
+	}
+	item, found := c.getItem(k)
+	if !found || c.expired(item) {
+		c.miss()
+		return Item{}, false
+	}
+	if t, ok := c.Policy.(AccessTracker); ok {
+		t.Access(k)
+	} else if c.lru() {
+		now := c.now().UnixNano()
+		c.touch(k, &item, now)
+	}
+	c.hit()
+	if item.Expiration > 0 && c.refreshes.inUse() {
+		c.refreshAhead(k, item, c.now().UnixNano())
+	}
+	return item, true
 }
 
 // GetWithExpiration returns an item and its expiration time from the cache.
@@ -247,38 +544,52 @@ func (c *cache) getItem(k string) (Item, bool) {
 // never expires a zero value for time.Time is returned), and a bool indicating
 // whether the key was found.
 func (c *cache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	if c.isClosed() {
+		return nil, time.Time{}, false
+	}
+	if c.Admission != nil {
+		c.Admission.Record(k)
+	}
+	if c.hotKeys != nil {
+		c.hotKeys.record(k)
+	}
 	// "Inlining" of get and Expired
 	item, found := c.getItem(k)
 	if !found {
+		c.miss()
 		return nil, time.Time{}, false
 	}
 	var now int64
 	if item.Expiration > 0 {
-		now = time.Now().UnixNano()
+		now = c.now().UnixNano()
 		if now > item.Expiration {
+			c.miss()
 			return nil, time.Time{}, false
 		}
-		if c.CacheSize > 0 {
+		if t, ok := c.Policy.(AccessTracker); ok {
+			t.Access(k)
+		} else if c.lru() {
 			if now == 0 {
-				now = time.Now().UnixNano()
+				now = c.now().UnixNano()
 			}
-			item.Accessed = now
-			c.items.Store(k, item)
+			c.touch(k, &item, now)
 		}
-
-		return item.Object, time.Unix(0, item.Expiration), true
+		c.hit()
+		return c.copyOut(item.Object), time.Unix(0, item.Expiration), true
 	}
-	if c.CacheSize > 0 {
+	if t, ok := c.Policy.(AccessTracker); ok {
+		t.Access(k)
+	} else if c.lru() {
 		if now == 0 {
-			now = time.Now().UnixNano()
+			now = c.now().UnixNano()
 		}
-		item.Accessed = now
-		c.items.Store(k, item)
+		c.touch(k, &item, now)
 	}
+	c.hit()
 
 	// If expiration <= 0 (i.e. no expiration time set) then return the item
 	// and a zeroed time.Time
-	return item.Object, time.Time{}, true
+	return c.copyOut(item.Object), time.Time{}, true
 }
 
 // Increment an item of type int, int8, int16, int32, int64, uintptr, uint,
@@ -287,13 +598,17 @@ func (c *cache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
 // possible to increment it by n. To retrieve the incremented value, use one
 // of the specialized methods, e.g. IncrementInt64.
 func (c *cache) Increment(k string, n int64) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	c.mu.Lock()
 	v, found := c.getItem(k)
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
 		c.mu.Unlock()
 		return fmt.Errorf("Item %s not found", k)
 	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
 	}
 	switch v.Object.(type) {
 	case int:
@@ -306,6 +621,12 @@ func (c *cache) Increment(k string, n int64) error {
 		v.Object = v.Object.(int32) + int32(n)
 	case int64:
 		v.Object = v.Object.(int64) + n
+	case *Counter:
+		ctr := v.Object.(*Counter)
+		ctr.Add(n)
+		c.mu.Unlock()
+		c.notify(EventSet, k, ctr)
+		return nil
 	case uint:
 		v.Object = v.Object.(uint) + uint(n)
 	case uintptr:
@@ -323,9 +644,13 @@ func (c *cache) Increment(k string, n int64) error {
 	case float64:
 		v.Object = v.Object.(float64) + float64(n)
 	default:
+		c.mu.Unlock()
 		return fmt.Errorf("The value for %s is not an integer", k)
 	}
-	c.items.Store(k, v)
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, v.Object)
 	return nil
 }
 
@@ -335,12 +660,17 @@ func (c *cache) Increment(k string, n int64) error {
 // value. To retrieve the incremented value, use one of the specialized methods,
 // e.g. IncrementFloat64.
 func (c *cache) IncrementFloat(k string, n float64) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	c.mu.Lock()
 	v, found := c.getItem(k)
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
+		c.mu.Unlock()
 		return fmt.Errorf("Item %s not found", k)
 	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
 	}
 	switch v.Object.(type) {
 	case float32:
@@ -348,285 +678,131 @@ func (c *cache) IncrementFloat(k string, n float64) error {
 	case float64:
 		v.Object = v.Object.(float64) + n
 	default:
+		c.mu.Unlock()
 		return fmt.Errorf("The value for %s does not have type float32 or float64", k)
 	}
-	c.items.Store(k, v)
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, v.Object)
 	return nil
 }
 
 // Increment an item of type int by n. Returns an error if the item's value is
 // not an int, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementInt(k string, n int) (int, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type int8 by n. Returns an error if the item's value is
 // not an int8, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementInt8(k string, n int8) (int8, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int8)
-	if !ok {
-		c.mu.Unlock()
-		return 0, fmt.Errorf("The value for %s is not an int8", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type int16 by n. Returns an error if the item's value is
 // not an int16, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementInt16(k string, n int16) (int16, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int16)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int16", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type int32 by n. Returns an error if the item's value is
 // not an int32, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementInt32(k string, n int32) (int32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int32", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type int64 by n. Returns an error if the item's value is
 // not an int64, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementInt64(k string, n int64) (int64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int64", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uint by n. Returns an error if the item's value is
 // not an uint, or if it was not found. If there is no error, the incremented
 // value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUint(k string, n uint) (uint, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uintptr by n. Returns an error if the item's value
 // is not an uintptr, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUintptr(k string, n uintptr) (uintptr, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uintptr)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uintptr", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uint8 by n. Returns an error if the item's value
 // is not an uint8, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUint8(k string, n uint8) (uint8, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint8)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint8", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uint16 by n. Returns an error if the item's value
 // is not an uint16, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUint16(k string, n uint16) (uint16, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint16)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint16", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uint32 by n. Returns an error if the item's value
 // is not an uint32, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUint32(k string, n uint32) (uint32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint32", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type uint64 by n. Returns an error if the item's value
 // is not an uint64, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementUint64(k string, n uint64) (uint64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint64", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type float32 by n. Returns an error if the item's value
 // is not an float32, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementFloat32(k string, n float32) (float32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(float32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an float32", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Increment an item of type float64 by n. Returns an error if the item's value
 // is not an float64, or if it was not found. If there is no error, the
 // incremented value is returned.
+//
+// Deprecated: Use IncrementNumber.
 func (c *cache) IncrementFloat64(k string, n float64) (float64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(float64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an float64", k)
-	}
-	nv := rv + n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, false)
 }
 
 // Decrement an item of type int, int8, int16, int32, int64, uintptr, uint,
@@ -635,14 +811,19 @@ func (c *cache) IncrementFloat64(k string, n float64) (float64, error) {
 // possible to decrement it by n. To retrieve the decremented value, use one
 // of the specialized methods, e.g. DecrementInt64.
 func (c *cache) Decrement(k string, n int64) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
 	// TODO: Implement Increment and Decrement more cleanly.
 	// (Cannot do Increment(k, n*-1) for uints.)
+	c.mu.Lock()
 	v, found := c.getItem(k)
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
+		c.mu.Unlock()
 		return fmt.Errorf("Item not found")
 	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
 	}
 	switch v.Object.(type) {
 	case int:
@@ -655,6 +836,12 @@ func (c *cache) Decrement(k string, n int64) error {
 		v.Object = v.Object.(int32) - int32(n)
 	case int64:
 		v.Object = v.Object.(int64) - n
+	case *Counter:
+		ctr := v.Object.(*Counter)
+		ctr.Add(-n)
+		c.mu.Unlock()
+		c.notify(EventSet, k, ctr)
+		return nil
 	case uint:
 		v.Object = v.Object.(uint) - uint(n)
 	case uintptr:
@@ -672,9 +859,13 @@ func (c *cache) Decrement(k string, n int64) error {
 	case float64:
 		v.Object = v.Object.(float64) - float64(n)
 	default:
+		c.mu.Unlock()
 		return fmt.Errorf("The value for %s is not an integer", k)
 	}
-	c.items.Store(k, v)
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, v.Object)
 	return nil
 }
 
@@ -684,12 +875,17 @@ func (c *cache) Decrement(k string, n int64) error {
 // value. To retrieve the decremented value, use one of the specialized methods,
 // e.g. DecrementFloat64.
 func (c *cache) DecrementFloat(k string, n float64) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	c.mu.Lock()
 	v, found := c.getItem(k)
-	if !found || v.Expired() {
+	if !found || c.expired(v) {
+		c.mu.Unlock()
 		return fmt.Errorf("Item %s not found", k)
 	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
 	}
 	switch v.Object.(type) {
 	case float32:
@@ -697,439 +893,464 @@ func (c *cache) DecrementFloat(k string, n float64) error {
 	case float64:
 		v.Object = v.Object.(float64) - n
 	default:
+		c.mu.Unlock()
 		return fmt.Errorf("The value for %s does not have type float32 or float64", k)
 	}
-	c.items.Store(k, v)
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, v.Object)
 	return nil
 }
 
 // Decrement an item of type int by n. Returns an error if the item's value is
 // not an int, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementInt(k string, n int) (int, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		c.mu.Unlock()
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type int8 by n. Returns an error if the item's value is
 // not an int8, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementInt8(k string, n int8) (int8, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int8)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int8", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type int16 by n. Returns an error if the item's value is
 // not an int16, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementInt16(k string, n int16) (int16, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int16)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int16", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type int32 by n. Returns an error if the item's value is
 // not an int32, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementInt32(k string, n int32) (int32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int32", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type int64 by n. Returns an error if the item's value is
 // not an int64, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementInt64(k string, n int64) (int64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(int64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an int64", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uint by n. Returns an error if the item's value is
 // not an uint, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUint(k string, n uint) (uint, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uintptr by n. Returns an error if the item's value
 // is not an uintptr, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUintptr(k string, n uintptr) (uintptr, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uintptr)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uintptr", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uint8 by n. Returns an error if the item's value is
 // not an uint8, or if it was not found. If there is no error, the decremented
 // value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUint8(k string, n uint8) (uint8, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint8)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint8", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uint16 by n. Returns an error if the item's value
 // is not an uint16, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUint16(k string, n uint16) (uint16, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint16)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint16", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uint32 by n. Returns an error if the item's value
 // is not an uint32, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUint32(k string, n uint32) (uint32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint32", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type uint64 by n. Returns an error if the item's value
 // is not an uint64, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementUint64(k string, n uint64) (uint64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(uint64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an uint64", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type float32 by n. Returns an error if the item's value
 // is not an float32, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementFloat32(k string, n float32) (float32, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
-	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
-	}
-	rv, ok := v.Object.(float32)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an float32", k)
-	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return addNumber(c, k, n, true)
 }
 
 // Decrement an item of type float64 by n. Returns an error if the item's value
 // is not an float64, or if it was not found. If there is no error, the
 // decremented value is returned.
+//
+// Deprecated: Use DecrementNumber.
 func (c *cache) DecrementFloat64(k string, n float64) (float64, error) {
-	v, found := c.getItem(k)
-	if !found || v.Expired() {
-		return 0, fmt.Errorf("Item %s not found", k)
+	return addNumber(c, k, n, true)
+}
+
+// Delete an item from the cache. Does nothing if the key is not in the cache.
+func (c *cache) Delete(k string) {
+	if c.chain != nil {
+		c.chain.delete(k)
+		return
 	}
-	if c.CacheSize > 0 {
-		v.Accessed = time.Now().UnixNano()
+	c.doDelete(k)
+}
+
+func (c *cache) doDelete(k string) {
+	if v, evicted := c.delete(k, EventDelete); evicted {
+		c.evictOne(k, v, EventDelete)
 	}
-	rv, ok := v.Object.(float64)
-	if !ok {
-		return 0, fmt.Errorf("The value for %s is not an float64", k)
+}
+
+// Delete k, emitting an event of type ev if it was found. Returns the item's
+// value and whether it must be passed to the eviction callback.
+func (c *cache) delete(k string, ev EventType) (interface{}, bool) {
+	callback := c.hasEvictionCallback() && (!c.EvictionsOnly || (ev != EventDelete && ev != EventFlush))
+	if v, found := c.remove(k); found && (callback || c.tags.inUse() || c.events.inUse()) {
+		c.tags.remove(k, v.Tags)
+		c.notify(ev, k, v.Object)
+		return v.Object, callback
 	}
-	nv := rv - n
-	v.Object = nv
-	c.items.Store(k, v)
-	return nv, nil
+	return nil, false
 }
 
-// Delete an item from the cache. Does nothing if the key is not in the cache.
-func (c *cache) Delete(k string) {
-	if v, evicted := c.delete(k); evicted {
-		c.EvictionCallback(k, v)
+// Store v under k, keeping track of the number of items.
+func (c *cache) store(k string, v Item) {
+	c.beginWrite()
+	old, loaded := c.storeItem(k, v)
+	c.endWrite()
+	if loaded && c.DisposeValues {
+		disposeReplaced(old, v.Object)
 	}
 }
 
-func (c *cache) delete(k string) (interface{}, bool) {
-	if c.EvictionCallback != nil {
-		if tmp, found := c.items.Load(k); found {
-			v := tmp.(Item)
-			c.items.Delete(k)
-			return v.Object, true
+// Like store, but without beginWrite and disposal. Returns the value of the
+// item v replaced, if there was one.
+func (c *cache) storeItem(k string, v Item) (interface{}, bool) {
+	if a := v.access; a != nil {
+		atomic.StoreInt64(&a.accessed, v.Accessed)
+		atomic.StoreInt64(&a.hits, v.Hits)
+	}
+	old, loaded := c.items.Swap(k, v)
+	var oldObject interface{}
+	if len(v.Tags) > 0 && (!loaded || !sameTags(old.(Item).Tags, v.Tags)) {
+		// Every way of storing a tagged item (SetWithTags, Load, Merge, ...)
+		// keeps the index InvalidateTag uses up to date.
+		c.tags.add(k, v.Tags)
+	}
+	if loaded {
+		oldObject = old.(Item).Object
+	} else {
+		atomic.AddInt64(&c.count, 1)
+		if c.ordered != nil {
+			c.ordered.insert(k)
+		}
+		if c.bloom != nil {
+			c.bloom.add(k)
 		}
 	}
-	c.items.Delete(k)
-	return nil, false
+	if c.indexes != nil {
+		c.reindex(k, oldObject, loaded, v.Object)
+	}
+	return oldObject, loaded
+}
+
+// Delete the item with key k, keeping track of the number of items. Returns
+// the deleted item, if there was one.
+func (c *cache) remove(k string) (Item, bool) {
+	c.beginWrite()
+	v, found := c.removeItem(k)
+	c.endWrite()
+	if found && c.DisposeValues {
+		dispose(v.Object)
+	}
+	return v, found
+}
+
+// Like remove, but without beginWrite and disposal.
+func (c *cache) removeItem(k string) (Item, bool) {
+	tmp, found := c.items.LoadAndDelete(k)
+	if !found {
+		return Item{}, false
+	}
+	atomic.AddInt64(&c.count, -1)
+	if c.bloom != nil {
+		c.bloom.remove(k)
+	}
+	if c.ordered != nil {
+		c.ordered.delete(k, func() bool {
+			_, found := c.items.Load(k)
+			return found
+		})
+	}
+	v := itemOf(tmp)
+	if c.indexes != nil {
+		c.unindex(k, v.Object)
+	}
+	return v, true
+}
+
+// Delete all expired items from the cache. Returns the number of items
+// deleted.
+func (c *cache) DeleteExpired() int {
+	n, _, _ := c.deleteExpired(false)
+	return n
 }
 
-type keyAndValue struct {
-	key   string
-	value interface{}
+// Like DeleteExpired, but returns the keys of the deleted items.
+func (c *cache) DeleteExpiredKeys() []string {
+	_, keys, _ := c.deleteExpired(true)
+	return keys
 }
 
-// Delete all expired items from the cache.
-func (c *cache) DeleteExpired() {
-	var evictedItems []keyAndValue
-	now := time.Now().UnixNano()
-	evictFunc := c.EvictionCallback
+func (c *cache) deleteExpired(withKeys bool) (int, []string, int) {
+	var (
+		evictedItems []KeyValue
+		keys         []string
+		removed      int
+		scanned      int
+	)
+	now := c.now().UnixNano()
 	c.items.Range(func(key, value interface{}) bool {
+		scanned++
 
-		v := value.(Item)
+		v := itemOf(value)
 		k := key.(string)
 		// "Inlining" of Expired
 		if v.Expiration > 0 && now > v.Expiration {
-			ov, evicted := c.delete(k)
+			ov, evicted := c.delete(k, EventExpire)
 			if evicted {
-				evictedItems = append(evictedItems, keyAndValue{k, ov})
+				evictedItems = append(evictedItems, KeyValue{k, ov, EventExpire})
+			}
+			if withKeys {
+				keys = append(keys, k)
 			}
+			removed++
 		}
 
 		return true
 	})
-	for _, v := range evictedItems {
-		evictFunc(v.key, v.value)
-	}
+	c.stats.evicted(removed)
+	c.leases.deleteExpired(now)
+	c.refreshes.prune(c)
+	if removed > 0 {
+		c.debug("cache: deleted expired items", "count", removed)
+	}
+	c.evictMany(evictedItems)
+	return removed, keys, scanned
+}
+
+// Delete all items whose keys start with prefix, calling the eviction
+// callback for each of them. Returns the number of items deleted, which may
+// include items that had expired but had not yet been cleaned up.
+func (c *cache) DeleteByPrefix(prefix string) int {
+	return c.DeleteFunc(func(k string, _ interface{}) bool {
+		return strings.HasPrefix(k, prefix)
+	})
 }
 
-// Delete some of the oldest items in the cache if the soft size limit has been
-// exceeded.
-func (c *cache) DeleteLRU() {
+// Delete all items for which pred returns true, calling the eviction callback
+// for each of them. Returns the number of items deleted, which may include
+// items that had expired but had not yet been cleaned up.
+func (c *cache) DeleteFunc(pred func(k string, v interface{}) bool) int {
 	var (
-		overCount = c.itemCount() - c.CacheSize
-		evictFunc = c.EvictionCallback
+		evictedItems []KeyValue
+		removed      int
 	)
-	evicted := c.deleteLRUAmount(overCount)
-	for _, v := range evicted {
-		evictFunc(v.key, v.value)
+	c.items.Range(func(key, value interface{}) bool {
+		k := key.(string)
+		if pred(k, value.(Item).Object) {
+			ov, evicted := c.delete(k, EventDelete)
+			if evicted {
+				evictedItems = append(evictedItems, KeyValue{k, ov, EventDelete})
+			}
+			removed++
+		}
+		return true
+	})
+	c.evictMany(evictedItems)
+	return removed
+}
+
+// Delete some of the oldest items in the cache if the soft size limit
+// (CacheSize), the byte limit (MaxBytes) or the cost budget (MaxCost) has been
+// exceeded. The items are chosen by the cache's eviction policy, which by
+// default evicts the least recently used items. Returns the number of items
+// deleted.
+func (c *cache) DeleteLRU() int {
+	return len(c.DeleteLRUKeys())
+}
+
+// Like DeleteLRU, but returns the keys of the deleted items, in the order they
+// were evicted.
+func (c *cache) DeleteLRUKeys() []string {
+	var (
+		evicted []KeyValue
+		keys    []string
+	)
+	add := func(e []KeyValue, k []string) {
+		evicted = append(evicted, e...)
+		keys = append(keys, k...)
+	}
+	if size := c.EffectiveCacheSize(); size > 0 {
+		if n := c.itemCount(); n > size {
+			add(c.deleteLRUAmount(n - int(c.evictionTarget(int64(size)))))
+		}
+	}
+	if c.MaxBytes > 0 {
+		add(c.deleteLRUBytes(c.MaxBytes))
+	}
+	if c.MaxCost > 0 {
+		add(c.deleteLRUCost(c.MaxCost))
+	}
+	for _, q := range c.Quotas {
+		add(c.deleteQuota(q))
+	}
+	if atomic.LoadUint32(&c.nsLimits) == 1 {
+		add(c.deleteNamespaceLRU())
 	}
+	if len(keys) > 0 {
+		c.debug("cache: evicted items", "count", len(keys))
+	}
+	c.evictMany(evicted)
+	return keys
 }
 
-// Delete a number of the oldest items from the cache.
+// Delete a number of items from the cache, chosen by the cache's eviction
+// policy (by default, the least recently used items.)
 func (c *cache) DeleteLRUAmount(numItems int) {
 	c.mu.Lock()
-	evictFunc := c.EvictionCallback
-	evicted := c.deleteLRUAmount(numItems)
+	evicted, _ := c.deleteLRUAmount(numItems)
 	c.mu.Unlock()
-	for _, v := range evicted {
-		evictFunc(v.key, v.value)
-	}
+	c.evictMany(evicted)
 }
 
-func (c *cache) deleteLRUAmount(numItems int) []keyAndValue {
+func (c *cache) deleteLRUAmount(numItems int) ([]KeyValue, []string) {
 	if numItems <= 0 {
-		return nil
+		return nil, nil
 	}
-	var (
-		lastTime     int64 = 0
-		lastItems          = make([]string, numItems) // Ring buffer
-		liCount            = 0
-		full               = false
-		evictedItems []keyAndValue
-		now          = time.Now().UnixNano()
-	)
-	if c.EvictionCallback != nil {
-		evictedItems = make([]keyAndValue, 0, numItems)
+	if c.EvictionSamples > 0 {
+		return c.deleteSampled(numItems)
 	}
-	c.items.Range(func(key, value interface{}) bool {
-
-		v := value.(Item)
-		k := key.(string)
-
-		// "Inlining" of !Expired
-		if v.Expiration == 0 || now <= v.Expiration {
-			if full == false || v.Accessed < lastTime {
-				// We found a least-recently-used item, or our
-				// purge buffer isn't full yet
-				lastTime = v.Accessed
-				// Append it to the buffer, or start overwriting
-				// it
-				if liCount < numItems {
-					lastItems[liCount] = k
-					liCount++
-				} else {
-					lastItems[0] = k
-					liCount = 1
-					full = true
-				}
-			}
-		}
+	candidates, _ := c.evictionCandidates(nil)
+	if numItems > len(candidates) {
+		numItems = len(candidates)
+	}
+	c.orderCandidates(candidates)
+	return c.evictCandidates(candidates[:numItems])
+}
 
-		return true
-	})
+// The codec's output within a snapshot (see snapshot.go) is a snapshotHeader,
+// followed by one snapshotRecord per item, up to the end of the stream. Items
+// are encoded as they are visited, so saving does not copy the cache.
+type snapshotHeader struct {
+	// The number of items in the cache when the snapshot was started. Items
+	// set or deleted while saving may make the actual number differ.
+	Count int
+	// If set, item expirations are durations relative to the time the item
+	// was saved, rather than absolute times.
+	RelativeTTL bool
+}
 
-	if lastTime > 0 {
-		for _, v := range lastItems {
-			if v != "" {
-				ov, evicted := c.delete(v)
-				if evicted {
-					evictedItems = append(evictedItems, keyAndValue{v, ov})
-				}
-			}
-		}
-	}
-	return evictedItems
+type snapshotRecord struct {
+	Key  string
+	Item Item
 }
 
-// Write the cache's items (using Gob) to an io.Writer.
+// Write the cache's items (using Gob, or the Codec given with WithCodec) to
+// an io.Writer. Items set or deleted while the cache is being saved may or
+// may not be included, unless AtomicSnapshots is enabled.
 //
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
 func (c *cache) Save(w io.Writer) (err error) {
-
-	m := make(map[string]Item)
-	c.items.Range(func(key, value interface{}) bool {
-		v := value.(Item)
-		k := key.(string)
-		m[k] = v
-		return true // if false, Range stops
-	})
-
-	enc := gob.NewEncoder(w)
+	ew, err := c.encryptor(w)
+	if err != nil {
+		return err
+	}
+	if ew != nil {
+		defer func() {
+			if cerr := ew.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = ew
+	}
+	if zw := c.compressor(w); zw != nil {
+		defer func() {
+			if cerr := zw.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = zw
+	}
+	if err = writeSnapshotHeader(w); err != nil {
+		return
+	}
+	cw := &chunkWriter{w: w}
+	enc := c.codec().NewEncoder(cw)
 	defer func() {
 		if x := recover(); x != nil {
 			err = fmt.Errorf("Error registering item types with Gob library")
 		}
 	}()
-	for _, v := range m {
-		gob.Register(v.Object)
+	header := snapshotHeader{Count: c.ItemCount(), RelativeTTL: c.RelativeTTL}
+	if err = enc.Encode(header); err != nil {
+		return
+	}
+	var n uint64
+	c.rangeItems(func(k string, v Item) bool {
+		if header.RelativeTTL && v.Expiration > 0 {
+			remaining := v.Expiration - c.now().UnixNano()
+			if remaining < 0 {
+				// Expired items would expire again as soon as they
+				// are loaded.
+				return true
+			}
+			// A 0 expiration would mean that the item never expires.
+			v.Expiration = remaining + 1
+		}
+		err = enc.Encode(snapshotRecord{Key: k, Item: v})
+		n++
+		return err == nil
+	})
+	if err != nil {
+		return
 	}
-
-	err = enc.Encode(m)
-	return
+	return cw.Close(n)
 }
 
 // Save the cache's items to the given filename, creating the file if it
@@ -1150,24 +1371,106 @@ func (c *cache) SaveFile(fname string) error {
 	return fp.Close()
 }
 
-// Add (Gob-serialized) cache items from an io.Reader, excluding any items with
+// Add cache items serialized by Save from an io.Reader, excluding any items with
 // keys that already exist (and haven't expired) in the current cache.
 //
+// If the snapshot is corrupt or truncated, a *SnapshotError is returned.
+// Every chunk of the snapshot is checksummed before it is decoded, but items
+// from the chunks preceding a damaged one will already have been added.
+//
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
 func (c *cache) Load(r io.Reader) error {
-	dec := gob.NewDecoder(r)
-	items := map[string]Item{}
-	err := dec.Decode(&items)
-	if err == nil {
-		for k, v := range items {
-			ov, found := c.getItem(k)
-			if !found || ov.Expired() {
-				c.items.Store(k, v)
+	return c.LoadWithPolicy(r, SkipExisting)
+}
+
+// Decides what Load does with items whose keys already exist, and haven't
+// expired, in the cache. Expired items are always replaced.
+type LoadPolicy int
+
+const (
+	// Keep the existing item.
+	SkipExisting LoadPolicy = iota
+	// Replace the existing item with the loaded one.
+	Overwrite
+	// Keep whichever item expires later, counting items that never expire
+	// as the latest, and using the time they were last accessed to break
+	// ties. If that is equal too, the existing item is kept.
+	NewestWins
+)
+
+func (p LoadPolicy) replaces(existing, loaded Item) bool {
+	switch p {
+	case Overwrite:
+		return true
+	case NewestWins:
+		if existing.Expiration != loaded.Expiration {
+			if existing.Expiration == 0 || loaded.Expiration == 0 {
+				return loaded.Expiration == 0
+			}
+			return loaded.Expiration > existing.Expiration
+		}
+		return loaded.Accessed > existing.Accessed
+	}
+	return false
+}
+
+// Like Load, but resolves conflicts with existing items using policy. Like
+// Set, every item loaded is passed to the EventSink and watchers, and logged
+// to the WAL.
+func (c *cache) LoadWithPolicy(r io.Reader, policy LoadPolicy) error {
+	return c.load(r, policy, true)
+}
+
+// Load the snapshot in r, notifying about the loaded items if notify is set.
+func (c *cache) load(r io.Reader, policy LoadPolicy, notify bool) error {
+	r, err := c.decryptor(r)
+	if err != nil {
+		return err
+	}
+	r, err = decompressor(r)
+	if err != nil {
+		return err
+	}
+	if err := readSnapshotHeader(r); err != nil {
+		return err
+	}
+	cr := &chunkReader{r: r}
+	dec := c.codec().NewDecoder(cr)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		if cr.err != nil {
+			return cr.err
+		}
+		return err
+	}
+	// Records are applied as they are decoded, so loading takes no more
+	// memory than a chunk; the decoder only sees chunks whose checksums
+	// have been verified.
+	var n uint64
+	for {
+		var rec snapshotRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return cr.Close(n)
+		} else if err != nil {
+			if cr.err != nil {
+				return cr.err
+			}
+			return err
+		}
+		n++
+		if header.RelativeTTL && rec.Item.Expiration > 0 {
+			rec.Item.Expiration += c.now().UnixNano()
+		}
+		ov, found := c.getItem(rec.Key)
+		if !found || c.expired(ov) || policy.replaces(ov, rec.Item) {
+			rec.Item.Version = c.nextVersion()
+			c.store(rec.Key, rec.Item)
+			if notify {
+				c.notify(EventSet, rec.Key, rec.Item.Object)
 			}
 		}
 	}
-	return err
 }
 
 // Load and add cache items from the given filename, excluding any items with
@@ -1176,11 +1479,16 @@ func (c *cache) Load(r io.Reader) error {
 // NOTE: This method is deprecated in favor of c.Items() and NewFrom() (see the
 // documentation for NewFrom().)
 func (c *cache) LoadFile(fname string) error {
+	return c.LoadFileWithPolicy(fname, SkipExisting)
+}
+
+// Like LoadFile, but resolves conflicts with existing items using policy.
+func (c *cache) LoadFileWithPolicy(fname string, policy LoadPolicy) error {
 	fp, err := os.Open(fname)
 	if err != nil {
 		return err
 	}
-	err = c.Load(fp)
+	err = c.LoadWithPolicy(fp, policy)
 	if err != nil {
 		fp.Close()
 		return err
@@ -1188,26 +1496,55 @@ func (c *cache) LoadFile(fname string) error {
 	return fp.Close()
 }
 
-// Copies all unexpired items in the cache into a new map and returns it.
+// Copies all unexpired items in the cache into a new map and returns it. See
+// AtomicSnapshots for a consistent copy under concurrent writes.
 func (c *cache) Items() map[string]Item {
-	m := make(map[string]Item)
-	now := time.Now().UnixNano()
-	c.items.Range(func(key, value interface{}) bool {
-		v := value.(Item)
-		k := key.(string)
+	return c.ItemsWhere(nil)
+}
 
+// Like Items, but only copies the items for which pred returns true, e.g. to
+// export the items whose keys start with "order:" without copying the whole
+// cache first. pred is called during the pass over the cache, for unexpired
+// items only. A nil pred selects every item.
+func (c *cache) ItemsWhere(pred func(k string, v Item) bool) map[string]Item {
+	m := make(map[string]Item)
+	now := c.now().UnixNano()
+	c.rangeItems(func(k string, v Item) bool {
 		// "Inlining" of Expired
 		if v.Expiration > 0 {
 			if now > v.Expiration {
 				return true
 			}
 		}
-		m[k] = v
+		if pred == nil || pred(k, v) {
+			m[k] = v
+		}
 		return true
 	})
 	return m
 }
 
+// Calls fn for every unexpired item in the cache, with its key, value and
+// expiration time (or a zero time.Time if it never expires), until fn returns
+// false. Unlike Items, Range does not copy the cache. As with sync.Map.Range,
+// items set or deleted during the iteration may or may not be visited, and fn
+// may safely modify the cache.
+func (c *cache) Range(fn func(k string, v interface{}, exp time.Time) bool) {
+	now := c.now().UnixNano()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		var exp time.Time
+		// "Inlining" of Expired
+		if v.Expiration > 0 {
+			if now > v.Expiration {
+				return true
+			}
+			exp = time.Unix(0, v.Expiration)
+		}
+		return fn(key.(string), v.Object, exp)
+	})
+}
+
 // Returns the number of items in the cache. This may include items that have
 // expired, but have not yet been cleaned up.
 func (c *cache) ItemCount() int {
@@ -1223,61 +1560,286 @@ func (c *cache) ItemCount() int {
 // items that have expired, but have not yet been cleaned up. Equivalent to
 // len(c.Items()).
 func (c *cache) itemCount() int {
-	n := c.ItemCount()
-	return n
+	return int(atomic.LoadInt64(&c.count))
 }
 
 // Delete all items from the cache.
 func (c *cache) Flush() {
+	var disposed []interface{}
 	c.mu.Lock()
+	if c.AtomicSnapshots {
+		c.snap.Lock()
+	}
+	if c.DisposeValues {
+		c.items.Range(func(_, value interface{}) bool {
+			disposed = append(disposed, value.(Item).Object)
+			return true
+		})
+	}
 	c.items = sync.Map{}
+	atomic.StoreInt64(&c.count, 0)
+	c.tags.reset()
+	for _, ix := range c.indexes {
+		ix.reset()
+	}
+	if c.ordered != nil {
+		c.ordered.reset()
+	}
+	if c.bloom != nil {
+		c.bloom.reset()
+	}
+	if c.wal != nil {
+		c.wal.append(walRecord{Op: walFlush})
+	}
+	if c.AtomicSnapshots {
+		c.snap.Unlock()
+	}
+	c.mu.Unlock()
+	for _, x := range disposed {
+		dispose(x)
+	}
+}
+
+// Like Flush, but deletes the items one by one, passing each of them to the
+// eviction callback (with the reason EventFlush for a BatchEvictionCallback)
+// and to watchers. Returns the number of items deleted. This is slower than
+// Flush, which drops all the items at once.
+func (c *cache) FlushWithCallbacks() int {
+	var (
+		evicted []KeyValue
+		removed int
+	)
+	c.mu.Lock()
+	c.items.Range(func(key, value interface{}) bool {
+		k := key.(string)
+		if ov, ok := c.delete(k, EventFlush); ok {
+			evicted = append(evicted, KeyValue{k, ov, EventFlush})
+		}
+		removed++
+		return true
+	})
 	c.mu.Unlock()
+	c.evictMany(evicted)
+	return removed
+}
+
+// Delete all items from the cache except those for which keep returns true,
+// e.g. to clear the cache on a configuration reload while keeping a few
+// bootstrap entries. Returns the number of items deleted. Like Flush, it does
+// not call the eviction callback.
+func (c *cache) FlushExcept(keep func(k string, v interface{}) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	c.items.Range(func(key, value interface{}) bool {
+		k := key.(string)
+		if !keep(k, value.(Item).Object) {
+			c.delete(k, EventDelete)
+			removed++
+		}
+		return true
+	})
+	return removed
 }
 
 type janitor struct {
 	Interval time.Duration
 	stop     chan bool
+	exited   chan struct{} // closed when Run returns
+	done     <-chan struct{}
+	ticker   Ticker
+	paused   uint32
+	stopped  uint32
+	// Updated atomically; see JanitorStats.
+	lastTick     int64
+	lastRun      int64
+	lastDuration int64
+	scanned      int64
+	expired      int64
+	evicted      int64
+	runs         uint64
+	// The keys left to check by a bounded sweep; see JanitorBudget.
+	cursor []string
+	// The versions of the items ExpiryCallback was called for.
+	noticed map[string]uint64
 }
 
 func (j *janitor) Run(c *cache) {
-	j.stop = make(chan bool)
-	ticker := time.NewTicker(j.Interval)
+	defer func() {
+		j.ticker.Stop()
+		atomic.StoreUint32(&j.stopped, 1)
+		c.debug("cache: janitor stopped")
+		close(j.exited)
+	}()
 	for {
 		select {
-		case <-ticker.C:
-			c.DeleteExpired()
-			if c.CacheSize > 0 {
-				c.DeleteLRU()
+		case <-j.ticker.C():
+			atomic.StoreInt64(&j.lastTick, c.now().UnixNano())
+			if atomic.LoadUint32(&j.paused) == 1 {
+				continue
 			}
+			j.run(c)
 		case <-j.stop:
-			ticker.Stop()
+			return
+		case <-j.done:
 			return
 		}
 	}
 }
 
+func (j *janitor) run(c *cache) {
+	start := time.Now()
+	var expired, scanned int
+	if c.JanitorMaxItems > 0 || c.JanitorMaxTime > 0 {
+		expired, scanned = j.sweep(c)
+	} else {
+		expired, _, scanned = c.deleteExpired(false)
+	}
+	if c.sizer != nil {
+		c.sizer.adjust(c)
+	}
+	evicted := 0
+	if c.lru() {
+		evicted = c.DeleteLRU()
+	}
+	if c.ExpiryCallback != nil {
+		j.noticeExpiring(c)
+	}
+	d := time.Since(start)
+	if c.CleanupMax > 0 {
+		j.adapt(c, expired, scanned)
+	}
+	atomic.StoreInt64(&c.stats.janitorRun, int64(d))
+	atomic.StoreInt64(&j.lastRun, c.now().UnixNano())
+	atomic.StoreInt64(&j.lastDuration, int64(d))
+	atomic.StoreInt64(&j.scanned, int64(scanned))
+	atomic.StoreInt64(&j.expired, int64(expired))
+	atomic.StoreInt64(&j.evicted, int64(evicted))
+	atomic.AddUint64(&j.runs, 1)
+	c.debug("cache: janitor run", "expired", expired, "evicted", evicted, "duration", d)
+}
+
 func stopJanitor(c *Cache) {
-	c.janitor.stop <- true
+	atomic.StoreUint32(&c.janitor.stopped, 1)
+	close(c.janitor.stop)
+	<-c.janitor.exited
+}
+
+// Stop the goroutines running on behalf of the cache, and wait for them to
+// return, so that none of them touches the cache afterwards.
+func stopBackground(c *Cache) {
+	if c.janitor != nil {
+		stopJanitor(c)
+	}
+	if c.persister != nil {
+		close(c.persister.stop)
+		<-c.persister.exited
+	}
+	if c.wal != nil {
+		c.wal.close()
+	}
+	if c.callbacks != nil {
+		c.callbacks.close()
+	}
+	if c.memory != nil {
+		close(c.memory.stop)
+		<-c.memory.exited
+	}
+}
+
+// Reports whether the cache has goroutines or resources that stopBackground
+// releases.
+func (c *cache) hasBackground() bool {
+	return c.janitor != nil || c.persister != nil || c.wal != nil || c.callbacks != nil ||
+		c.memory != nil
 }
 
 func runJanitor(c *cache, ci time.Duration) {
 	j := &janitor{
 		Interval: ci,
+		stop:     make(chan bool),
+		exited:   make(chan struct{}),
+		// Created here rather than in Run, so that a FakeClock advanced
+		// right after New fires it.
+		ticker:   c.newTicker(ci),
+		lastTick: c.now().UnixNano(),
+	}
+	if c.Context != nil {
+		j.done = c.Context.Done()
 	}
 	c.janitor = j
 	go j.Run(c)
 }
 
 func newunexportedCache(items sync.Map, options *CacheOptions) *cache {
-	return &cache{
+	c := &cache{
 		items:        items,
 		CacheOptions: options,
 	}
+	if len(options.Indexes) > 0 {
+		c.indexes = newIndexes(options.Indexes)
+	}
+	if options.OrderedKeys {
+		c.ordered = newKeyList()
+	}
+	if options.BloomFilter > 0 {
+		c.bloom = newBloomFilter(options.BloomFilter)
+	}
+	c.items.Range(func(key, value interface{}) bool {
+		c.count++
+		if c.ordered != nil {
+			c.ordered.insert(key.(string))
+		}
+		if c.bloom != nil {
+			c.bloom.add(key.(string))
+		}
+		if c.indexes != nil {
+			c.reindex(key.(string), nil, false, value.(Item).Object)
+		}
+		if tags := value.(Item).Tags; len(tags) > 0 {
+			c.tags.add(key.(string), tags)
+		}
+		return true
+	})
+	if options.EventSink != nil {
+		c.events.setSink(options.EventSink)
+	}
+	if len(options.Middleware) > 0 {
+		c.chain = newChain(c, options.Middleware)
+	}
+	if options.HotKeys > 0 {
+		c.hotKeys = newHotKeys(options.HotKeys)
+	}
+	if options.HitRatioWindow > 0 {
+		c.window = newHitWindow(options.HitRatioWindow)
+	}
+	if options.SizeMax > 0 {
+		c.sizer = newSizer(options)
+	}
+	return c
 }
 
-func newCache(items sync.Map, options *CacheOptions) *Cache {
+func newCache(items sync.Map, options *CacheOptions) (*Cache, error) {
 
 	c := newunexportedCache(items, options)
+	if options.PersistPath != "" {
+		if err := c.loadPersisted(); err != nil {
+			return nil, err
+		}
+	}
+	if options.WAL {
+		if err := openWAL(c); err != nil {
+			return nil, err
+		}
+	}
+	if options.ExpvarName != "" {
+		if err := publishExpvar(c, options.ExpvarName); err != nil {
+			if c.wal != nil {
+				c.wal.close()
+			}
+			return nil, err
+		}
+	}
 
 	// This trick ensures that the janitor goroutine (which--granted it
 	// was enabled--is running DeleteExpired on c forever) does not keep
@@ -1286,20 +1848,91 @@ func newCache(items sync.Map, options *CacheOptions) *Cache {
 	// which c can be collected.
 	C := &Cache{c}
 
-	if options.CleanupInterval > 0 {
+	if options.CleanupMax > 0 {
+		runJanitor(c, clampInterval(options.CleanupInterval, options.CleanupMin, options.CleanupMax))
+	} else if options.CleanupInterval > 0 {
 		runJanitor(c, options.CleanupInterval)
-		runtime.SetFinalizer(C, stopJanitor)
 	}
-	return C
+	if options.PersistInterval > 0 {
+		runPersister(c, options.PersistInterval)
+	}
+	if options.CallbackWorkers > 0 {
+		c.callbacks = newCallbackPool(options.CallbackWorkers, options.CallbackQueue, options.CallbackPolicy)
+	}
+	if options.MemoryLimit > 0 {
+		runMemWatcher(c, options.MemoryInterval)
+	}
+	if c.hasBackground() {
+		runtime.SetFinalizer(C, stopBackground)
+	}
+	return C, nil
 }
 
 type CacheOptions struct {
-	Expiration       time.Duration
-	CleanupInterval  time.Duration
-	EvictionCallback func(string, interface{})
-	CacheSize        int
-	InitialItems     map[string]Item
-	Shards           int
+	Expiration         time.Duration
+	CleanupInterval    time.Duration
+	EvictionCallback   func(string, interface{})
+	BatchEviction      func([]KeyValue)
+	CacheSize          int
+	MaxBytes           int64
+	MaxCost            int64
+	Policy             EvictionPolicy
+	Admission          Admitter
+	AccessedResolution time.Duration
+	InitialItems       map[string]Item
+	Shards             int
+	ExpvarName         string
+	EvictOnClose       bool
+	Context            context.Context
+	Clock              Clock
+	EventSink          chan<- Event
+	Codec              Codec
+	Compression        Compression
+	EncryptionKey      []byte
+	RelativeTTL        bool
+	PersistPath        string
+	PersistInterval    time.Duration
+	WAL                bool
+	WALSyncInterval    time.Duration
+	CallbackWorkers    int
+	CallbackQueue      int
+	CallbackPolicy     QueuePolicy
+	EvictionsOnly      bool
+	Middleware         []Middleware
+	Logger             *slog.Logger
+	CopyOnGet          bool
+	CopyOnSet          bool
+	CopyFunc           func(interface{}) interface{}
+	HotKeys            int
+	JanitorMaxItems    int
+	JanitorMaxTime     time.Duration
+	CleanupMin         time.Duration
+	CleanupMax         time.Duration
+	EvictionSamples    int
+	HardCacheSize      int
+	EvictionTarget     float64
+	Quotas             []PrefixQuota
+	HitRatioWindow     time.Duration
+	SizeMin            int
+	SizeMax            int
+	SizeMaxHeap        uint64
+	MemoryLimit        uint64
+	MemoryInterval     time.Duration
+	OffHeap            bool
+	Hasher             func(string) uint64
+	MaxKeyLength       int
+	MaxValueBytes      int64
+	RefreshAhead       float64
+	ExpiryNotice       time.Duration
+	ExpiryCallback     func(string, interface{}, time.Time)
+	PrefixExpirations  []PrefixExpiration
+	DisposeValues      bool
+	AtomicSnapshots    bool
+	Indexes            map[string]func(interface{}) []string
+	OrderedKeys        bool
+	MaxListLength      int
+	BloomFilter        int
+	TrackAccess        bool
 }
 
 type CacheOption func(*CacheOptions) error
@@ -1313,9 +1946,17 @@ func GetDefaultOptions() *CacheOptions {
 		// no cleanup
 		CleanupInterval: 0,
 		// unlimited
-		CacheSize:    0,
-		InitialItems: nil,
-		Shards:       0,
+		CacheSize: 0,
+		MaxBytes:  0,
+		MaxCost:   0,
+		// least recently used items are evicted first
+		Policy: LRU(),
+		// every item is admitted
+		Admission: nil,
+		// every access is recorded
+		AccessedResolution: 0,
+		InitialItems:       nil,
+		Shards:             0,
 	}
 }
 
@@ -1347,6 +1988,65 @@ func CacheSize(c int) CacheOption {
 	}
 }
 
+// Never let the number of items in the cache exceed n. Unlike CacheSize, which
+// is only enforced by DeleteLRU and the janitor, so that a burst of Sets can
+// overshoot it, the limit is enforced by every Set (and Add, SetMulti,
+// SetWithCost, etc.) of a new key: if the cache is full, expired items are
+// deleted and then items are evicted according to the eviction policy before
+// the new item is stored. Concurrent Sets may briefly exceed the limit by one
+// item each. Unless EvictionTarget is set, a full cache evicts 1/64th of n
+// more than it needs to, so that the Sets that follow don't each have to scan
+// the cache.
+//
+// Both limits may be used together, e.g. a CacheSize for the janitor to trim
+// the cache to in the background, and a somewhat higher HardCacheSize to
+// bound it in between runs. Eviction callbacks are called by the Set that
+// caused the eviction.
+func HardCacheSize(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.HardCacheSize = n
+		return nil
+	}
+}
+
+// Limit the estimated total size of the items in the cache to n bytes. Like
+// CacheSize, the limit is enforced by DeleteLRU (and the janitor), which
+// removes the least recently used items until the cache is under the limit.
+// Values implementing Sized report their own size; the size of other values
+// is estimated.
+func MaxBytes(n int64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MaxBytes = n
+		return nil
+	}
+}
+
+// Only update an item's Accessed time (and Hits) on Get if it was last
+// updated more than d ago. When the cache is bounded, every Get otherwise
+// rewrites the item it returns, which is expensive for read-heavy workloads.
+// A resolution of e.g. one second makes the LRU order (and LFU counts)
+// slightly less precise, but turns most reads back into pure reads.
+func AccessedResolution(d time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.AccessedResolution = d
+		return nil
+	}
+}
+
+// Track the Created time, Accessed time and Hits of every item, which are
+// otherwise only tracked in caches with a size limit, where eviction needs
+// them. They can then be inspected with GetItem and Items, e.g. to find out
+// why an item is stale, or to refresh the most popular items. Hits counts the
+// retrievals of an item with Get and the like since it was last set, subject
+// to AccessedResolution. Tracking makes every Get record the access, like in
+// a bounded cache.
+func TrackAccess(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.TrackAccess = b
+		return nil
+	}
+}
+
 func InitialItems(i map[string]Item) CacheOption {
 	return func(m *CacheOptions) error {
 		m.InitialItems = i
@@ -1360,6 +2060,7 @@ func (c *Cache) Configure(options ...CacheOption) {
 	for _, opt := range options {
 		opt(c.CacheOptions)
 	}
+	c.debug("cache: configuration changed", "options", len(options))
 
 	c.mu.Unlock()
 }
@@ -1369,20 +2070,41 @@ func (c *Cache) Configure(options ...CacheOption) {
 // the items in the cache never expire (by default), and must be deleted
 // manually. If the cleanup interval is less than one, expired items are not
 // deleted from the cache before calling c.DeleteExpired().
+//
+// New returns nil if any of the options is invalid. Use NewWithError to find
+// out why.
 func New(options ...CacheOption) *Cache {
+	c, err := NewWithError(options...)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// Like New, but returns an error describing the problem if an option fails or
+// the options are invalid or conflict with each other.
+func NewWithError(options ...CacheOption) (*Cache, error) {
 
 	opts := GetDefaultOptions()
 
 	for _, opt := range options {
 		if err := opt(opts); err != nil {
-			return nil
+			return nil, err
 		}
 	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Hasher != nil {
+		// New does not shard the cache, so the Hasher would go unused.
+		return nil, fmt.Errorf("Hasher requires a sharded cache, which New does not create")
+	}
 
 	items := sync.Map{}
 
 	if opts.InitialItems != nil {
 		for k, v := range opts.InitialItems {
+			v.access = nil
 			items.Store(k, v)
 		}
 	}
@@ -2,9 +2,11 @@ package cache
 
 import (
 	"bytes"
+	"encoding/gob"
 	"io/ioutil"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -1173,6 +1175,79 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestRange(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, 1*time.Hour)
+	tc.Set("expired", 3, 1*time.Millisecond)
+	<-time.After(5 * time.Millisecond)
+
+	seen := map[string]interface{}{}
+	tc.Range(func(k string, v interface{}, exp time.Time) bool {
+		seen[k] = v
+		if k == "a" && !exp.IsZero() {
+			t.Error("expiration for a is not a zeroed time")
+		}
+		if k == "b" && exp.Before(time.Now()) {
+			t.Error("expiration for b is in the past")
+		}
+		return true
+	})
+	if len(seen) != 2 || seen["a"] != 1 || seen["b"] != 2 {
+		t.Error("Range visited the wrong items:", seen)
+	}
+
+	n := 0
+	tc.Range(func(string, interface{}, time.Time) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Error("Range did not stop when fn returned false:", n)
+	}
+}
+
+func TestDeleteByPrefix(t *testing.T) {
+	var evicted []string
+	tc := New(Expiration(DefaultExpiration), EvictionCallback(func(k string, v interface{}) {
+		evicted = append(evicted, k)
+	}))
+	tc.Set("user:1", 1, DefaultExpiration)
+	tc.Set("user:2", 2, DefaultExpiration)
+	tc.Set("session:1", 3, DefaultExpiration)
+	if n := tc.DeleteByPrefix("user:"); n != 2 {
+		t.Error("DeleteByPrefix did not delete 2 items:", n)
+	}
+	if len(evicted) != 2 {
+		t.Error("Eviction callback was not called for every deleted item:", evicted)
+	}
+	if _, found := tc.Get("session:1"); !found {
+		t.Error("session:1 was deleted")
+	}
+	if tc.ItemCount() != 1 {
+		t.Error("tc.ItemCount() is not 1")
+	}
+}
+
+func TestDeleteFunc(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	for i := 0; i < 10; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	n := tc.DeleteFunc(func(k string, v interface{}) bool {
+		return v.(int)%2 == 0
+	})
+	if n != 5 {
+		t.Error("DeleteFunc did not delete 5 items:", n)
+	}
+	if _, found := tc.Get("3"); !found {
+		t.Error("3 was deleted")
+	}
+	if _, found := tc.Get("4"); found {
+		t.Error("4 was not deleted")
+	}
+}
+
 func TestItemCount(t *testing.T) {
 	tc := New(Expiration(DefaultExpiration))
 	tc.Set("foo", "1", DefaultExpiration)
@@ -1204,6 +1279,51 @@ func TestFlush(t *testing.T) {
 	}
 }
 
+func TestFlushWithCallbacks(t *testing.T) {
+	var evicted []string
+	var reasons []EventType
+	tc := New(Expiration(DefaultExpiration),
+		EvictionCallback(func(k string, _ interface{}) { evicted = append(evicted, k) }),
+		BatchEvictionCallback(func(kvs []KeyValue) {
+			for _, v := range kvs {
+				reasons = append(reasons, v.Reason)
+			}
+		}))
+	tc.Set("foo", "bar", DefaultExpiration)
+	tc.Set("baz", "yes", DefaultExpiration)
+	if n := tc.FlushWithCallbacks(); n != 2 {
+		t.Error("FlushWithCallbacks deleted", n, "items instead of 2")
+	}
+	if len(evicted) != 2 {
+		t.Error("the eviction callback was called for", evicted)
+	}
+	if len(reasons) != 2 || reasons[0] != EventFlush || reasons[1] != EventFlush {
+		t.Error("the batch callback got the reasons", reasons)
+	}
+	if tc.ItemCount() != 0 {
+		t.Error("tc.ItemCount() is not 0")
+	}
+}
+
+func TestFlushExcept(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("boot:config", "x", DefaultExpiration)
+	tc.Set("foo", "bar", DefaultExpiration)
+	tc.Set("baz", "yes", DefaultExpiration)
+	n := tc.FlushExcept(func(k string, _ interface{}) bool {
+		return strings.HasPrefix(k, "boot:")
+	})
+	if n != 2 {
+		t.Error("FlushExcept deleted", n, "items instead of 2")
+	}
+	if _, found := tc.Get("boot:config"); !found {
+		t.Error("boot:config was deleted")
+	}
+	if tc.ItemCount() != 1 {
+		t.Error("tc.ItemCount() is not 1")
+	}
+}
+
 func TestIncrementOverflowInt(t *testing.T) {
 	tc := New(Expiration(DefaultExpiration))
 	tc.Set("int8", int8(127), DefaultExpiration)
@@ -1258,6 +1378,26 @@ func TestDeleteLRU(t *testing.T) {
 	}
 }
 
+func TestAccessedResolution(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), AccessedResolution(1*time.Hour))
+	tc.Set("foo", "bar", DefaultExpiration)
+	before, _ := tc.getItem("foo")
+	<-time.After(1 * time.Millisecond)
+	tc.Get("foo")
+	after, _ := tc.getItem("foo")
+	if after.Accessed != before.Accessed {
+		t.Error("Accessed was updated within the resolution")
+	}
+
+	tc.Configure(AccessedResolution(1 * time.Millisecond))
+	<-time.After(2 * time.Millisecond)
+	tc.Get("foo")
+	after, _ = tc.getItem("foo")
+	if after.Accessed == before.Accessed {
+		t.Error("Accessed was not updated after the resolution had passed")
+	}
+}
+
 func TestOnEvicted(t *testing.T) {
 
 	works := false
@@ -1781,3 +1921,196 @@ func TestGetWithExpiration(t *testing.T) {
 		t.Error("expiration for e is in the past")
 	}
 }
+
+func TestSaveLoadStream(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	for i := 0; i < 1000; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if err := readSnapshotHeader(r); err != nil {
+		t.Fatal(err)
+	}
+	dec := gob.NewDecoder(&chunkReader{r: r})
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil || header.Count != 1000 {
+		t.Error("Wrong snapshot header:", header, err)
+	}
+
+	oc := New(Expiration(DefaultExpiration))
+	oc.Set("1", "existing", DefaultExpiration)
+	if err := oc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := oc.ItemCount(); n != 1000 {
+		t.Error("Expected 1000 items after Load, got", n)
+	}
+	if x, _ := oc.Get("1"); x != "existing" {
+		t.Error("Load replaced an existing item:", x)
+	}
+	if x, _ := oc.Get("999"); x != 999 {
+		t.Error("Item 999 was not loaded:", x)
+	}
+}
+
+func TestDeleteExpiredReturnsCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	tc.Set("b", 2, time.Second)
+	tc.Set("c", 3, DefaultExpiration)
+	clock.Advance(2 * time.Second)
+
+	if n := tc.DeleteExpired(); n != 2 {
+		t.Error("DeleteExpired returned", n, "instead of 2")
+	}
+	if tc.ItemCount() != 1 {
+		t.Error("tc.ItemCount() is not 1")
+	}
+}
+
+func TestDeleteExpiredKeys(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	tc.Set("b", 2, DefaultExpiration)
+	clock.Advance(2 * time.Second)
+
+	keys := tc.DeleteExpiredKeys()
+	if len(keys) != 1 || keys[0] != "a" {
+		t.Error("DeleteExpiredKeys returned", keys, "instead of [a]")
+	}
+	if keys = tc.DeleteExpiredKeys(); len(keys) != 0 {
+		t.Error("DeleteExpiredKeys returned", keys, "on a second call")
+	}
+}
+
+func TestHas(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(2))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Second)
+	tc.Set("b", 2, time.Second)
+	clock.Advance(time.Second)
+	if !tc.Has("a") || !tc.Has("b") || tc.Has("c") {
+		t.Error("wrong presence")
+	}
+	if s := tc.Stats(); s.Hits != 0 || s.Misses != 0 {
+		t.Errorf("Has counted %d hits and %d misses", s.Hits, s.Misses)
+	}
+	// a is still the least recently used item.
+	tc.Set("c", 3, DefaultExpiration)
+	tc.DeleteLRU()
+	if tc.Has("a") {
+		t.Error("Has updated the recency of a")
+	}
+	clock.Advance(time.Second)
+	if tc.Has("b") {
+		t.Error("expired item is present")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(2))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Second)
+	tc.Set("b", 2, time.Second)
+	clock.Advance(time.Second)
+	if x, found := tc.Peek("a"); !found || x != 1 {
+		t.Errorf("got %v, %v; want 1", x, found)
+	}
+	if _, found := tc.Peek("c"); found {
+		t.Error("missing item was found")
+	}
+	if s := tc.Stats(); s.Hits != 0 || s.Misses != 0 {
+		t.Errorf("Peek counted %d hits and %d misses", s.Hits, s.Misses)
+	}
+	if item, _ := tc.Items()["a"]; item.Hits != 0 {
+		t.Errorf("Peek recorded %d hits", item.Hits)
+	}
+	tc.Set("c", 3, DefaultExpiration)
+	tc.DeleteLRU()
+	if _, found := tc.Peek("a"); found {
+		t.Error("Peek updated the recency of a")
+	}
+	clock.Advance(time.Second)
+	if _, found := tc.Peek("b"); found {
+		t.Error("expired item was found")
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(10))
+	tc.SetWithTags("a", "value", time.Minute, "t")
+	clock.Advance(time.Second)
+	tc.Get("a")
+	item, found := tc.GetItem("a")
+	if !found {
+		t.Fatal("item not found")
+	}
+	if item.Object != "value" || item.Size() != 5 || len(item.Tags) != 1 || item.Tags[0] != "t" {
+		t.Errorf("got %+v", item)
+	}
+	if !item.CreatedAt().Equal(time.Unix(1000, 0)) || !item.LastAccessed().Equal(clock.Now()) {
+		t.Errorf("created at %v, accessed at %v", item.CreatedAt(), item.LastAccessed())
+	}
+	if item.Hits != 2 {
+		t.Errorf("item has %d hits; want 2", item.Hits)
+	}
+	if _, found := tc.GetItem("missing"); found {
+		t.Error("missing item was found")
+	}
+	if (Item{}).CreatedAt() != (time.Time{}) {
+		t.Error("untracked creation time is not zero")
+	}
+}
+
+func TestTrackAccess(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), TrackAccess(true))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Second)
+	for i := 0; i < 3; i++ {
+		tc.Get("a")
+	}
+	item := tc.Items()["a"]
+	if item.Hits != 3 || !item.CreatedAt().Equal(time.Unix(1000, 0)) || !item.LastAccessed().Equal(clock.Now()) {
+		t.Errorf("got %d hits, created at %v, accessed at %v", item.Hits, item.CreatedAt(), item.LastAccessed())
+	}
+	tc.Set("a", 2, DefaultExpiration)
+	if item := tc.Items()["a"]; item.Hits != 0 || !item.CreatedAt().Equal(clock.Now()) {
+		t.Errorf("setting the item again left %d hits, created at %v", item.Hits, item.CreatedAt())
+	}
+
+	untracked := New(Expiration(DefaultExpiration))
+	untracked.Set("a", 1, DefaultExpiration)
+	untracked.Get("a")
+	if item := untracked.Items()["a"]; item.Hits != 0 || item.Created != 0 {
+		t.Errorf("untracked item has %d hits, created %d", item.Hits, item.Created)
+	}
+}
+
+func TestItemsWhere(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("order:1", 1, DefaultExpiration)
+	tc.Set("order:2", 2, DefaultExpiration)
+	tc.Set("order:3", 3, time.Nanosecond)
+	tc.Set("user:1", 4, DefaultExpiration)
+	time.Sleep(time.Millisecond)
+	items := tc.ItemsWhere(func(k string, v Item) bool {
+		return strings.HasPrefix(k, "order:")
+	})
+	if len(items) != 2 || items["order:1"].Object != 1 || items["order:2"].Object != 2 {
+		t.Errorf("got %v; want the unexpired orders", items)
+	}
+	if n := len(tc.ItemsWhere(nil)); n != 3 {
+		t.Errorf("ItemsWhere(nil) returned %d items; want 3", n)
+	}
+}
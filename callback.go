@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A KeyValue is the key and value of an item removed from the cache, as
+// passed to a BatchEvictionCallback, and the reason it was removed: one of
+// EventDelete, EventExpire, EventEvict and EventFlush.
+type KeyValue struct {
+	Key    string
+	Value  interface{}
+	Reason EventType
+}
+
+// Call cb once per deletion with every item it removed, instead of calling
+// the EvictionCallback once per item. A janitor run, for instance, results in
+// (at most) one call for the expired items and one for the items evicted
+// because the cache was over its size limit.
+//
+// If both callbacks are set, both are called.
+func BatchEvictionCallback(cb func([]KeyValue)) CacheOption {
+	return func(m *CacheOptions) error {
+		m.BatchEviction = cb
+		return nil
+	}
+}
+
+// If enabled, the eviction callbacks are only called for items that expired,
+// were evicted because the cache was over its size limit, or were removed by
+// Close (see EvictOnClose), and not for items removed by Delete, DeleteFunc,
+// DeleteByPrefix, InvalidateTag or FlushWithCallbacks. This suits callbacks
+// that reload evicted data, for which deletions by the caller are intentional
+// purges.
+func EvictionsOnly(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.EvictionsOnly = b
+		return nil
+	}
+}
+
+func (c *cache) hasEvictionCallback() bool {
+	return c.EvictionCallback != nil || c.BatchEviction != nil
+}
+
+// Pass a single removed item to the eviction callbacks.
+func (c *cache) evictOne(k string, v interface{}, reason EventType) {
+	if c.callbacks != nil && c.callbacks.submit(c, func() { c.runEvictOne(k, v, reason) }) {
+		return
+	}
+	c.runEvictOne(k, v, reason)
+}
+
+func (c *cache) runEvictOne(k string, v interface{}, reason EventType) {
+	if c.BatchEviction != nil {
+		c.BatchEviction([]KeyValue{{k, v, reason}})
+	}
+	if c.EvictionCallback != nil {
+		c.EvictionCallback(k, v)
+	}
+}
+
+// Pass the items removed by a single deletion to the eviction callbacks.
+func (c *cache) evictMany(evicted []KeyValue) {
+	if len(evicted) == 0 {
+		return
+	}
+	if c.callbacks != nil && c.callbacks.submit(c, func() { c.runEvictMany(evicted) }) {
+		return
+	}
+	c.runEvictMany(evicted)
+}
+
+func (c *cache) runEvictMany(evicted []KeyValue) {
+	if c.BatchEviction != nil {
+		c.BatchEviction(evicted)
+	}
+	if c.EvictionCallback != nil {
+		for _, v := range evicted {
+			c.EvictionCallback(v.Key, v.Value)
+		}
+	}
+}
+
+// What an asynchronous callback pool does when its queue is full.
+type QueuePolicy int
+
+const (
+	// Wait for room in the queue, stalling the deletion (and the janitor)
+	// until a worker catches up.
+	BlockWhenFull QueuePolicy = iota
+	// Discard the callback. Dropped callbacks are counted in
+	// Stats.DroppedCallbacks.
+	DropWhenFull
+)
+
+// Run the eviction callbacks on the given number of worker goroutines instead
+// of on the goroutine deleting the items, so that slow callbacks do not stall
+// Delete or the janitor. Up to queueSize pending calls are buffered; what
+// happens when the queue is full is decided by policy. Callbacks may run
+// concurrently with each other and in a different order than the deletions.
+//
+// Close waits for the queued callbacks to finish; callbacks for items deleted
+// after that are run synchronously.
+func AsyncCallbacks(workers, queueSize int, policy QueuePolicy) CacheOption {
+	return func(m *CacheOptions) error {
+		m.CallbackWorkers = workers
+		m.CallbackQueue = queueSize
+		m.CallbackPolicy = policy
+		return nil
+	}
+}
+
+type callbackPool struct {
+	mu     sync.RWMutex
+	queue  chan func()
+	policy QueuePolicy
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newCallbackPool(workers, queueSize int, policy QueuePolicy) *callbackPool {
+	p := &callbackPool{
+		queue:  make(chan func(), queueSize),
+		policy: policy,
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.run()
+	}
+	return p
+}
+
+func (p *callbackPool) run() {
+	defer p.wg.Done()
+	for f := range p.queue {
+		f()
+	}
+}
+
+// Queue f, returning false if the pool has been closed and f must be run by
+// the caller.
+func (p *callbackPool) submit(c *cache, f func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	if p.policy == DropWhenFull {
+		select {
+		case p.queue <- f:
+		default:
+			atomic.AddUint64(&c.stats.droppedCallbacks, 1)
+		}
+		return true
+	}
+	p.queue <- f
+	return true
+}
+
+// Stop accepting callbacks, and wait for the queued ones to finish.
+func (p *callbackPool) close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
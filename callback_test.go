@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchEvictionCallback(t *testing.T) {
+	var batches [][]KeyValue
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), BatchEvictionCallback(func(kvs []KeyValue) {
+		batches = append(batches, kvs)
+	}))
+	tc.Set("a", 1, time.Second)
+	tc.Set("b", 2, time.Second)
+	tc.Set("c", 3, DefaultExpiration)
+	clock.Advance(2 * time.Second)
+
+	tc.DeleteExpired()
+	if len(batches) != 1 {
+		t.Fatal("got", len(batches), "batches instead of 1")
+	}
+	b := batches[0]
+	sort.Slice(b, func(i, j int) bool { return b[i].Key < b[j].Key })
+	if len(b) != 2 || b[0] != (KeyValue{"a", 1, EventExpire}) || b[1] != (KeyValue{"b", 2, EventExpire}) {
+		t.Error("unexpected batch:", b)
+	}
+
+	tc.DeleteExpired()
+	if len(batches) != 1 {
+		t.Error("an empty batch was delivered")
+	}
+
+	tc.Delete("c")
+	if len(batches) != 2 || len(batches[1]) != 1 || batches[1][0] != (KeyValue{"c", 3, EventDelete}) {
+		t.Error("unexpected batches after Delete:", batches)
+	}
+}
+
+func TestBatchAndPerItemEvictionCallbacks(t *testing.T) {
+	var batched, single int
+	tc := New(Expiration(DefaultExpiration), CacheSize(1),
+		EvictionCallback(func(string, interface{}) { single++ }),
+		BatchEvictionCallback(func(kvs []KeyValue) { batched += len(kvs) }))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+
+	tc.DeleteLRU()
+	if batched != 2 || single != 2 {
+		t.Error("batched and single are", batched, "and", single, "instead of 2 and 2")
+	}
+}
+
+func TestAsyncCallbacks(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan string, 10)
+	tc := New(Expiration(DefaultExpiration), AsyncCallbacks(1, 10, BlockWhenFull),
+		EvictionCallback(func(k string, _ interface{}) {
+			<-release
+			done <- k
+		}))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+
+	// Neither Delete waits for the blocked callback.
+	tc.Delete("a")
+	tc.Delete("b")
+	close(release)
+	if k := <-done; k != "a" {
+		t.Error("first callback was for", k)
+	}
+	if k := <-done; k != "b" {
+		t.Error("second callback was for", k)
+	}
+	tc.Close()
+}
+
+func TestAsyncCallbacksDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	tc := New(Expiration(DefaultExpiration), AsyncCallbacks(1, 1, DropWhenFull),
+		EvictionCallback(func(string, interface{}) {
+			<-release
+			atomic.AddInt32(&calls, 1)
+		}))
+	started := false
+	for i := 0; i < 10; i++ {
+		k := strconv.Itoa(i)
+		tc.Set(k, i, DefaultExpiration)
+		tc.Delete(k)
+		if !started {
+			// Let the worker pick up the first callback, so that the
+			// queue holds exactly one more.
+			for len(tc.callbacks.queue) != 0 {
+				runtime.Gosched()
+			}
+			started = true
+		}
+	}
+	close(release)
+	tc.Close()
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Error("callback was called", n, "times instead of 2")
+	}
+	if s := tc.Stats(); s.DroppedCallbacks != 8 {
+		t.Error("DroppedCallbacks is", s.DroppedCallbacks, "instead of 8")
+	}
+}
+
+func TestAsyncCallbacksAfterClose(t *testing.T) {
+	var called bool
+	tc := New(Expiration(DefaultExpiration), AsyncCallbacks(2, 0, BlockWhenFull),
+		EvictOnClose(true), EvictionCallback(func(string, interface{}) {
+			called = true
+		}))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Close()
+	if !called {
+		t.Error("EvictOnClose callback did not run before Close returned")
+	}
+}
+
+func TestAsyncCallbacksValidation(t *testing.T) {
+	if _, err := NewWithError(AsyncCallbacks(-1, 0, BlockWhenFull)); err == nil {
+		t.Error("negative worker count was accepted")
+	}
+	if _, err := NewWithError(AsyncCallbacks(1, 0, QueuePolicy(5))); err == nil {
+		t.Error("unknown QueuePolicy was accepted")
+	}
+}
+
+func TestDeleteWithoutEvictionCallback(t *testing.T) {
+	// Tags make delete load the item even though there is no callback.
+	tc := New(Expiration(DefaultExpiration))
+	tc.SetWithTags("a", 1, DefaultExpiration, "t")
+	tc.Delete("a")
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found after Delete")
+	}
+}
+
+func TestEvictionsOnly(t *testing.T) {
+	var got []string
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), EvictionsOnly(true),
+		EvictionCallback(func(k string, _ interface{}) { got = append(got, k) }))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, time.Second)
+
+	tc.Delete("a")
+	tc.DeleteFunc(func(k string, _ interface{}) bool { return k == "b" })
+	if len(got) != 0 {
+		t.Error("callback was called for caller deletes:", got)
+	}
+	if tc.ItemCount() != 1 {
+		t.Error("tc.ItemCount() is not 1")
+	}
+
+	clock.Advance(2 * time.Second)
+	tc.DeleteExpired()
+	if len(got) != 1 || got[0] != "c" {
+		t.Error("callback was not called for the expired item:", got)
+	}
+}
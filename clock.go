@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// EvictionPolicies implementing AccessTracker record accesses themselves. When
+// a cache's policy implements it, Get calls Access instead of rewriting the
+// item to update its Accessed time and Hits, so reads never write to the
+// underlying map. Accessed and Hits are therefore not maintained for such
+// caches.
+type AccessTracker interface {
+	// Record that k was retrieved. Called concurrently from every Get that
+	// finds an unexpired item.
+	Access(k string)
+}
+
+// The CLOCK policy keeps a reference bit for every key that has been
+// retrieved, which is set atomically on access.
+type clockPolicy struct {
+	refs sync.Map // string -> *uint32
+}
+
+// Returns a CLOCK (second chance) policy. Instead of recording the time of
+// every access, which turns every Get into a write, it sets a reference bit
+// for the item. When items must be evicted, items whose bit is clear are
+// evicted first, oldest first, and then the bits of all items are cleared,
+// so an item survives eviction passes only if it keeps being used.
+//
+// The returned policy keeps state about the cache's keys, and must not be
+// shared between caches.
+func CLOCK() EvictionPolicy {
+	return &clockPolicy{}
+}
+
+// Returns a copy of the reference bits for a clone of the cache (see Clone).
+func (p *clockPolicy) copyState() interface{} {
+	np := &clockPolicy{}
+	p.refs.Range(func(key, value interface{}) bool {
+		ref := atomic.LoadUint32(value.(*uint32))
+		np.refs.Store(key, &ref)
+		return true
+	})
+	return np
+}
+
+func (p *clockPolicy) Access(k string) {
+	if v, found := p.refs.Load(k); found {
+		ref := v.(*uint32)
+		if atomic.LoadUint32(ref) == 0 {
+			atomic.StoreUint32(ref, 1)
+		}
+		return
+	}
+	one := uint32(1)
+	if v, loaded := p.refs.LoadOrStore(k, &one); loaded {
+		atomic.StoreUint32(v.(*uint32), 1)
+	}
+}
+
+func (p *clockPolicy) referenced(k string) bool {
+	v, found := p.refs.Load(k)
+	return found && atomic.LoadUint32(v.(*uint32)) == 1
+}
+
+func (p *clockPolicy) Order(candidates []Candidate) {
+	ref := make(map[string]bool, len(candidates))
+	for _, v := range candidates {
+		ref[v.Key] = p.referenced(v.Key)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if ref[a.Key] != ref[b.Key] {
+			return !ref[a.Key]
+		}
+		return a.Item.Created < b.Item.Created
+	})
+	// Give every item a second chance, and forget keys that are no longer
+	// in the cache.
+	p.refs.Range(func(key, value interface{}) bool {
+		if _, found := ref[key.(string)]; found {
+			atomic.StoreUint32(value.(*uint32), 0)
+		} else {
+			p.refs.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCLOCK(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), Policy(CLOCK()))
+	tc.Set("a", 1, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Get("a")
+	tc.Get("c")
+
+	if item, _ := tc.getItem("a"); item.Hits != 0 {
+		t.Error("Get rewrote the item even though the policy tracks accesses")
+	}
+
+	tc.DeleteLRU()
+	if _, found := tc.getItem("b"); found {
+		t.Error("b was found, but it should have been evicted")
+	}
+	if tc.ItemCount() != 2 {
+		t.Error("tc.ItemCount() is not 2")
+	}
+
+	// a and c have now lost their reference bits, so the oldest is evicted
+	tc.DeleteLRUAmount(1)
+	if _, found := tc.getItem("a"); found {
+		t.Error("a was found, but it should have been evicted")
+	}
+}
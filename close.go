@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by operations on a cache that has been closed.
+var ErrClosed = errors.New("cache: closed")
+
+// If enabled, Close passes every item remaining in the cache to the eviction
+// callback before removing it.
+func EvictOnClose(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.EvictOnClose = b
+		return nil
+	}
+}
+
+func (c *cache) isClosed() bool {
+	return atomic.LoadUint32(&c.closed) == 1
+}
+
+// Close the cache, stopping its janitor and its other background goroutines
+// and waiting for them to return. If the cache has a PersistPath, a final
+// snapshot is written to it, and any error doing so is returned. Then, if the
+// EvictOnClose option is set, the remaining items are removed and passed to
+// the eviction callback.
+//
+// After Close, Set and its variants do nothing, Get and its variants find
+// nothing, and Add, Replace and the Increment and Decrement methods return
+// ErrClosed. Close returns ErrClosed if the cache was already closed.
+func (c *Cache) Close() error {
+	if !atomic.CompareAndSwapUint32(&c.closed, 0, 1) {
+		return ErrClosed
+	}
+	var err error
+	if c.PersistPath != "" {
+		err = c.Persist()
+	}
+	if c.hasBackground() {
+		runtime.SetFinalizer(c, nil)
+		stopBackground(c)
+	}
+	if c.EvictOnClose {
+		var evicted []KeyValue
+		c.items.Range(func(key, value interface{}) bool {
+			k := key.(string)
+			if ov, ok := c.delete(k, EventEvict); ok {
+				evicted = append(evicted, KeyValue{k, ov, EventEvict})
+			}
+			return true
+		})
+		c.evictMany(evicted)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	tc := New(Expiration(DefaultExpiration), CleanupInterval(1*time.Millisecond))
+	tc.Set("foo", 1, DefaultExpiration)
+	if err := tc.Close(); err != nil {
+		t.Fatal("Error closing cache:", err)
+	}
+	<-time.After(5 * time.Millisecond)
+	if n := runtime.NumGoroutine(); n > before {
+		t.Error("Janitor is still running after Close")
+	}
+
+	if err := tc.Close(); err != ErrClosed {
+		t.Error("Closing twice did not return ErrClosed:", err)
+	}
+	if _, found := tc.Get("foo"); found {
+		t.Error("foo was found after Close")
+	}
+	tc.Set("bar", 2, DefaultExpiration)
+	if _, found := tc.getItem("bar"); found {
+		t.Error("bar was set after Close")
+	}
+	if err := tc.Add("baz", 3, DefaultExpiration); err != ErrClosed {
+		t.Error("Add did not return ErrClosed:", err)
+	}
+	if err := tc.Increment("foo", 1); err != ErrClosed {
+		t.Error("Increment did not return ErrClosed:", err)
+	}
+	if _, err := tc.IncrementInt("foo", 1); err != ErrClosed {
+		t.Error("IncrementInt did not return ErrClosed:", err)
+	}
+}
+
+func TestEvictOnClose(t *testing.T) {
+	evicted := map[string]interface{}{}
+	tc := New(Expiration(DefaultExpiration), EvictOnClose(true), EvictionCallback(func(k string, v interface{}) {
+		evicted[k] = v
+	}))
+	tc.Set("foo", 1, DefaultExpiration)
+	tc.Set("bar", 2, DefaultExpiration)
+	tc.Close()
+	if len(evicted) != 2 || evicted["foo"] != 1 || evicted["bar"] != 2 {
+		t.Error("Remaining items were not evicted on Close:", evicted)
+	}
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("ItemCount is not 0:", n)
+	}
+}
+
+func TestCloseWaitsForJanitor(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	running := make(chan bool)
+	release := make(chan bool)
+	tc := New(Expiration(time.Second), CleanupInterval(time.Minute), WithClock(clock),
+		EvictionCallback(func(string, interface{}) {
+			running <- true
+			<-release
+		}))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Minute)
+	<-running
+
+	closed := make(chan bool)
+	go func() {
+		tc.Close()
+		closed <- true
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while the janitor was running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-closed
+}
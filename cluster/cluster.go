@@ -0,0 +1,189 @@
+// Package cluster propagates changes between several instances of a
+// cache.Cache over a pluggable Transport.
+//
+// Sets and deletes made through a Node are applied to the local cache and
+// broadcast to the other nodes, which apply them to their own caches. Every
+// message carries the ID of the node that sent it, so that a node never
+// re-applies its own changes, even on transports that echo messages back to
+// the sender. By default a broadcast Set only invalidates the key on other
+// nodes; if a Codec is configured, the value is sent along and stored.
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// A Transport broadcasts messages to all nodes in a cluster.
+type Transport interface {
+	// Send data to every node subscribed to the transport. Whether the
+	// sender receives its own messages depends on the transport.
+	Publish(data []byte) error
+	// Call handler with every message received until the returned function
+	// is called.
+	Subscribe(handler func(data []byte)) (unsubscribe func() error, err error)
+}
+
+// A Codec converts values to and from the bytes sent to other nodes.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+type Option func(*Node)
+
+// Send values to other nodes using codec, instead of invalidating the key.
+func WithCodec(codec Codec) Option {
+	return func(n *Node) {
+		n.codec = codec
+	}
+}
+
+// Identify the node as id, instead of a randomly generated ID. IDs must be
+// unique within the cluster.
+func WithNodeID(id string) Option {
+	return func(n *Node) {
+		n.id = id
+	}
+}
+
+// Called with errors that occur while applying messages received from other
+// nodes. By default they are ignored.
+func WithErrorHandler(fn func(error)) Option {
+	return func(n *Node) {
+		n.onError = fn
+	}
+}
+
+const (
+	opSet    = "set"
+	opDelete = "delete"
+)
+
+type message struct {
+	Node  string `json:"node"`
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	// Absolute expiration in UnixNano, or 0 for none.
+	Expiration int64 `json:"exp,omitempty"`
+}
+
+// A Node is a member of a cluster of caches.
+type Node struct {
+	c           *cache.Cache
+	t           Transport
+	id          string
+	codec       Codec
+	onError     func(error)
+	unsubscribe func() error
+}
+
+// Join the cluster reachable through t, keeping c in sync with the other
+// nodes until Close is called.
+func New(c *cache.Cache, t Transport, opts ...Option) (*Node, error) {
+	n := &Node{
+		c:       c,
+		t:       t,
+		onError: func(error) {},
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	if n.id == "" {
+		var b [8]byte
+		rand.Read(b[:])
+		n.id = hex.EncodeToString(b[:])
+	}
+	unsubscribe, err := t.Subscribe(func(data []byte) {
+		if err := n.handle(data); err != nil {
+			n.onError(err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	n.unsubscribe = unsubscribe
+	return n, nil
+}
+
+// Returns the ID identifying the node to the rest of the cluster.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Leave the cluster. The local cache is left as it is.
+func (n *Node) Close() error {
+	return n.unsubscribe()
+}
+
+// Add an item to the local cache, replacing any existing item, and broadcast
+// the change to the other nodes.
+func (n *Node) Set(k string, x interface{}, d time.Duration) error {
+	n.c.Set(k, x, d)
+	msg := message{Op: opSet, Key: k}
+	if n.codec != nil {
+		v, err := n.codec.Marshal(x)
+		if err != nil {
+			// The local Set has happened, so the other nodes must at
+			// least drop their old value.
+			if perr := n.publish(message{Op: opDelete, Key: k}); perr != nil {
+				return perr
+			}
+			return err
+		}
+		msg.Value = v
+		if _, exp, found := n.c.GetWithExpiration(k); found && !exp.IsZero() {
+			msg.Expiration = exp.UnixNano()
+		}
+	}
+	return n.publish(msg)
+}
+
+// Delete an item from the local cache and from the caches of the other nodes.
+func (n *Node) Delete(k string) error {
+	n.c.Delete(k)
+	return n.publish(message{Op: opDelete, Key: k})
+}
+
+func (n *Node) publish(msg message) error {
+	msg.Node = n.id
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return n.t.Publish(b)
+}
+
+func (n *Node) handle(data []byte) error {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.Node == n.id {
+		return nil
+	}
+	if msg.Op != opSet || n.codec == nil || msg.Value == nil {
+		n.c.Delete(msg.Key)
+		return nil
+	}
+	x, err := n.codec.Unmarshal(msg.Value)
+	if err != nil {
+		n.c.Delete(msg.Key)
+		return err
+	}
+	d := cache.NoExpiration
+	if msg.Expiration > 0 {
+		d = time.Until(time.Unix(0, msg.Expiration))
+		if d <= 0 {
+			n.c.Delete(msg.Key)
+			return nil
+		}
+	}
+	n.c.Set(msg.Key, x, d)
+	return nil
+}
@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// An in-memory transport which, like NATS, echoes messages to the sender.
+type bus struct {
+	mu       sync.Mutex
+	handlers map[int]func([]byte)
+	next     int
+}
+
+func (b *bus) Publish(data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, h := range b.handlers {
+		h(data)
+	}
+	return nil
+}
+
+func (b *bus) Subscribe(handler func([]byte)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[int]func([]byte))
+	}
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() error {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+		return nil
+	}, nil
+}
+
+type intCodec struct{}
+
+func (intCodec) Marshal(v interface{}) ([]byte, error) {
+	i, ok := v.(int)
+	if !ok {
+		return nil, fmt.Errorf("not an int: %v", v)
+	}
+	return []byte(strconv.Itoa(i)), nil
+}
+
+func (intCodec) Unmarshal(data []byte) (interface{}, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestInvalidate(t *testing.T) {
+	b := &bus{}
+	ca, cb := cache.New(), cache.New()
+	na, err := New(ca, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, _ := New(cb, b)
+	if na.ID() == nb.ID() {
+		t.Fatal("Generated node IDs are not unique")
+	}
+
+	cb.Set("foo", 1, cache.DefaultExpiration)
+	na.Set("foo", 2, cache.DefaultExpiration)
+	if x, found := ca.Get("foo"); !found || x != 2 {
+		t.Error("Node invalidated its own Set:", x, found)
+	}
+	if _, found := cb.Get("foo"); found {
+		t.Error("Set did not invalidate the key on another node")
+	}
+
+	ca.Set("bar", 1, cache.DefaultExpiration)
+	nb.Delete("bar")
+	if _, found := ca.Get("bar"); found {
+		t.Error("Delete was not applied on another node")
+	}
+
+	nb.Close()
+	cb.Set("foo", 3, cache.DefaultExpiration)
+	na.Set("foo", 4, cache.DefaultExpiration)
+	if x, _ := cb.Get("foo"); x != 3 {
+		t.Error("Node received a message after Close:", x)
+	}
+}
+
+func TestCodec(t *testing.T) {
+	b := &bus{}
+	ca, cb := cache.New(), cache.New()
+	na, _ := New(ca, b, WithCodec(intCodec{}), WithNodeID("a"))
+	New(cb, b, WithCodec(intCodec{}), WithNodeID("b"))
+
+	na.Set("foo", 42, time.Hour)
+	x, exp, found := cb.GetWithExpiration("foo")
+	if !found || x != 42 {
+		t.Error("Set did not send the value to another node:", x, found)
+	}
+	if until := time.Until(exp); until < 59*time.Minute || until > time.Hour {
+		t.Error("Expiration was not sent to another node:", exp)
+	}
+}
+
+func TestBadMessage(t *testing.T) {
+	var errs []error
+	b := &bus{}
+	New(cache.New(), b, WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	b.Publish([]byte("not json"))
+	if len(errs) != 1 {
+		t.Error("Expected the error handler to be called once, got", len(errs))
+	}
+}
+
+func TestCodecErrorInvalidates(t *testing.T) {
+	b := &bus{}
+	ca, cb := cache.New(), cache.New()
+	na, _ := New(ca, b, WithCodec(intCodec{}), WithNodeID("a"))
+	New(cb, b, WithCodec(intCodec{}), WithNodeID("b"))
+
+	cb.Set("foo", 1, cache.DefaultExpiration)
+	if err := na.Set("foo", "not an int", cache.DefaultExpiration); err == nil {
+		t.Fatal("Set of a value the codec can't marshal returned no error")
+	}
+	if _, found := cb.Get("foo"); found {
+		t.Error("A failed marshal left the old value on another node")
+	}
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+type natsTransport struct {
+	nc      *nats.Conn
+	subject string
+}
+
+// Returns a Transport broadcasting messages on the given NATS subject. NATS
+// delivers a connection's messages back to its own subscriptions, which the
+// Node ignores.
+func NATS(nc *nats.Conn, subject string) Transport {
+	return &natsTransport{
+		nc:      nc,
+		subject: subject,
+	}
+}
+
+func (t *natsTransport) Publish(data []byte) error {
+	return t.nc.Publish(t.subject, data)
+}
+
+func (t *natsTransport) Subscribe(handler func([]byte)) (func() error, error) {
+	sub, err := t.nc.Subscribe(t.subject, func(m *nats.Msg) {
+		handler(m.Data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub.Unsubscribe, nil
+}
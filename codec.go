@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// A Codec serializes the values written by Save and read by Load.
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// An Encoder writes a stream of values.
+type Encoder interface {
+	Encode(v interface{}) error
+}
+
+// A Decoder reads a stream of values written by the matching Encoder. Decode
+// must return io.EOF at the end of the stream.
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+// Use codec to serialize snapshots written by Save and read by Load. The
+// default is GobCodec.
+func WithCodec(codec Codec) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Codec = codec
+		return nil
+	}
+}
+
+type gobCodec struct{}
+
+// GobCodec serializes snapshots using encoding/gob. Item values keep their Go
+// types, which are registered with gob automatically, but snapshots can only
+// be read by Go programs that know the same types.
+var GobCodec Codec = gobCodec{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder {
+	return gobEncoder{gob.NewEncoder(w)}
+}
+
+func (gobCodec) NewDecoder(r io.Reader) Decoder {
+	return gob.NewDecoder(r)
+}
+
+type gobEncoder struct {
+	*gob.Encoder
+}
+
+func (e gobEncoder) Encode(v interface{}) error {
+	var x interface{}
+	switch rec := v.(type) {
+	case snapshotRecord:
+		x = rec.Item.Object
+	case walRecord:
+		x = rec.Item.Object
+	}
+	if x != nil {
+		gob.Register(x)
+	}
+	return e.Encoder.Encode(v)
+}
+
+type jsonCodec struct{}
+
+// JSONCodec serializes snapshots as a stream of JSON objects, one per line,
+// which can be read by other tools. Values are decoded into the generic types
+// of encoding/json: numbers become float64, structs become
+// map[string]interface{}, and so on.
+var JSONCodec Codec = jsonCodec{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder {
+	return json.NewEncoder(w)
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	return json.NewDecoder(r)
+}
+
+func (c *cache) codec() Codec {
+	if c.Codec != nil {
+		return c.Codec
+	}
+	return GobCodec
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONCodec(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), WithCodec(JSONCodec))
+	tc.Set("foo", "bar", time.Hour)
+	tc.Set("num", 42, DefaultExpiration)
+	tc.Set("struct", TestStruct{Num: 1}, DefaultExpiration)
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Key":"foo"`) {
+		t.Error("Snapshot is not JSON:", buf.String())
+	}
+
+	oc := New(Expiration(DefaultExpiration), WithCodec(JSONCodec))
+	if err := oc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	x, exp, found := oc.GetWithExpiration("foo")
+	if !found || x != "bar" || exp.IsZero() {
+		t.Error("foo was not loaded with its expiration:", x, exp)
+	}
+	if x, _ := oc.Get("num"); x != float64(42) {
+		t.Errorf("num was not loaded as a float64: %#v", x)
+	}
+	if x, _ := oc.Get("struct"); x.(map[string]interface{})["Num"] != float64(1) {
+		t.Errorf("struct was not loaded as a map: %#v", x)
+	}
+}
+
+func TestCodecMismatch(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("foo", "bar", DefaultExpiration)
+	buf := &bytes.Buffer{}
+	tc.Save(buf)
+	if err := New(WithCodec(JSONCodec)).Load(buf); err == nil {
+		t.Error("Expected an error loading a Gob snapshot with JSONCodec")
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// A Compression algorithm for snapshots.
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	// Smaller snapshots, at a higher CPU cost.
+	Gzip
+	// Less compression than Gzip, but much faster.
+	Snappy
+)
+
+const (
+	gzipMagic   = "\x1f\x8b"
+	snappyMagic = "\xff\x06\x00\x00sNaPpY"
+)
+
+// Compress snapshots written by Save (and SaveFile and Persist) using
+// compression. Load detects compressed snapshots by themselves, so the
+// option can be changed without breaking existing snapshots.
+func Compress(compression Compression) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Compression = compression
+		return nil
+	}
+}
+
+// Returns a writer compressing into w, or nil if snapshots are not
+// compressed.
+func (c *cache) compressor(w io.Writer) io.WriteCloser {
+	switch c.Compression {
+	case Gzip:
+		return gzip.NewWriter(w)
+	case Snappy:
+		return snappy.NewBufferedWriter(w)
+	}
+	return nil
+}
+
+// Returns a reader decompressing r if it starts with the magic number of a
+// supported compression format, and reading r as it is otherwise.
+func decompressor(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(snappyMagic))
+	switch {
+	case bytes.HasPrefix(magic, []byte(gzipMagic)):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte(snappyMagic)):
+		return snappy.NewReader(br), nil
+	}
+	return br, nil
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	for _, compression := range []Compression{NoCompression, Gzip, Snappy} {
+		tc := New(Expiration(DefaultExpiration), Compress(compression))
+		tc.Set("foo", strings.Repeat("bar", 1000), DefaultExpiration)
+		buf := &bytes.Buffer{}
+		if err := tc.Save(buf); err != nil {
+			t.Fatal(err)
+		}
+		if compression == Gzip && buf.Len() > 1000 {
+			t.Error("Gzip snapshot was not compressed:", buf.Len())
+		}
+
+		// Load detects the compression regardless of the option.
+		oc := New(Expiration(DefaultExpiration))
+		if err := oc.Load(buf); err != nil {
+			t.Fatalf("Couldn't load snapshot with compression %d: %v", compression, err)
+		}
+		if x, _ := oc.Get("foo"); x != strings.Repeat("bar", 1000) {
+			t.Errorf("foo was not loaded with compression %d", compression)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync/atomic"
+)
+
+// If enabled, Items, Snapshot, Save and ExportJSONL see the cache as it was
+// at a single point in time, even while it is being written to: every item
+// set before that point is included, none set after it is, and the items of a
+// SetMulti are included all together or not at all. Without it, items set or
+// deleted while the cache is being copied may or may not be included.
+//
+// This makes every write take a shared lock, and holds off writers while the
+// items are copied (but not while they are encoded by Save and ExportJSONL),
+// so it suits caches backed up regularly more than write-heavy ones.
+func AtomicSnapshots(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.AtomicSnapshots = b
+		return nil
+	}
+}
+
+// Called around every change to c.items, except by Flush, which holds off
+// both writers and snapshots.
+func (c *cache) beginWrite() {
+	if c.AtomicSnapshots {
+		c.snap.RLock()
+	}
+}
+
+func (c *cache) endWrite() {
+	if c.AtomicSnapshots {
+		c.snap.RUnlock()
+	}
+}
+
+// Calls fn for every item in the cache, including expired ones, until fn
+// returns false. With AtomicSnapshots, the items are collected while writers
+// are held off, and fn is called after.
+func (c *cache) rangeItems(fn func(k string, v Item) bool) {
+	if !c.AtomicSnapshots {
+		c.items.Range(func(key, value interface{}) bool {
+			return fn(key.(string), itemOf(value))
+		})
+		return
+	}
+	type entry struct {
+		k string
+		v Item
+	}
+	c.snap.Lock()
+	entries := make([]entry, 0, atomic.LoadInt64(&c.count))
+	c.items.Range(func(key, value interface{}) bool {
+		entries = append(entries, entry{key.(string), itemOf(value)})
+		return true
+	})
+	c.snap.Unlock()
+	for _, e := range entries {
+		if !fn(e.k, e.v) {
+			return
+		}
+	}
+}
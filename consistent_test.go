@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestAtomicSnapshots(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), AtomicSnapshots(true))
+	batch := func(n int) map[string]interface{} {
+		m := make(map[string]interface{}, 1000)
+		for i := 0; i < 1000; i++ {
+			m[fmt.Sprint(i)] = n
+		}
+		return m
+	}
+	tc.SetMulti(batch(0), DefaultExpiration)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for n := 1; ; n++ {
+			select {
+			case <-stop:
+				return
+			default:
+				tc.SetMulti(batch(n), DefaultExpiration)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		items := tc.Items()
+		if len(items) != 1000 {
+			t.Fatalf("got %d items; want 1000", len(items))
+		}
+		want := items["0"].Object
+		for k, v := range items {
+			if v.Object != want {
+				t.Fatalf("item %s is from batch %v, item 0 from batch %v", k, v.Object, want)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	tc.Flush()
+	if n := tc.Snapshot().Len(); n != 0 {
+		t.Errorf("snapshot after Flush has %d items", n)
+	}
+}
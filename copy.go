@@ -0,0 +1,66 @@
+package cache
+
+// Values implementing Cloner are copied with CacheClone when the cache is
+// configured to copy values (see CopyOnGet and CopyOnSet.) CacheClone should
+// return a deep copy of the value, of the same type.
+type Cloner interface {
+	CacheClone() interface{}
+}
+
+// If enabled, Get, GetWithExpiration and GetWithVersion return copies of the
+// stored values, so that callers cannot modify the values in the cache by
+// accident. See CopyFunc for how values are copied.
+func CopyOnGet(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.CopyOnGet = b
+		return nil
+	}
+}
+
+// If enabled, Set and its variants store copies of the values they are given,
+// so that callers cannot modify the values in the cache by modifying them
+// later. See CopyFunc for how values are copied.
+func CopyOnSet(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.CopyOnSet = b
+		return nil
+	}
+}
+
+// Copy values with f when CopyOnGet or CopyOnSet is enabled. Without a copy
+// function, values implementing Cloner are copied with CacheClone, byte slices
+// are copied, and other values are used as they are, which is only safe for
+// values that are immutable (e.g. strings and numbers.)
+func CopyFunc(f func(x interface{}) interface{}) CacheOption {
+	return func(m *CacheOptions) error {
+		m.CopyFunc = f
+		return nil
+	}
+}
+
+func (c *cache) copyValue(x interface{}) interface{} {
+	if c.CopyFunc != nil {
+		return c.CopyFunc(x)
+	}
+	switch v := x.(type) {
+	case Cloner:
+		return v.CacheClone()
+	case []byte:
+		return append([]byte(nil), v...)
+	}
+	return x
+}
+
+func (c *cache) copyIn(x interface{}) interface{} {
+	if c.CopyOnSet {
+		return c.copyValue(x)
+	}
+	return x
+}
+
+func (c *cache) copyOut(x interface{}) interface{} {
+	if c.CopyOnGet {
+		return c.copyValue(x)
+	}
+	return x
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"testing"
+)
+
+type clonedMap map[string]int
+
+func (m clonedMap) CacheClone() interface{} {
+	c := make(clonedMap, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func TestCopyOnGet(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CopyOnGet(true))
+	tc.Set("m", clonedMap{"a": 1}, DefaultExpiration)
+	x, _ := tc.Get("m")
+	x.(clonedMap)["a"] = 2
+	if x, _ := tc.Get("m"); x.(clonedMap)["a"] != 1 {
+		t.Error("modifying the result of Get changed the cached value")
+	}
+
+	tc.Set("b", []byte("foo"), DefaultExpiration)
+	b, _, _ := tc.GetWithExpiration("b")
+	b.([]byte)[0] = 'g'
+	if b, _, _ := tc.GetWithVersion("b"); string(b.([]byte)) != "foo" {
+		t.Error("modifying the result of GetWithExpiration changed the cached value")
+	}
+}
+
+func TestCopyOnSet(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CopyOnSet(true))
+	m := clonedMap{"a": 1}
+	tc.Set("m", m, DefaultExpiration)
+	m["a"] = 2
+	if x, _ := tc.Get("m"); x.(clonedMap)["a"] != 1 {
+		t.Error("modifying the value after Set changed the cached value")
+	}
+}
+
+func TestCopyFunc(t *testing.T) {
+	var copies int
+	tc := New(Expiration(DefaultExpiration), CopyOnGet(true), CopyOnSet(true),
+		CopyFunc(func(x interface{}) interface{} {
+			copies++
+			return x
+		}))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.SetMulti(map[string]interface{}{"b": 2}, DefaultExpiration)
+	tc.Get("a")
+	if copies != 3 {
+		t.Error("CopyFunc was called", copies, "times instead of 3")
+	}
+}
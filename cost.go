@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"time"
+)
+
+// Limit the total cost of the items in the cache to n. Items are given a cost
+// with SetWithCost; items added in any other way have a cost of 1. Like
+// CacheSize, the budget is enforced by DeleteLRU (and the janitor), which
+// removes the least recently used items until the cache is within budget.
+func MaxCost(n int64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MaxCost = n
+		return nil
+	}
+}
+
+// Add an item to the cache with the given cost, replacing any existing item.
+// The cost is an arbitrary weight, e.g. the number of bytes the item holds or
+// the time it took to compute, which counts against the MaxCost budget. A
+// cost of 0 or less is treated as 1.
+func (c *cache) SetWithCost(k string, x interface{}, cost int64, d time.Duration) {
+	c.setItem(k, x, d, Item{Cost: cost})
+}
+
+// Returns the cost of the item that counts against MaxCost.
+func itemCost(_ string, v Item) int64 {
+	if v.Cost <= 0 {
+		return 1
+	}
+	return v.Cost
+}
+
+// Delete the least recently used items until the total cost of the unexpired
+// items in the cache is at most max.
+func (c *cache) deleteLRUCost(max int64) ([]KeyValue, []string) {
+	return c.deleteLRUWeight(max, itemCost)
+}
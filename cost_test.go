@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetWithCost(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxCost(10))
+	tc.SetWithCost("expensive", "a", 8, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.SetWithCost("cheap", "b", 2, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("other", "c", DefaultExpiration)
+
+	tc.DeleteLRU()
+	if _, found := tc.Get("expensive"); found {
+		t.Error("expensive was found, but it should have been evicted")
+	}
+	if _, found := tc.Get("cheap"); !found {
+		t.Error("cheap was not found")
+	}
+	if _, found := tc.Get("other"); !found {
+		t.Error("other was not found")
+	}
+}
+
+func TestSetWithCostWithinBudget(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxCost(10))
+	tc.SetWithCost("a", "a", 5, DefaultExpiration)
+	tc.SetWithCost("b", "b", 5, DefaultExpiration)
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 2 {
+		t.Error("ItemCount is not 2:", n)
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A Counter is an int64 stored in the cache that is updated in place with
+// atomic operations, so that concurrent increments are never lost and do not
+// copy the item holding it. Get, Items and the like return the *Counter
+// itself; use Load to read its value.
+//
+// Counters are created with Counter or IncrementCounter. IncrementNumber (for
+// int64), IncrementInt64, Increment and their Decrement counterparts also
+// update counters atomically.
+type Counter struct {
+	n int64
+}
+
+// Add n to the counter, returning the new value.
+func (ctr *Counter) Add(n int64) int64 {
+	return atomic.AddInt64(&ctr.n, n)
+}
+
+// Returns the value of the counter.
+func (ctr *Counter) Load() int64 {
+	return atomic.LoadInt64(&ctr.n)
+}
+
+// Set the value of the counter.
+func (ctr *Counter) Store(n int64) {
+	atomic.StoreInt64(&ctr.n, n)
+}
+
+// Atomically replace the value of the counter with the result of f, unless f
+// returns an error.
+func (ctr *Counter) update(f func(int64) (int64, error)) (int64, error) {
+	for {
+		rv := ctr.Load()
+		nv, err := f(rv)
+		if err != nil {
+			return rv, err
+		}
+		if atomic.CompareAndSwapInt64(&ctr.n, rv, nv) {
+			return nv, nil
+		}
+	}
+}
+
+func (ctr *Counter) String() string {
+	return strconv.FormatInt(ctr.Load(), 10)
+}
+
+func (ctr *Counter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ctr.Load())
+}
+
+func (ctr *Counter) UnmarshalJSON(b []byte) error {
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	ctr.Store(n)
+	return nil
+}
+
+func (ctr *Counter) GobEncode() ([]byte, error) {
+	return ctr.MarshalJSON()
+}
+
+func (ctr *Counter) GobDecode(b []byte) error {
+	return ctr.UnmarshalJSON(b)
+}
+
+// Returns the counter stored under k, first setting k to a new counter with
+// the expiration d if it is not in the cache, has expired, or holds a value
+// that is not a *Counter. Returns nil if the cache is closed.
+func (c *cache) Counter(k string, d time.Duration) *Counter {
+	if c.isClosed() {
+		return nil
+	}
+	if v, found := c.getItem(k); found && !c.expired(v) {
+		if ctr, ok := v.Object.(*Counter); ok {
+			return ctr
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, found := c.getItem(k); found && !c.expired(v) {
+		if ctr, ok := v.Object.(*Counter); ok {
+			return ctr
+		}
+	}
+	ctr := &Counter{}
+	c.set(k, ctr, d)
+	return ctr
+}
+
+// Add n to the counter stored under k, creating it as Counter does, and
+// return the new value.
+func (c *cache) IncrementCounter(k string, n int64, d time.Duration) int64 {
+	ctr := c.Counter(k, d)
+	if ctr == nil {
+		return 0
+	}
+	nv := ctr.Add(n)
+	c.notify(EventSet, k, ctr)
+	return nv
+}
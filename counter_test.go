@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounterConcurrent(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tc.IncrementCounter("n", 1, DefaultExpiration)
+				tc.Increment("n", 1)
+				tc.IncrementInt64("n", 1)
+				IncrementNumber(tc, "n", int64(1))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := tc.Counter("n", DefaultExpiration).Load(); n != 8000 {
+		t.Error("n is", n, "instead of 8000")
+	}
+}
+
+func TestCounterDecrement(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.IncrementCounter("n", 10, DefaultExpiration)
+	tc.Decrement("n", 3)
+	if n, err := tc.DecrementInt64("n", 2); err != nil || n != 5 {
+		t.Error("n is", n, err)
+	}
+	x, _ := tc.Get("n")
+	if ctr, ok := x.(*Counter); !ok || ctr.Load() != 5 {
+		t.Error("Get returned", x, "instead of a counter with the value 5")
+	}
+}
+
+func TestCounterExpired(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.IncrementCounter("n", 5, time.Second)
+	clock.Advance(2 * time.Second)
+	if n := tc.IncrementCounter("n", 1, time.Second); n != 1 {
+		t.Error("n is", n, "instead of 1 after expiring")
+	}
+	tc.Set("s", "foo", DefaultExpiration)
+	if n := tc.IncrementCounter("s", 1, DefaultExpiration); n != 1 {
+		t.Error("s is", n, "instead of 1")
+	}
+}
+
+func TestCounterSaveLoad(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.IncrementCounter("n", 42, DefaultExpiration)
+	var buf bytes.Buffer
+	if err := tc.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	oc := New(Expiration(DefaultExpiration))
+	if err := oc.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := oc.IncrementCounter("n", 1, DefaultExpiration); n != 43 {
+		t.Error("n is", n, "instead of 43 after loading")
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"io"
+)
+
+// Values implementing Disposer, or io.Closer, hold resources that must be
+// released when they leave the cache; see DisposeValues.
+type Disposer interface {
+	Dispose()
+}
+
+// If enabled, values implementing Disposer or io.Closer are disposed of when
+// their item leaves the cache: when it is deleted, expires, is evicted, is
+// replaced by a different value, or is flushed. Dispose (or Close, if the
+// value isn't a Disposer) is called once, synchronously, by the goroutine
+// removing the item, before any eviction callback is called with it. Errors
+// returned by Close are ignored.
+//
+// Values must not be used after their item has left the cache, so callers
+// should not keep them beyond their use of the cache, and CopyOnGet should
+// not be used with them.
+func DisposeValues(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.DisposeValues = b
+		return nil
+	}
+}
+
+func dispose(x interface{}) {
+	switch v := x.(type) {
+	case Disposer:
+		v.Dispose()
+	case io.Closer:
+		v.Close()
+	}
+}
+
+// Dispose of old, replaced by x, unless the item was stored again with the
+// same value (e.g. to update its access time).
+func disposeReplaced(old, x interface{}) {
+	switch old.(type) {
+	case Disposer, io.Closer:
+	default:
+		return
+	}
+	if !sameValue(old, x) {
+		dispose(old)
+	}
+}
+
+// Reports whether a and b are equal, without panicking on values that are
+// not comparable, which are never equal.
+func sameValue(a, b interface{}) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
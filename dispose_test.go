@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type resource struct {
+	disposed int
+}
+
+func (r *resource) Dispose() { r.disposed++ }
+
+type closer struct {
+	closed int
+}
+
+func (c *closer) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestDisposeValues(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(2), DisposeValues(true))
+	replaced, deleted, expired, evicted := &resource{}, &resource{}, &closer{}, &resource{}
+
+	tc.Set("a", replaced, DefaultExpiration)
+	tc.Get("a")
+	tc.Set("a", replaced, DefaultExpiration)
+	if replaced.disposed != 0 {
+		t.Error("value was disposed when its item was stored again")
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	if replaced.disposed != 1 {
+		t.Errorf("replaced value was disposed %d times; want 1", replaced.disposed)
+	}
+
+	tc.Set("b", deleted, DefaultExpiration)
+	tc.Delete("b")
+	if deleted.disposed != 1 {
+		t.Errorf("deleted value was disposed %d times; want 1", deleted.disposed)
+	}
+
+	tc.Set("c", expired, time.Second)
+	clock.Advance(2 * time.Second)
+	tc.DeleteExpired()
+	if expired.closed != 1 {
+		t.Errorf("expired value was closed %d times; want 1", expired.closed)
+	}
+
+	tc.Set("d", evicted, DefaultExpiration)
+	clock.Advance(time.Second)
+	tc.Set("e", 2, DefaultExpiration)
+	tc.Set("f", 3, DefaultExpiration)
+	tc.DeleteLRU()
+	if evicted.disposed != 1 {
+		t.Errorf("evicted value was disposed %d times; want 1", evicted.disposed)
+	}
+
+	flushed := &resource{}
+	tc.Set("g", flushed, DefaultExpiration)
+	tc.Flush()
+	if flushed.disposed != 1 {
+		t.Errorf("flushed value was disposed %d times; want 1", flushed.disposed)
+	}
+}
+
+func TestDisposeValuesDisabled(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	r := &resource{}
+	tc.Set("a", r, DefaultExpiration)
+	tc.Delete("a")
+	if r.disposed != 0 {
+		t.Error("value was disposed without DisposeValues")
+	}
+}
@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypt snapshots written by Save (and SaveFile and Persist) with AES-GCM
+// using key, which must be 16, 24 or 32 bytes long to select AES-128,
+// AES-192 or AES-256. Load decrypts encrypted snapshots with the same key.
+// Unencrypted snapshots can still be loaded, so that existing snapshots can
+// be migrated.
+func EncryptionKey(key []byte) CacheOption {
+	return func(m *CacheOptions) error {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return fmt.Errorf("EncryptionKey must be 16, 24 or 32 bytes long, not %d", len(key))
+		}
+		m.EncryptionKey = key
+		return nil
+	}
+}
+
+// ErrEncrypted is returned by Load for an encrypted snapshot if the cache
+// has no EncryptionKey.
+var ErrEncrypted = errors.New("cache: snapshot is encrypted")
+
+// Encrypted snapshots start with encryptedMagic and a random nonce. The
+// plaintext follows in sealed chunks, each preceded by its length as a
+// big-endian uint32. Every chunk's nonce is the file's nonce XORed with the
+// chunk's index, and its additional data marks whether it is the last chunk,
+// so that chunks can't be reordered, and truncation is detected.
+const (
+	encryptedMagic     = "GOCACHEE"
+	encryptedChunkSize = 64 << 10
+)
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(base []byte, i uint64) []byte {
+	nonce := append([]byte(nil), base...)
+	ctr := binary.BigEndian.Uint64(nonce[len(nonce)-8:])
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], ctr^i)
+	return nonce
+}
+
+var (
+	adMore = []byte{0}
+	adLast = []byte{1}
+)
+
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	nonce []byte
+	buf   []byte
+	n     uint64
+}
+
+// Returns a writer encrypting into w with the cache's key, or nil if
+// snapshots are not encrypted.
+func (c *cache) encryptor(w io.Writer) (io.WriteCloser, error) {
+	if c.EncryptionKey == nil {
+		return nil, nil
+	}
+	aead, err := newGCM(c.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append([]byte(encryptedMagic), nonce...)); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, nonce: nonce}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		m := encryptedChunkSize - len(ew.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		ew.buf = append(ew.buf, p[:m]...)
+		p = p[m:]
+		// A full chunk is only sealed once more data arrives, as the last
+		// chunk must be marked as such.
+		if len(ew.buf) == encryptedChunkSize && len(p) > 0 {
+			if err := ew.seal(adMore); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (ew *encryptWriter) seal(ad []byte) error {
+	sealed := ew.aead.Seal(nil, chunkNonce(ew.nonce, ew.n), ew.buf, ad)
+	var h [4]byte
+	binary.BigEndian.PutUint32(h[:], uint32(len(sealed)))
+	if _, err := ew.w.Write(h[:]); err != nil {
+		return err
+	}
+	if _, err := ew.w.Write(sealed); err != nil {
+		return err
+	}
+	ew.buf = ew.buf[:0]
+	ew.n++
+	return nil
+}
+
+// Seal the last chunk. The underlying writer is not closed.
+func (ew *encryptWriter) Close() error {
+	return ew.seal(adLast)
+}
+
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	buf   []byte
+	n     uint64
+	last  bool
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.last {
+			return 0, io.EOF
+		}
+		if err := dr.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+func (dr *decryptReader) open() error {
+	var h [4]byte
+	if _, err := io.ReadFull(dr.r, h[:]); err != nil {
+		return truncated(err)
+	}
+	size := binary.BigEndian.Uint32(h[:])
+	if size > encryptedChunkSize+uint32(dr.aead.Overhead()) {
+		return &SnapshotError{"bad chunk length"}
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		return truncated(err)
+	}
+	nonce := chunkNonce(dr.nonce, dr.n)
+	buf, err := dr.aead.Open(nil, nonce, sealed, adMore)
+	if err != nil {
+		if buf, err = dr.aead.Open(nil, nonce, sealed, adLast); err != nil {
+			return &SnapshotError{"decryption failed"}
+		}
+		dr.last = true
+	}
+	dr.buf = buf
+	dr.n++
+	return nil
+}
+
+// Returns a reader decrypting r if it is an encrypted snapshot, and reading r
+// as it is otherwise.
+func (c *cache) decryptor(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(encryptedMagic))
+	if !bytes.Equal(magic, []byte(encryptedMagic)) {
+		return br, nil
+	}
+	if c.EncryptionKey == nil {
+		return nil, ErrEncrypted
+	}
+	aead, err := newGCM(c.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	br.Discard(len(encryptedMagic))
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(br, nonce); err != nil {
+		return nil, truncated(err)
+	}
+	return &decryptReader{r: br, aead: aead, nonce: nonce}, nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	secret := strings.Repeat("secret", 20000)
+
+	tc := New(Expiration(DefaultExpiration), EncryptionKey(key), Compress(Gzip))
+	tc.Set("pii", secret, DefaultExpiration)
+	if err := tc.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	oc := New(Expiration(DefaultExpiration), EncryptionKey(key))
+	if err := oc.LoadFile(path); err != nil {
+		t.Fatal("Couldn't load an encrypted snapshot:", err)
+	}
+	if x, _ := oc.Get("pii"); x != secret {
+		t.Error("pii was not decrypted")
+	}
+
+	if err := New().LoadFile(path); err != ErrEncrypted {
+		t.Error("Expected ErrEncrypted without a key, got", err)
+	}
+	other := New(EncryptionKey(bytes.Repeat([]byte{8}, 32)))
+	if _, ok := other.LoadFile(path).(*SnapshotError); !ok {
+		t.Error("Expected a SnapshotError with the wrong key")
+	}
+	if _, err := NewWithError(EncryptionKey([]byte("short"))); err == nil {
+		t.Error("Expected an error for a short key")
+	}
+}
+
+func TestEncryptionTampering(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 16)
+	tc := New(EncryptionKey(key))
+	for i := 0; i < 10000; i++ {
+		tc.Set(strings.Repeat("k", i%50)+string(rune(i)), i, DefaultExpiration)
+	}
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if bytes.Contains(b, []byte("kkkkk")) {
+		t.Error("Snapshot contains plaintext")
+	}
+
+	if err := New(EncryptionKey(key)).Load(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte(nil), b...)
+	flipped[len(flipped)/2] ^= 1
+	if err := New(EncryptionKey(key)).Load(bytes.NewReader(flipped)); err == nil {
+		t.Error("Expected an error loading a tampered snapshot")
+	}
+	// Dropping the last chunk must not go unnoticed, even at a chunk
+	// boundary.
+	if err := New(EncryptionKey(key)).Load(bytes.NewReader(b[:len(b)-100])); err == nil {
+		t.Error("Expected an error loading a truncated snapshot")
+	}
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The type of an Event.
+type EventType int
+
+const (
+	// An item was added or replaced, or its value was incremented or
+	// decremented.
+	EventSet EventType = iota + 1
+	// An item was deleted explicitly, e.g. with Delete or InvalidateTag.
+	EventDelete
+	// An expired item was removed by DeleteExpired (or the janitor.)
+	EventExpire
+	// An item was evicted because the cache was over its size limit.
+	EventEvict
+	// An item was removed by FlushWithCallbacks.
+	EventFlush
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	case EventFlush:
+		return "flush"
+	}
+	return "unknown"
+}
+
+// An Event describes a change to an item in the cache.
+type Event struct {
+	Type  EventType
+	Key   string
+	Value interface{}
+	Time  time.Time
+}
+
+// The number of events buffered for each watcher. Events for a watcher whose
+// buffer is full are dropped.
+const watchBuffer = 128
+
+type watcher struct {
+	key    string
+	prefix bool
+	ch     chan Event
+}
+
+func (w *watcher) matches(k string) bool {
+	if w.prefix {
+		return strings.HasPrefix(k, w.key)
+	}
+	return k == w.key
+}
+
+type eventHub struct {
+	used     uint32
+	mu       sync.RWMutex
+	watchers map[*watcher]struct{}
+	sink     chan<- Event
+	// Set if the sink or the write-ahead log need events, even if nobody
+	// is watching.
+	pinned bool
+}
+
+func (h *eventHub) inUse() bool {
+	return atomic.LoadUint32(&h.used) == 1
+}
+
+func (h *eventHub) setSink(sink chan<- Event) {
+	h.sink = sink
+	h.pin()
+}
+
+func (h *eventHub) pin() {
+	h.pinned = true
+	atomic.StoreUint32(&h.used, 1)
+}
+
+func (h *eventHub) send(e Event) {
+	if h.sink != nil {
+		h.sink <- e
+	}
+	h.mu.RLock()
+	for w := range h.watchers {
+		if w.matches(e.Key) {
+			select {
+			case w.ch <- e:
+			default:
+			}
+		}
+	}
+	h.mu.RUnlock()
+}
+
+func (h *eventHub) add(w *watcher) {
+	h.mu.Lock()
+	if h.watchers == nil {
+		h.watchers = make(map[*watcher]struct{})
+	}
+	h.watchers[w] = struct{}{}
+	atomic.StoreUint32(&h.used, 1)
+	h.mu.Unlock()
+}
+
+func (h *eventHub) remove(w *watcher) {
+	h.mu.Lock()
+	if _, found := h.watchers[w]; found {
+		delete(h.watchers, w)
+		close(w.ch)
+	}
+	if len(h.watchers) == 0 && !h.pinned {
+		atomic.StoreUint32(&h.used, 0)
+	}
+	h.mu.Unlock()
+}
+
+// Send every change to the cache, including evictions, to sink, e.g. to
+// replicate the cache to another node or to write an audit log. Unlike Watch,
+// no events are dropped: the operation that caused an event blocks until sink
+// accepts it, so sink should be buffered and drained promptly, and must not
+// be drained by a goroutine that is itself waiting on the cache.
+func EventSink(sink chan<- Event) CacheOption {
+	return func(m *CacheOptions) error {
+		m.EventSink = sink
+		return nil
+	}
+}
+
+// Emit an event to the write-ahead log, the sink and interested watchers, if
+// there are any.
+func (c *cache) notify(typ EventType, k string, v interface{}) {
+	if !c.events.inUse() {
+		return
+	}
+	if c.wal != nil {
+		c.wal.log(c, typ, k)
+	}
+	c.events.send(Event{
+		Type:  typ,
+		Key:   k,
+		Value: v,
+		Time:  c.now(),
+	})
+}
+
+// Returns a channel on which changes to the item with key keyOrPrefix are
+// delivered, or, if keyOrPrefix ends with '*', changes to all items whose
+// keys start with the part before the '*'. The returned function stops the
+// watch and closes the channel; it must be called when the watch is no longer
+// needed.
+//
+// Events are delivered without blocking the cache: if the receiver falls
+// more than a hundred or so events behind, further events are dropped until
+// it catches up.
+func (c *cache) Watch(keyOrPrefix string) (<-chan Event, func()) {
+	w := &watcher{
+		key: keyOrPrefix,
+		ch:  make(chan Event, watchBuffer),
+	}
+	if strings.HasSuffix(keyOrPrefix, "*") {
+		w.key = keyOrPrefix[:len(keyOrPrefix)-1]
+		w.prefix = true
+	}
+	c.events.add(w)
+	var once sync.Once
+	return w.ch, func() {
+		once.Do(func() {
+			c.events.remove(w)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(1 * time.Second):
+		t.Fatal("No event was delivered")
+	}
+	return Event{}
+}
+
+func TestWatch(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	ch, cancel := tc.Watch("foo")
+	defer cancel()
+
+	tc.Set("bar", 1, DefaultExpiration)
+	tc.Set("foo", 2, DefaultExpiration)
+	if e := receive(t, ch); e.Type != EventSet || e.Key != "foo" || e.Value != 2 {
+		t.Error("Wrong event for Set:", e)
+	}
+
+	tc.IncrementInt("foo", 1)
+	if e := receive(t, ch); e.Type != EventSet || e.Value != 3 {
+		t.Error("Wrong event for IncrementInt:", e)
+	}
+
+	tc.Delete("foo")
+	if e := receive(t, ch); e.Type != EventDelete || e.Value != 3 {
+		t.Error("Wrong event for Delete:", e)
+	}
+
+	tc.Set("foo", 4, 1*time.Millisecond)
+	receive(t, ch)
+	<-time.After(5 * time.Millisecond)
+	tc.DeleteExpired()
+	if e := receive(t, ch); e.Type != EventExpire || e.Key != "foo" {
+		t.Error("Wrong event for DeleteExpired:", e)
+	}
+
+	select {
+	case e := <-ch:
+		t.Error("Unexpected event:", e)
+	default:
+	}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(1))
+	ch, cancel := tc.Watch("user:*")
+	tc.Set("user:1", 1, DefaultExpiration)
+	tc.Set("session:1", 1, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("user:2", 2, DefaultExpiration)
+	receive(t, ch)
+	receive(t, ch)
+
+	tc.DeleteLRU()
+	if e := receive(t, ch); e.Type != EventEvict || e.Key != "user:1" {
+		t.Error("Wrong event for DeleteLRU:", e)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("Channel was not closed by cancel")
+	}
+	if tc.events.inUse() {
+		t.Error("Events are still in use after the last watch was cancelled")
+	}
+}
+
+func TestEventSink(t *testing.T) {
+	sink := make(chan Event, 10)
+	tc := New(Expiration(DefaultExpiration), CacheSize(1), EventSink(sink))
+	tc.Set("a", 1, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Delete("b")
+	tc.Set("c", 3, DefaultExpiration)
+	tc.DeleteLRU()
+
+	want := []Event{
+		{Type: EventSet, Key: "a", Value: 1},
+		{Type: EventSet, Key: "b", Value: 2},
+		{Type: EventDelete, Key: "b", Value: 2},
+		{Type: EventSet, Key: "c", Value: 3},
+		{Type: EventEvict, Key: "a", Value: 1},
+	}
+	for _, w := range want {
+		e := receive(t, sink)
+		if e.Type != w.Type || e.Key != w.Key || e.Value != w.Value {
+			t.Errorf("Got event %v, want %v", e, w)
+		}
+		if e.Time.IsZero() {
+			t.Error("Event has no timestamp:", e)
+		}
+	}
+
+	// Cancelling the last watch must not stop events going to the sink.
+	_, cancel := tc.Watch("x")
+	cancel()
+	tc.Set("x", 4, DefaultExpiration)
+	if e := receive(t, sink); e.Key != "x" {
+		t.Error("Wrong event after cancelling a watch:", e)
+	}
+}
+
+func TestLoadEvents(t *testing.T) {
+	src := New(Expiration(DefaultExpiration))
+	src.Set("a", 1, DefaultExpiration)
+	buf := &bytes.Buffer{}
+	if err := src.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := New(Expiration(DefaultExpiration))
+	ch, stop := tc.Watch("a")
+	defer stop()
+	if err := tc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	if e := receive(t, ch); e.Type != EventSet || e.Key != "a" || e.Value != 1 {
+		t.Error("Got event", e)
+	}
+}
@@ -0,0 +1,433 @@
+package cache
+
+import (
+	"math/rand"
+	"sort"
+	"sync/atomic"
+)
+
+// An EvictionPolicy decides which items are removed first when the cache is
+// over one of its limits (CacheSize, MaxBytes or MaxCost.) Eviction happens
+// in DeleteLRU and DeleteLRUAmount (and therefore in the janitor), which pass
+// every unexpired item in the cache to the policy.
+type EvictionPolicy interface {
+	// Sort the candidates so that the items that should be evicted first
+	// come first.
+	Order(candidates []Candidate)
+}
+
+// A Candidate is an unexpired item that may be evicted.
+type Candidate struct {
+	Key  string
+	Item Item
+
+	weight int64
+}
+
+// Evict by sampling, like Redis, instead of ordering every item in the cache:
+// each item evicted is chosen by the eviction policy from about n randomly
+// sampled items, rather than from all of them. When evicting a number of
+// items, only about n of them per item evicted are visited, copied and sorted;
+// when evicting by MaxBytes or MaxCost, every item is still visited to weigh
+// the cache. This makes eviction much cheaper for caches with millions of
+// items, at the cost
+// of sometimes evicting an item that the policy would have kept. The larger
+// n, the closer the result is to that of the policy; 5 to 10 is usually
+// enough.
+func SampledEviction(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.EvictionSamples = n
+		return nil
+	}
+}
+
+// When the cache is over one of its limits (CacheSize, HardCacheSize, MaxBytes
+// or MaxCost), evict items until it is at fraction (between 0 and 1) of the
+// limit, rather than exactly at the limit, e.g. 0.9 to trim it to 90%. This
+// leaves room for new items, so that eviction does not happen again for
+// nearly every Set. The default is 1.
+func EvictionTarget(fraction float64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.EvictionTarget = fraction
+		return nil
+	}
+}
+
+// Returns the size to which the cache is trimmed when it is over limit, as
+// set with EvictionTarget.
+func (c *cache) evictionTarget(limit int64) int64 {
+	if c.EvictionTarget <= 0 || c.EvictionTarget >= 1 {
+		return limit
+	}
+	return int64(float64(limit) * c.EvictionTarget)
+}
+
+// Set the policy used to choose which items are evicted when the cache is
+// over its size limit. The default is LRU().
+func Policy(p EvictionPolicy) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Policy = p
+		return nil
+	}
+}
+
+type lruPolicy struct{}
+
+// Returns a policy that evicts the least recently used items first.
+func LRU() EvictionPolicy {
+	return lruPolicy{}
+}
+
+func (lruPolicy) Order(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Item.Accessed < candidates[j].Item.Accessed
+	})
+}
+
+type lfuPolicy struct{}
+
+// Returns a policy that evicts the least frequently used items first, i.e.
+// the items that have been retrieved the fewest times since they were set.
+// Items that have been used equally often are evicted in LRU order.
+func LFU() EvictionPolicy {
+	return lfuPolicy{}
+}
+
+func (lfuPolicy) Order(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i].Item, candidates[j].Item
+		if a.Hits != b.Hits {
+			return a.Hits < b.Hits
+		}
+		return a.Accessed < b.Accessed
+	})
+}
+
+type fifoPolicy struct{}
+
+// Returns a policy that evicts the items that were set the longest time ago
+// first, regardless of how they have been used since.
+func FIFO() EvictionPolicy {
+	return fifoPolicy{}
+}
+
+func (fifoPolicy) Order(candidates []Candidate) {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Item.Created < candidates[j].Item.Created
+	})
+}
+
+type slruPolicy struct {
+	protected float64
+}
+
+// Returns a segmented LRU policy. Items start out in a probation segment, and
+// are promoted to a protected segment the first time they are retrieved.
+// Items in the probation segment are evicted first, in LRU order, so that
+// items which are set but never used again cannot push out items that have
+// proven useful. protected is the fraction (between 0 and 1) of the cache
+// that may be occupied by the protected segment; the least recently used
+// protected items beyond that are demoted back to probation. 0.8 is a good
+// default.
+func SLRU(protected float64) EvictionPolicy {
+	if protected < 0 {
+		protected = 0
+	} else if protected > 1 {
+		protected = 1
+	}
+	return slruPolicy{protected}
+}
+
+func (p slruPolicy) Order(candidates []Candidate) {
+	lruPolicy{}.Order(candidates)
+	var probation, protected []Candidate
+	for _, v := range candidates {
+		if v.Item.Hits == 0 {
+			probation = append(probation, v)
+		} else {
+			protected = append(protected, v)
+		}
+	}
+	if max := int(p.protected * float64(len(candidates))); len(protected) > max {
+		probation = append(probation, protected[:len(protected)-max]...)
+		protected = protected[len(protected)-max:]
+		lruPolicy{}.Order(probation)
+	}
+	n := copy(candidates, probation)
+	copy(candidates[n:], protected)
+}
+
+type randomPolicy struct{}
+
+// Returns a policy that evicts randomly chosen items.
+func Random() EvictionPolicy {
+	return randomPolicy{}
+}
+
+func (randomPolicy) Order(candidates []Candidate) {
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+}
+
+// Returns every unexpired item in the cache that has not been pinned, in no
+// particular order, along with the total weight of all unexpired items as
+// reported by weigh. If weigh is nil, the weight of every item is 0.
+func (c *cache) evictionCandidates(weigh func(k string, v Item) int64) ([]Candidate, int64) {
+	candidates, total, _ := c.sampleCandidates(weigh, 1)
+	return candidates, total
+}
+
+// Like evictionCandidates, but each candidate is only included with
+// probability p. Also returns the number of unexpired items.
+func (c *cache) sampleCandidates(weigh func(k string, v Item) int64, p float64) ([]Candidate, int64, int) {
+	var (
+		total      int64
+		count      int
+		candidates []Candidate
+		now        = c.now().UnixNano()
+	)
+	c.pins.mu.RLock()
+	defer c.pins.mu.RUnlock()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		k := key.(string)
+		// "Inlining" of !Expired
+		if v.Expiration == 0 || now <= v.Expiration {
+			var w int64
+			if weigh != nil {
+				w = weigh(k, v)
+				total += w
+			}
+			count++
+			if p < 1 && rand.Float64() >= p {
+				return true
+			}
+			if _, pinned := c.pins.keys[k]; !pinned {
+				candidates = append(candidates, Candidate{Key: k, Item: v, weight: w})
+			}
+		}
+		return true
+	})
+	return candidates, total, count
+}
+
+func (c *cache) orderCandidates(candidates []Candidate) {
+	p := c.Policy
+	if p == nil {
+		p = LRU()
+	}
+	p.Order(candidates)
+	if c.Admission != nil {
+		admit(c.Admission, candidates)
+	}
+	orderByPriority(candidates)
+}
+
+// Delete the given candidates from the cache, returning the ones that must be
+// passed to the eviction callback and the keys of all of them.
+func (c *cache) evictCandidates(candidates []Candidate) ([]KeyValue, []string) {
+	var evictedItems []KeyValue
+	keys := make([]string, len(candidates))
+	for i, v := range candidates {
+		ov, evicted := c.delete(v.Key, EventEvict)
+		if evicted {
+			evictedItems = append(evictedItems, KeyValue{v.Key, ov, EventEvict})
+		}
+		keys[i] = v.Key
+	}
+	c.stats.evicted(len(candidates))
+	return evictedItems, keys
+}
+
+// Delete items in the order chosen by the eviction policy until the total
+// weight of the unexpired items in the cache, as reported by weigh, is at most
+// max.
+func (c *cache) deleteLRUWeight(max int64, weigh func(k string, v Item) int64) ([]KeyValue, []string) {
+	if c.EvictionSamples > 0 {
+		return c.deleteSampledWeight(max, weigh)
+	}
+	candidates, total := c.evictionCandidates(weigh)
+	return c.trimCandidates(candidates, total, max)
+}
+
+// Delete candidates in the order chosen by the eviction policy until their
+// total weight is within max, if it is over.
+func (c *cache) trimCandidates(candidates []Candidate, total, max int64) ([]KeyValue, []string) {
+	if total <= max {
+		return nil, nil
+	}
+	c.orderCandidates(candidates)
+	target := c.evictionTarget(max)
+	i := 0
+	for ; i < len(candidates) && total > target; i++ {
+		total -= candidates[i].weight
+	}
+	return c.evictCandidates(candidates[:i])
+}
+
+// Returns the probability with which sampleCandidates should include each of
+// count items, so that about EvictionSamples candidates are sampled for each
+// of the n items to be evicted.
+func (c *cache) sampleRate(n, count int) float64 {
+	if count == 0 {
+		return 1
+	}
+	return float64(c.EvictionSamples) * float64(n) / float64(count)
+}
+
+// Returns up to n unexpired items that have not been pinned, stopping the
+// iteration over the cache as soon as it has found them. The order of the
+// iteration depends on the hashes of the keys rather than on when or how
+// recently the items were used, so the items found serve as a random sample.
+func (c *cache) firstCandidates(n int) []Candidate {
+	candidates := make([]Candidate, 0, n)
+	now := c.now().UnixNano()
+	c.pins.mu.RLock()
+	defer c.pins.mu.RUnlock()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		k := key.(string)
+		// "Inlining" of !Expired
+		if v.Expiration != 0 && now > v.Expiration {
+			return true
+		}
+		if _, pinned := c.pins.keys[k]; !pinned {
+			candidates = append(candidates, Candidate{Key: k, Item: v})
+		}
+		return len(candidates) < n
+	})
+	return candidates
+}
+
+// Like deleteLRUAmount, but choose the items to delete as described for
+// SampledEviction.
+func (c *cache) deleteSampled(numItems int) ([]KeyValue, []string) {
+	var (
+		evicted []KeyValue
+		keys    []string
+	)
+	for numItems > 0 {
+		candidates := c.firstCandidates(c.EvictionSamples * numItems)
+		if len(candidates) == 0 {
+			break
+		}
+		c.orderCandidates(candidates)
+		if len(candidates) > numItems {
+			candidates = candidates[:numItems]
+		}
+		e, k := c.evictCandidates(candidates)
+		evicted = append(evicted, e...)
+		keys = append(keys, k...)
+		numItems -= len(candidates)
+	}
+	return evicted, keys
+}
+
+// Like deleteLRUWeight, but choose the items to delete as described for
+// SampledEviction. The number of items to evict is estimated from the
+// average weight of the items in the cache.
+func (c *cache) deleteSampledWeight(max int64, weigh func(k string, v Item) int64) ([]KeyValue, []string) {
+	var (
+		evicted []KeyValue
+		keys    []string
+	)
+	_, total, count := c.sampleCandidates(weigh, 0)
+	if total <= max {
+		return nil, nil
+	}
+	max = c.evictionTarget(max)
+	for total > max && count > 0 {
+		avg := total / int64(count)
+		if avg < 1 {
+			avg = 1
+		}
+		n := int((total - max + avg - 1) / avg)
+		candidates, t, cnt := c.sampleCandidates(weigh, c.sampleRate(n, count))
+		total, count = t, cnt
+		if total <= max || len(candidates) == 0 {
+			break
+		}
+		c.orderCandidates(candidates)
+		i := 0
+		for ; i < len(candidates) && total > max; i++ {
+			total -= candidates[i].weight
+			count--
+		}
+		e, k := c.evictCandidates(candidates[:i])
+		evicted = append(evicted, e...)
+		keys = append(keys, k...)
+	}
+	return evicted, keys
+}
+
+// When EvictionTarget is not set, a full cache evicts 1/hardEvictionBatch of
+// HardCacheSize at a time, so that the following Sets of new keys don't each
+// have to scan the cache.
+const hardEvictionBatch = 64
+
+// Enforce HardCacheSize before an item with key k is stored.
+func (c *cache) makeRoom(k string) {
+	if c.HardCacheSize <= 0 || atomic.LoadInt64(&c.count) < int64(c.HardCacheSize) {
+		return
+	}
+	if _, found := c.items.Load(k); found {
+		// Replacing an item does not grow the cache.
+		return
+	}
+	c.evictMany(c.reserve(1))
+}
+
+// Make room for n new items under HardCacheSize, returning the evicted items
+// that must be passed to the eviction callback. Expired items are deleted and
+// the candidates for eviction are collected in a single pass over the cache.
+func (c *cache) reserve(n int) []KeyValue {
+	limit := int64(c.HardCacheSize)
+	if limit <= 0 || n <= 0 || atomic.LoadInt64(&c.count)+int64(n) <= limit {
+		return nil
+	}
+	target := c.evictionTarget(limit)
+	if target == limit {
+		target -= limit / hardEvictionBatch
+	}
+	var (
+		evictedItems []KeyValue
+		expired      []string
+		candidates   []Candidate
+		now          = c.now().UnixNano()
+	)
+	c.pins.mu.RLock()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		k := key.(string)
+		// "Inlining" of Expired
+		if v.Expiration > 0 && now > v.Expiration {
+			expired = append(expired, k)
+		} else if _, pinned := c.pins.keys[k]; !pinned {
+			candidates = append(candidates, Candidate{Key: k, Item: v})
+		}
+		return true
+	})
+	c.pins.mu.RUnlock()
+	for _, k := range expired {
+		if ov, evicted := c.delete(k, EventExpire); evicted {
+			evictedItems = append(evictedItems, KeyValue{k, ov, EventExpire})
+		}
+	}
+	c.stats.evicted(len(expired))
+	m := int(atomic.LoadInt64(&c.count) + int64(n) - target)
+	if m <= 0 {
+		return evictedItems
+	}
+	var e []KeyValue
+	if c.EvictionSamples > 0 {
+		e, _ = c.deleteSampled(m)
+	} else {
+		if m > len(candidates) {
+			m = len(candidates)
+		}
+		c.orderCandidates(candidates)
+		e, _ = c.evictCandidates(candidates[:m])
+	}
+	return append(evictedItems, e...)
+}
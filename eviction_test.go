@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyLRU(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), Policy(LRU()))
+	tc.Set("a", 1, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Get("a")
+
+	tc.DeleteLRU()
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found, but it should have been evicted")
+	}
+	if tc.ItemCount() != 2 {
+		t.Error("tc.ItemCount() is not 2")
+	}
+}
+
+func TestPolicyLFU(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), Policy(LFU()))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Get("a")
+	tc.Get("a")
+	tc.Get("b")
+	tc.Get("b")
+	tc.Get("c")
+
+	tc.DeleteLRU()
+	if _, found := tc.Get("c"); found {
+		t.Error("c was found, but it should have been evicted")
+	}
+	if tc.ItemCount() != 2 {
+		t.Error("tc.ItemCount() is not 2")
+	}
+}
+
+func TestPolicyFIFO(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), Policy(FIFO()))
+	tc.Set("a", 1, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Get("a")
+
+	tc.DeleteLRU()
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found, but it should have been evicted")
+	}
+	if tc.ItemCount() != 2 {
+		t.Error("tc.ItemCount() is not 2")
+	}
+}
+
+func TestPolicyRandom(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(5), Policy(Random()))
+	for i := 0; i < 10; i++ {
+		tc.Set(string(rune('a'+i)), i, DefaultExpiration)
+	}
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 5 {
+		t.Error("ItemCount is not 5:", n)
+	}
+}
+
+func TestPolicySLRU(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(3), Policy(SLRU(0.5)))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Get("a")
+	tc.Get("b")
+	<-time.After(1 * time.Millisecond)
+	// c and d are newer, but have never been retrieved
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Set("d", 4, DefaultExpiration)
+
+	tc.DeleteLRU()
+	if _, found := tc.Get("a"); !found {
+		t.Error("a was evicted from the protected segment")
+	}
+	if _, found := tc.Get("b"); !found {
+		t.Error("b was evicted from the protected segment")
+	}
+	if tc.ItemCount() != 3 {
+		t.Error("tc.ItemCount() is not 3")
+	}
+}
+
+func TestPolicySLRUDemotion(t *testing.T) {
+	candidates := []Candidate{
+		{Key: "old-protected", Item: Item{Accessed: 1, Hits: 1}},
+		{Key: "new-protected", Item: Item{Accessed: 4, Hits: 1}},
+		{Key: "probation", Item: Item{Accessed: 3}},
+		{Key: "older-probation", Item: Item{Accessed: 2}},
+	}
+	SLRU(0.25).Order(candidates)
+	want := []string{"old-protected", "older-probation", "probation", "new-protected"}
+	for i, v := range candidates {
+		if v.Key != want[i] {
+			t.Errorf("Candidate %d is %s, not %s", i, v.Key, want[i])
+		}
+	}
+}
+
+func TestDeleteLRUReturnsCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(1), WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+
+	if n := tc.DeleteLRU(); n != 2 {
+		t.Error("DeleteLRU returned", n, "instead of 2")
+	}
+	if n := tc.DeleteLRU(); n != 0 {
+		t.Error("DeleteLRU returned", n, "instead of 0")
+	}
+}
+
+func TestDeleteLRUKeys(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(1), WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+
+	keys := tc.DeleteLRUKeys()
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Error("DeleteLRUKeys returned", keys, "instead of [a b]")
+	}
+}
+
+func TestSampledEviction(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(900), SampledEviction(10), WithClock(clock))
+	for i := 0; i < 1000; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	keys := tc.DeleteLRUKeys()
+	if len(keys) != 100 || tc.ItemCount() != 900 {
+		t.Fatalf("evicted %d items, leaving %d; want 100 and 900", len(keys), tc.ItemCount())
+	}
+	sum := 0
+	for _, k := range keys {
+		i, _ := strconv.Atoi(k)
+		sum += i
+	}
+	// Uniformly random eviction would average about 500.
+	if avg := sum / len(keys); avg > 300 {
+		t.Errorf("evicted items were set at position %d on average; want mostly old items", avg)
+	}
+}
+
+func TestSampledEvictionBytes(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxBytes(10*(itemOverhead+3+100)), SampledEviction(5))
+	for i := 0; i < 50; i++ {
+		tc.Set(strconv.Itoa(100+i), string(make([]byte, 100)), DefaultExpiration)
+	}
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 10 {
+		t.Errorf("%d items left; want 10", n)
+	}
+}
+
+func TestHardCacheSize(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), HardCacheSize(3), WithClock(clock))
+	for _, k := range []string{"a", "b", "c", "d"} {
+		tc.Set(k, k, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	if n := tc.ItemCount(); n != 3 {
+		t.Fatalf("%d items in the cache; want 3", n)
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found, but it should have been evicted")
+	}
+	tc.Set("b", "b2", DefaultExpiration)
+	if _, found := tc.Get("c"); !found {
+		t.Error("replacing b evicted c")
+	}
+
+	// Expired items are deleted before live ones are evicted.
+	tc.Set("d", "d", time.Second)
+	clock.Advance(2 * time.Second)
+	if err := tc.Add("e", "e", DefaultExpiration); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"b", "c", "e"} {
+		if _, found := tc.Get(k); !found {
+			t.Errorf("%s was evicted instead of the expired d", k)
+		}
+	}
+
+	tc.SetMulti(map[string]interface{}{"f": 1, "g": 2}, DefaultExpiration)
+	if n := tc.ItemCount(); n != 3 {
+		t.Errorf("%d items in the cache after SetMulti; want 3", n)
+	}
+
+	// A full cache evicts a batch of items at a time.
+	tc = New(Expiration(DefaultExpiration), HardCacheSize(128), WithClock(clock))
+	for i := 0; i < 129; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	if n := tc.ItemCount(); n != 126 {
+		t.Errorf("%d items in the cache; want 126", n)
+	}
+}
+
+func TestEvictionTarget(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), EvictionTarget(0.8))
+	for i := 0; i < 11; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := tc.DeleteLRU(); n != 3 {
+		t.Errorf("evicted %d items; want 3 to get to 80%% of CacheSize", n)
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	if n := tc.DeleteLRU(); n != 0 {
+		t.Errorf("evicted %d items from a cache under its limit", n)
+	}
+
+	tc = New(Expiration(DefaultExpiration), MaxBytes(10*(itemOverhead+2+100)), EvictionTarget(0.5))
+	for i := 0; i < 11; i++ {
+		tc.Set(strconv.Itoa(10+i), string(make([]byte, 100)), DefaultExpiration)
+	}
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 5 {
+		t.Errorf("%d items left; want 5", n)
+	}
+
+	tc = New(Expiration(DefaultExpiration), HardCacheSize(10), EvictionTarget(0.5))
+	for i := 0; i < 11; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	if n := tc.ItemCount(); n != 5 {
+		t.Errorf("%d items left; want 5", n)
+	}
+	if _, err := NewWithError(EvictionTarget(1.5)); err == nil {
+		t.Error("EvictionTarget above 1 accepted")
+	}
+}
+
+func TestGetDoesNotAllocateWhenBounded(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Get("a")
+	// AllocsPerRun calls the function once more to warm up.
+	allocs := testing.AllocsPerRun(100, func() {
+		clock.Advance(time.Millisecond)
+		tc.Get("a")
+	})
+	if allocs != 0 {
+		t.Errorf("Get allocated %v times", allocs)
+	}
+	clock.Advance(time.Millisecond)
+	tc.Get("a")
+	items := tc.Items()
+	if v := items["a"]; v.Hits != 103 || v.Accessed != clock.Now().UnixNano() {
+		t.Errorf("item has %d hits, last accessed at %d; want 103 at %d", v.Hits, v.Accessed, clock.Now().UnixNano())
+	}
+	// Items passed to another cache don't share their access counts.
+	tc2 := New(Expiration(DefaultExpiration), CacheSize(10), WithClock(clock), InitialItems(items))
+	clock.Advance(time.Millisecond)
+	tc2.Get("a")
+	if v := tc.Items()["a"]; v.Hits != 103 {
+		t.Errorf("Get on another cache changed the hits of an item to %d", v.Hits)
+	}
+}
+
+func TestFirstCandidates(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	for i := 0; i < 1000; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+		tc.Set("expired"+strconv.Itoa(i), i, time.Second)
+	}
+	clock.Advance(2 * time.Second)
+	candidates := tc.firstCandidates(5)
+	if len(candidates) != 5 {
+		t.Fatalf("got %d candidates; want 5", len(candidates))
+	}
+	for _, v := range candidates {
+		if strings.HasPrefix(v.Key, "expired") {
+			t.Error("expired item", v.Key, "was sampled")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// Held while checking for and publishing a cache's variables, so that caches
+// published concurrently under the same name can't both pass the check.
+var expvarMu sync.Mutex
+
+// Publish the cache's statistics with the expvar package, under the names
+// cache.<name>.hits, cache.<name>.misses, cache.<name>.evictions,
+// cache.<name>.items and cache.<name>.janitor_run_ns, so that they are
+// served at /debug/vars. New returns an error if a cache has already been
+// published under name.
+//
+// Published variables cannot be removed, so a published cache is never
+// garbage collected. This option should only be used for long-lived caches.
+func PublishExpvar(name string) CacheOption {
+	return func(m *CacheOptions) error {
+		if expvarPublished(name) {
+			return fmt.Errorf("A cache named %s has already been published", name)
+		}
+		m.ExpvarName = name
+		return nil
+	}
+}
+
+func expvarPublished(name string) bool {
+	return expvar.Get("cache."+name+".hits") != nil
+}
+
+func publishExpvar(c *cache, name string) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvarPublished(name) {
+		return fmt.Errorf("A cache named %s has already been published", name)
+	}
+	prefix := "cache." + name + "."
+	expvar.Publish(prefix+"hits", expvar.Func(func() interface{} {
+		return c.Stats().Hits
+	}))
+	expvar.Publish(prefix+"misses", expvar.Func(func() interface{} {
+		return c.Stats().Misses
+	}))
+	expvar.Publish(prefix+"evictions", expvar.Func(func() interface{} {
+		return c.Stats().Evictions
+	}))
+	expvar.Publish(prefix+"items", expvar.Func(func() interface{} {
+		return c.ItemCount()
+	}))
+	expvar.Publish(prefix+"janitor_run_ns", expvar.Func(func() interface{} {
+		return int64(c.Stats().JanitorRunDuration)
+	}))
+	return nil
+}
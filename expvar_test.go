@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"expvar"
+	"sync"
+	"testing"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), PublishExpvar("expvartest"))
+	tc.Set("foo", "bar", DefaultExpiration)
+	tc.Get("foo")
+	tc.Get("baz")
+
+	for name, want := range map[string]string{
+		"cache.expvartest.hits":   "1",
+		"cache.expvartest.misses": "1",
+		"cache.expvartest.items":  "1",
+	} {
+		v := expvar.Get(name)
+		if v == nil {
+			t.Error(name, "was not published")
+			continue
+		}
+		if got := v.String(); got != want {
+			t.Errorf("%s is %s, not %s", name, got, want)
+		}
+	}
+
+	if oc := New(PublishExpvar("expvartest")); oc != nil {
+		t.Error("Published a second cache under the same name")
+	}
+}
+
+func TestPublishExpvarConcurrently(t *testing.T) {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		published int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := NewWithError(PublishExpvar("expvarconcurrent")); err == nil {
+				mu.Lock()
+				published++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if published != 1 {
+		t.Errorf("%d caches published under the same name; want 1", published)
+	}
+}
@@ -0,0 +1,370 @@
+package grpccache
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of cache.proto, encoded by hand with protowire so that the
+// package does not depend on generated code. They are wire-compatible with
+// clients generated from cache.proto in any language.
+
+type message interface {
+	marshal(b []byte) []byte
+	unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error)
+}
+
+type GetRequest struct {
+	Key string
+}
+
+type GetResponse struct {
+	Found              bool
+	Value              []byte
+	ExpirationUnixNano int64
+}
+
+type SetRequest struct {
+	Key   string
+	Value []byte
+	TTLMs int64
+}
+
+type SetResponse struct{}
+
+type DeleteRequest struct {
+	Key string
+}
+
+type DeleteResponse struct{}
+
+type GetMultiRequest struct {
+	Keys []string
+}
+
+type GetMultiResponse struct {
+	Items map[string][]byte
+}
+
+type StatsRequest struct{}
+
+type StatsResponse struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Items     int64
+}
+
+type WatchRequest struct {
+	KeyOrPrefix string
+}
+
+// Values of Event.Type, matching the EventType enum in cache.proto.
+const (
+	EventTypeUnspecified int32 = iota
+	EventTypeSet
+	EventTypeDelete
+	EventTypeExpire
+	EventTypeEvict
+	EventTypeFlush
+)
+
+type Event struct {
+	Type         int32
+	Key          string
+	Value        []byte
+	TimeUnixNano int64
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func marshal(m message) []byte {
+	return m.marshal(nil)
+}
+
+func unmarshal(b []byte, m message) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n, err := m.unmarshal(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// Unknown field
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// Decoders for a single field. They return 0 if the field has an unexpected
+// wire type, so that it is skipped like an unknown field.
+
+func consumeString(typ protowire.Type, b []byte, v *string) int {
+	if typ != protowire.BytesType {
+		return 0
+	}
+	s, n := protowire.ConsumeString(b)
+	*v = s
+	return n
+}
+
+func consumeBytes(typ protowire.Type, b []byte, v *[]byte) int {
+	if typ != protowire.BytesType {
+		return 0
+	}
+	s, n := protowire.ConsumeBytes(b)
+	*v = append([]byte(nil), s...)
+	return n
+}
+
+func consumeVarint(typ protowire.Type, b []byte, v *uint64) int {
+	if typ != protowire.VarintType {
+		return 0
+	}
+	x, n := protowire.ConsumeVarint(b)
+	*v = x
+	return n
+}
+
+func consumeInt64(typ protowire.Type, b []byte, v *int64) int {
+	var x uint64
+	n := consumeVarint(typ, b, &x)
+	*v = int64(x)
+	return n
+}
+
+func (m *GetRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, m.Key)
+}
+
+func (m *GetRequest) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if num == 1 {
+		return consumeString(typ, b, &m.Key), nil
+	}
+	return 0, nil
+}
+
+func (m *GetResponse) marshal(b []byte) []byte {
+	if m.Found {
+		b = appendVarint(b, 1, 1)
+	}
+	b = appendBytes(b, 2, m.Value)
+	return appendVarint(b, 3, uint64(m.ExpirationUnixNano))
+}
+
+func (m *GetResponse) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	switch num {
+	case 1:
+		var x uint64
+		n := consumeVarint(typ, b, &x)
+		m.Found = x != 0
+		return n, nil
+	case 2:
+		return consumeBytes(typ, b, &m.Value), nil
+	case 3:
+		return consumeInt64(typ, b, &m.ExpirationUnixNano), nil
+	}
+	return 0, nil
+}
+
+func (m *SetRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Key)
+	b = appendBytes(b, 2, m.Value)
+	return appendVarint(b, 3, uint64(m.TTLMs))
+}
+
+func (m *SetRequest) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	switch num {
+	case 1:
+		return consumeString(typ, b, &m.Key), nil
+	case 2:
+		return consumeBytes(typ, b, &m.Value), nil
+	case 3:
+		return consumeInt64(typ, b, &m.TTLMs), nil
+	}
+	return 0, nil
+}
+
+func (m *SetResponse) marshal(b []byte) []byte { return b }
+
+func (m *SetResponse) unmarshal(protowire.Number, protowire.Type, []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *DeleteRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, m.Key)
+}
+
+func (m *DeleteRequest) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if num == 1 {
+		return consumeString(typ, b, &m.Key), nil
+	}
+	return 0, nil
+}
+
+func (m *DeleteResponse) marshal(b []byte) []byte { return b }
+
+func (m *DeleteResponse) unmarshal(protowire.Number, protowire.Type, []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *GetMultiRequest) marshal(b []byte) []byte {
+	for _, k := range m.Keys {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, k)
+	}
+	return b
+}
+
+func (m *GetMultiRequest) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if num == 1 {
+		var k string
+		n := consumeString(typ, b, &k)
+		if n > 0 {
+			m.Keys = append(m.Keys, k)
+		}
+		return n, nil
+	}
+	return 0, nil
+}
+
+// A map field is encoded as a repeated message whose key is field 1 and
+// whose value is field 2.
+type mapEntry struct {
+	key   string
+	value []byte
+}
+
+func (e *mapEntry) marshal(b []byte) []byte {
+	b = appendString(b, 1, e.key)
+	return appendBytes(b, 2, e.value)
+}
+
+func (e *mapEntry) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	switch num {
+	case 1:
+		return consumeString(typ, b, &e.key), nil
+	case 2:
+		return consumeBytes(typ, b, &e.value), nil
+	}
+	return 0, nil
+}
+
+func (m *GetMultiResponse) marshal(b []byte) []byte {
+	for k, v := range m.Items {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, marshal(&mapEntry{k, v}))
+	}
+	return b
+}
+
+func (m *GetMultiResponse) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if num != 1 || typ != protowire.BytesType {
+		return 0, nil
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+	var e mapEntry
+	if err := unmarshal(v, &e); err != nil {
+		return 0, err
+	}
+	if m.Items == nil {
+		m.Items = make(map[string][]byte)
+	}
+	m.Items[e.key] = e.value
+	return n, nil
+}
+
+func (m *StatsRequest) marshal(b []byte) []byte { return b }
+
+func (m *StatsRequest) unmarshal(protowire.Number, protowire.Type, []byte) (int, error) {
+	return 0, nil
+}
+
+func (m *StatsResponse) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, m.Hits)
+	b = appendVarint(b, 2, m.Misses)
+	b = appendVarint(b, 3, m.Evictions)
+	return appendVarint(b, 4, uint64(m.Items))
+}
+
+func (m *StatsResponse) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	switch num {
+	case 1:
+		return consumeVarint(typ, b, &m.Hits), nil
+	case 2:
+		return consumeVarint(typ, b, &m.Misses), nil
+	case 3:
+		return consumeVarint(typ, b, &m.Evictions), nil
+	case 4:
+		return consumeInt64(typ, b, &m.Items), nil
+	}
+	return 0, nil
+}
+
+func (m *WatchRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, m.KeyOrPrefix)
+}
+
+func (m *WatchRequest) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	if num == 1 {
+		return consumeString(typ, b, &m.KeyOrPrefix), nil
+	}
+	return 0, nil
+}
+
+func (m *Event) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(m.Type))
+	b = appendString(b, 2, m.Key)
+	b = appendBytes(b, 3, m.Value)
+	return appendVarint(b, 4, uint64(m.TimeUnixNano))
+}
+
+func (m *Event) unmarshal(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	switch num {
+	case 1:
+		var x uint64
+		n := consumeVarint(typ, b, &x)
+		m.Type = int32(x)
+		return n, nil
+	case 2:
+		return consumeString(typ, b, &m.Key), nil
+	case 3:
+		return consumeBytes(typ, b, &m.Value), nil
+	case 4:
+		return consumeInt64(typ, b, &m.TimeUnixNano), nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,221 @@
+// Package grpccache exposes a cache.Cache as a gRPC service, so that
+// services written in other languages can use it. The service is defined in
+// cache.proto, from which clients can be generated as usual.
+//
+// The server does not use generated Go code: its messages are encoded by
+// hand, and must be sent and received with the package's Codec. NewServer
+// configures this automatically; when registering the service on an existing
+// server with Register, that server must have been created with
+//
+//	grpc.ForceServerCodec(grpccache.Codec())
+//
+// The codec passes any other message on to the standard protobuf codec, so
+// other services on the same server are unaffected.
+package grpccache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+type codec struct{}
+
+// Returns a gRPC codec handling the service's messages and delegating all
+// others to the registered "proto" codec.
+func Codec() encoding.Codec {
+	return codec{}
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(message); ok {
+		return marshal(m), nil
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Marshal(v)
+	}
+	return nil, fmt.Errorf("grpccache: cannot marshal %T", v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(message); ok {
+		return unmarshal(data, m)
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Unmarshal(data, v)
+	}
+	return fmt.Errorf("grpccache: cannot unmarshal %T", v)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+// Returns a gRPC server serving c, created with opts and the package's Codec.
+func NewServer(c *cache.Cache, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(append(opts, grpc.ForceServerCodec(Codec()))...)
+	Register(s, c)
+	return s
+}
+
+// Register the Cache service for c on s.
+func Register(s grpc.ServiceRegistrar, c *cache.Cache) {
+	s.RegisterService(&serviceDesc, &server{c: c})
+}
+
+type server struct {
+	c *cache.Cache
+}
+
+// Returns the bytes of a cached value, which must be a []byte or a string.
+func valueBytes(k string, x interface{}) ([]byte, error) {
+	switch v := x.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, status.Errorf(codes.FailedPrecondition, "Item %s is a %T, not bytes", k, x)
+}
+
+func (s *server) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	x, exp, found := s.c.GetWithExpiration(req.Key)
+	if !found {
+		return &GetResponse{}, nil
+	}
+	v, err := valueBytes(req.Key, x)
+	if err != nil {
+		return nil, err
+	}
+	res := &GetResponse{Found: true, Value: v}
+	if !exp.IsZero() {
+		res.ExpirationUnixNano = exp.UnixNano()
+	}
+	return res, nil
+}
+
+func (s *server) Set(ctx context.Context, req *SetRequest) (*SetResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "Key must not be empty")
+	}
+	d := cache.DefaultExpiration
+	if req.TTLMs < 0 {
+		d = cache.NoExpiration
+	} else if req.TTLMs > 0 {
+		d = time.Duration(req.TTLMs) * time.Millisecond
+	}
+	s.c.Set(req.Key, req.Value, d)
+	return &SetResponse{}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *DeleteRequest) (*DeleteResponse, error) {
+	s.c.Delete(req.Key)
+	return &DeleteResponse{}, nil
+}
+
+func (s *server) GetMulti(ctx context.Context, req *GetMultiRequest) (*GetMultiResponse, error) {
+	res := &GetMultiResponse{Items: make(map[string][]byte, len(req.Keys))}
+	for _, k := range req.Keys {
+		x, found := s.c.Get(k)
+		if !found {
+			continue
+		}
+		v, err := valueBytes(k, x)
+		if err != nil {
+			return nil, err
+		}
+		res.Items[k] = v
+	}
+	return res, nil
+}
+
+func (s *server) Stats(ctx context.Context, req *StatsRequest) (*StatsResponse, error) {
+	st := s.c.Stats()
+	return &StatsResponse{
+		Hits:      st.Hits,
+		Misses:    st.Misses,
+		Evictions: st.Evictions,
+		Items:     int64(s.c.ItemCount()),
+	}, nil
+}
+
+func (s *server) Watch(req *WatchRequest, stream grpc.ServerStream) error {
+	ch, cancel := s.c.Watch(req.KeyOrPrefix)
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			ev := &Event{
+				Type:         int32(e.Type),
+				Key:          e.Key,
+				TimeUnixNano: e.Time.UnixNano(),
+			}
+			// Values that aren't bytes are left out rather than ending
+			// the stream.
+			ev.Value, _ = valueBytes(e.Key, e.Value)
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unaryHandler[Req any, PReq interface {
+	*Req
+	message
+}, Res any](method string, fn func(*server, context.Context, PReq) (*Res, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := PReq(new(Req))
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return fn(srv.(*server), ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/gocache.v1.Cache/" + method,
+		}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(srv.(*server), ctx, req.(PReq))
+		})
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gocache.v1.Cache",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Get", Handler: unaryHandler("Get", (*server).Get)},
+		{MethodName: "Set", Handler: unaryHandler("Set", (*server).Set)},
+		{MethodName: "Delete", Handler: unaryHandler("Delete", (*server).Delete)},
+		{MethodName: "GetMulti", Handler: unaryHandler("GetMulti", (*server).GetMulti)},
+		{MethodName: "Stats", Handler: unaryHandler("Stats", (*server).Stats)},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Watch",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(WatchRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(*server).Watch(req, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cache.proto",
+}
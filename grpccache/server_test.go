@@ -0,0 +1,155 @@
+package grpccache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func TestCodec(t *testing.T) {
+	msgs := []message{
+		&GetRequest{Key: "foo"},
+		&GetResponse{Found: true, Value: []byte("bar"), ExpirationUnixNano: 1 << 60},
+		&SetRequest{Key: "foo", Value: []byte("bar"), TTLMs: -1},
+		&GetMultiRequest{Keys: []string{"a", "b"}},
+		&GetMultiResponse{Items: map[string][]byte{"a": []byte("1"), "b": []byte("2")}},
+		&StatsResponse{Hits: 1, Misses: 2, Evictions: 3, Items: 4},
+		&WatchRequest{KeyOrPrefix: "user:*"},
+		&Event{Type: EventTypeEvict, Key: "foo", Value: []byte("bar"), TimeUnixNano: 42},
+	}
+	for _, m := range msgs {
+		b, err := Codec().Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := reflect.New(reflect.TypeOf(m).Elem()).Interface()
+		if err := Codec().Unmarshal(b, got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("Round trip of %T: got %+v, want %+v", m, got, m)
+		}
+	}
+
+	// Unknown fields are skipped.
+	b := marshal(&Event{Type: EventTypeSet, Key: "foo", Value: []byte("x"), TimeUnixNano: 1})
+	var req GetRequest
+	if err := unmarshal(b, &req); err != nil || req.Key != "" {
+		t.Error("Unknown fields were not skipped:", req, err)
+	}
+	if err := unmarshal([]byte{0x0a, 0x05, 'a'}, &req); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestServer(t *testing.T) {
+	c := cache.New(cache.Expiration(time.Hour))
+	c.Set("obj", struct{}{}, cache.DefaultExpiration)
+	s := &server{c: c}
+	ctx := context.Background()
+
+	s.Set(ctx, &SetRequest{Key: "a", Value: []byte("1")})
+	s.Set(ctx, &SetRequest{Key: "b", Value: []byte("2"), TTLMs: -1})
+	if _, err := s.Set(ctx, &SetRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Error("Expected InvalidArgument for an empty key, got", err)
+	}
+
+	res, err := s.Get(ctx, &GetRequest{Key: "a"})
+	if err != nil || !res.Found || string(res.Value) != "1" || res.ExpirationUnixNano == 0 {
+		t.Error("Wrong response for a:", res, err)
+	}
+	res, _ = s.Get(ctx, &GetRequest{Key: "b"})
+	if res.ExpirationUnixNano != 0 {
+		t.Error("b should never expire:", res)
+	}
+	if res, _ := s.Get(ctx, &GetRequest{Key: "nope"}); res.Found {
+		t.Error("Found a missing key")
+	}
+	if _, err := s.Get(ctx, &GetRequest{Key: "obj"}); status.Code(err) != codes.FailedPrecondition {
+		t.Error("Expected FailedPrecondition for a non-bytes value, got", err)
+	}
+
+	multi, _ := s.GetMulti(ctx, &GetMultiRequest{Keys: []string{"a", "b", "nope"}})
+	if len(multi.Items) != 2 || string(multi.Items["b"]) != "2" {
+		t.Error("Wrong GetMulti response:", multi)
+	}
+
+	s.Delete(ctx, &DeleteRequest{Key: "a"})
+	if _, found := c.Get("a"); found {
+		t.Error("Delete did not delete a")
+	}
+
+	st, _ := s.Stats(ctx, &StatsRequest{})
+	if st.Items != 2 || st.Hits == 0 || st.Misses == 0 {
+		t.Error("Wrong stats:", st)
+	}
+}
+
+func TestUnaryHandler(t *testing.T) {
+	c := cache.New()
+	c.Set("foo", []byte("bar"), cache.DefaultExpiration)
+	h := serviceDesc.Methods[0].Handler
+	dec := func(v interface{}) error {
+		return Codec().Unmarshal(marshal(&GetRequest{Key: "foo"}), v)
+	}
+	var method string
+	res, err := h(&server{c: c}, context.Background(), dec, func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method = info.FullMethod
+		return handler(ctx, req)
+	})
+	if err != nil || string(res.(*GetResponse).Value) != "bar" {
+		t.Error("Wrong response from the Get handler:", res, err)
+	}
+	if method != "/gocache.v1.Cache/Get" {
+		t.Error("Wrong method passed to the interceptor:", method)
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *Event
+}
+
+func (f *fakeStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeStream) SendMsg(m interface{}) error {
+	f.sent <- m.(*Event)
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	c := cache.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeStream{ctx: ctx, sent: make(chan *Event, 10)}
+	done := make(chan error)
+	go func() {
+		done <- (&server{c: c}).Watch(&WatchRequest{KeyOrPrefix: "foo"}, stream)
+	}()
+
+	// Wait for the watch to be registered.
+	for i := 0; i < 100; i++ {
+		c.Set("foo", []byte("bar"), cache.DefaultExpiration)
+		select {
+		case e := <-stream.sent:
+			if e.Type != EventTypeSet || e.Key != "foo" || string(e.Value) != "bar" {
+				t.Error("Wrong event:", e)
+			}
+			i = 100
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Error("Watch returned", err)
+	}
+}
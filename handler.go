@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type handler struct {
+	c *cache
+}
+
+// Returns an http.Handler for inspecting and administering the cache, meant
+// to be mounted behind authentication on an internal port, e.g.
+//
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", c.Handler()))
+//
+// It serves the following endpoints, all of which respond with JSON:
+//
+//	GET    /keys?prefix=&offset=&limit=  sorted keys, 100 per page by default
+//	GET    /items/{key}                  an item's value, TTL and access times
+//	DELETE /items/{key}                  delete an item
+//	POST   /flush                        delete all items
+//	GET    /stats                        the cache's Stats and item count
+func (c *cache) Handler() http.Handler {
+	return &handler{c: c}
+}
+
+type keysPage struct {
+	Keys  []string `json:"keys"`
+	Total int      `json:"total"`
+	// Offset of the next page, or 0 if this is the last page.
+	Next int `json:"next,omitempty"`
+}
+
+type itemInfo struct {
+	Key        string          `json:"key"`
+	Value      json.RawMessage `json:"value"`
+	Expiration *time.Time      `json:"expiration,omitempty"`
+	TTL        string          `json:"ttl,omitempty"`
+	Created    *time.Time      `json:"created,omitempty"`
+	Accessed   *time.Time      `json:"accessed,omitempty"`
+	Hits       int64           `json:"hits"`
+	Tags       []string        `json:"tags,omitempty"`
+}
+
+type statsInfo struct {
+	Stats
+	Items int `json:"items"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case p == "/keys" && r.Method == http.MethodGet:
+		h.keys(w, r)
+	case strings.HasPrefix(p, "/items/") && r.Method == http.MethodGet:
+		h.item(w, strings.TrimPrefix(r.URL.Path, "/items/"))
+	case strings.HasPrefix(p, "/items/") && r.Method == http.MethodDelete:
+		k := strings.TrimPrefix(r.URL.Path, "/items/")
+		if _, found := h.c.items.Load(k); !found {
+			writeError(w, http.StatusNotFound, fmt.Errorf("Item %s not found", k))
+			return
+		}
+		h.c.Delete(k)
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/flush" && r.Method == http.MethodPost:
+		h.c.Flush()
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/stats" && r.Method == http.MethodGet:
+		writeJSON(w, statsInfo{Stats: h.c.Stats(), Items: h.c.ItemCount()})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("No such endpoint: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h *handler) keys(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	offset, limit := 0, 100
+	var err error
+	if s := q.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid offset %q", s))
+			return
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid limit %q", s))
+			return
+		}
+	}
+	keys := h.c.KeysWithPrefix(q.Get("prefix"))
+	sort.Strings(keys)
+	page := keysPage{Keys: []string{}, Total: len(keys)}
+	if offset < len(keys) {
+		end := offset + limit
+		if end < len(keys) {
+			page.Next = end
+		} else {
+			end = len(keys)
+		}
+		page.Keys = keys[offset:end]
+	}
+	writeJSON(w, page)
+}
+
+func (h *handler) item(w http.ResponseWriter, k string) {
+	v, found := h.c.items.Load(k)
+	if !found || h.c.expired(itemOf(v)) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("Item %s not found", k))
+		return
+	}
+	item := itemOf(v)
+	info := itemInfo{
+		Key:  k,
+		Hits: item.Hits,
+		Tags: item.Tags,
+	}
+	if b, err := json.Marshal(item.Object); err == nil {
+		info.Value = b
+	} else {
+		info.Value, _ = json.Marshal(fmt.Sprintf("%#v", item.Object))
+	}
+	if item.Expiration > 0 {
+		exp := time.Unix(0, item.Expiration)
+		info.Expiration = &exp
+		info.TTL = exp.Sub(h.c.now()).String()
+	}
+	if item.Created > 0 {
+		created := time.Unix(0, item.Created)
+		info.Created = &created
+	}
+	if item.Accessed > 0 {
+		accessed := time.Unix(0, item.Accessed)
+		info.Accessed = &accessed
+	}
+	writeJSON(w, info)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(t *testing.T, h http.Handler, method, path string, v interface{}) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", "two", time.Hour)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.Set("x", 4, DefaultExpiration)
+	h := tc.Handler()
+
+	var page keysPage
+	if code := serve(t, h, "GET", "/keys?limit=2", &page); code != http.StatusOK {
+		t.Fatal("GET /keys returned", code)
+	}
+	if len(page.Keys) != 2 || page.Keys[0] != "a" || page.Keys[1] != "b" || page.Next != 2 || page.Total != 4 {
+		t.Error("Wrong first page:", page)
+	}
+	page = keysPage{}
+	serve(t, h, "GET", "/keys?limit=2&offset=2", &page)
+	if len(page.Keys) != 2 || page.Keys[0] != "c" || page.Next != 0 {
+		t.Error("Wrong last page:", page)
+	}
+	page = keysPage{}
+	serve(t, h, "GET", "/keys?prefix=x", &page)
+	if len(page.Keys) != 1 || page.Keys[0] != "x" {
+		t.Error("Wrong page for prefix:", page)
+	}
+	if code := serve(t, h, "GET", "/keys?limit=0", nil); code != http.StatusBadRequest {
+		t.Error("Expected 400 for a bad limit, got", code)
+	}
+
+	var info itemInfo
+	if code := serve(t, h, "GET", "/items/b", &info); code != http.StatusOK {
+		t.Fatal("GET /items/b returned", code)
+	}
+	if string(info.Value) != `"two"` || info.Expiration == nil || info.TTL == "" {
+		t.Error("Wrong item info:", info)
+	}
+	if code := serve(t, h, "GET", "/items/nope", nil); code != http.StatusNotFound {
+		t.Error("Expected 404 for a missing item, got", code)
+	}
+
+	if code := serve(t, h, "DELETE", "/items/a", nil); code != http.StatusNoContent {
+		t.Error("DELETE /items/a returned", code)
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("Item was not deleted")
+	}
+
+	var stats statsInfo
+	serve(t, h, "GET", "/stats", &stats)
+	if stats.Items != 3 || stats.Hits != 0 || stats.Misses != 1 {
+		t.Error("Wrong stats:", stats)
+	}
+
+	if code := serve(t, h, "POST", "/flush", nil); code != http.StatusNoContent {
+		t.Error("POST /flush returned", code)
+	}
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("Flush left", n, "items")
+	}
+	if code := serve(t, h, "POST", "/keys", nil); code != http.StatusNotFound {
+		t.Error("Expected 404 for an unknown endpoint, got", code)
+	}
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Returned to modify by functions that leave the value as it is.
+var errUnchanged = errors.New("cache: unchanged")
+
+// Replace the value of k with the one fn returns, atomically with respect to
+// the other methods using modify, like Append. fn is called with c.mu held,
+// with the current value of k and true, or nil and false if k is not in the
+// cache (or has expired); in that case the new item gets the expiration d,
+// otherwise the expiration is unchanged. If fn returns remove, k is deleted
+// instead. If it returns an error, k is left as it is, and modify returns the
+// error, unless it is errUnchanged. If the new value is larger than
+// MaxValueBytes, modify returns ErrTooLarge.
+func (c *cache) modify(k string, d time.Duration, fn func(x interface{}, found bool) (nx interface{}, remove bool, err error)) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	// Eviction callbacks and events are delivered without holding c.mu, so
+	// room for a new item is made first.
+	c.makeRoom(k)
+	c.mu.Lock()
+	v, found := c.getItem(k)
+	if found && c.expired(v) {
+		found = false
+	}
+	var x interface{}
+	if found {
+		x = v.Object
+	}
+	nx, remove, err := fn(x, found)
+	if err == nil && !remove {
+		err = c.checkSize(k, nx)
+	}
+	if err != nil {
+		c.mu.Unlock()
+		if err == errUnchanged {
+			return nil
+		}
+		return err
+	}
+	if remove {
+		ov, removed := c.remove(k)
+		if removed {
+			c.tags.remove(k, ov.Tags)
+		}
+		c.mu.Unlock()
+		if removed {
+			c.notify(EventDelete, k, ov.Object)
+			if c.hasEvictionCallback() && !c.EvictionsOnly {
+				c.evictOne(k, ov.Object, EventDelete)
+			}
+		}
+		return nil
+	}
+	if !found {
+		nx = c.copyIn(nx)
+		c.put(k, nx, d)
+		c.mu.Unlock()
+		c.notify(EventSet, k, nx)
+		return nil
+	}
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
+	}
+	v.Object = nx
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, nx)
+	return nil
+}
+
+// Set field to x in the hash stored under k, a map[string]interface{}, like
+// Redis' HSET. If k is not in the cache (or has expired), it is set to a new
+// hash with the expiration d; otherwise its expiration is unchanged. Returns
+// an error if the value of k is not a hash.
+//
+// Hashes are never modified in place: every change copies the map, so maps
+// returned by Get and HGetAll remain valid, and concurrent changes to
+// different fields are not lost. This suits hashes of modest size.
+func (c *cache) HSet(k, field string, x interface{}, d time.Duration) error {
+	return c.modify(k, d, func(old interface{}, found bool) (interface{}, bool, error) {
+		h, err := hashOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		nh := make(map[string]interface{}, len(h)+1)
+		for f, v := range h {
+			nh[f] = v
+		}
+		nh[field] = x
+		return nh, false, nil
+	})
+}
+
+// Returns the value of field in the hash stored under k, and whether it was
+// found. A k that is not a hash is treated as missing.
+func (c *cache) HGet(k, field string) (interface{}, bool) {
+	item, found := c.lookup(k)
+	if !found {
+		return nil, false
+	}
+	h, ok := item.Object.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	x, found := h[field]
+	return x, found
+}
+
+// Delete fields from the hash stored under k, deleting k itself if no fields
+// are left, like Redis' HDEL. Returns the number of fields deleted, or an error
+// if the value of k is not a hash.
+func (c *cache) HDel(k string, fields ...string) (int, error) {
+	n := 0
+	err := c.modify(k, DefaultExpiration, func(old interface{}, found bool) (interface{}, bool, error) {
+		if !found {
+			return nil, false, errUnchanged
+		}
+		h, err := hashOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		nh := make(map[string]interface{}, len(h))
+		for f, v := range h {
+			nh[f] = v
+		}
+		for _, f := range fields {
+			if _, ok := nh[f]; ok {
+				delete(nh, f)
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, false, errUnchanged
+		}
+		return nh, len(nh) == 0, nil
+	})
+	return n, err
+}
+
+// Returns a copy of the hash stored under k, or nil if k is not in the cache
+// or is not a hash.
+func (c *cache) HGetAll(k string) map[string]interface{} {
+	item, found := c.lookup(k)
+	if !found {
+		return nil
+	}
+	h, ok := item.Object.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	m := make(map[string]interface{}, len(h))
+	for f, v := range h {
+		m[f] = v
+	}
+	return m
+}
+
+// Returns x, the value of k, as a hash, or an empty hash if k was not found.
+func hashOf(k string, x interface{}, found bool) (map[string]interface{}, error) {
+	if !found {
+		return nil, nil
+	}
+	h, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("The value for %s is not a map[string]interface{}", k)
+	}
+	return h, nil
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	if err := tc.HSet("u", "name", "ann", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	tc.HSet("u", "age", 30, time.Hour)
+	if x, found := tc.HGet("u", "name"); !found || x != "ann" {
+		t.Errorf("got %v, %v; want ann", x, found)
+	}
+	all := tc.HGetAll("u")
+	if len(all) != 2 || all["age"] != 30 {
+		t.Errorf("HGetAll returned %v", all)
+	}
+	all["name"] = "bob"
+	if x, _ := tc.HGet("u", "name"); x != "ann" {
+		t.Error("modifying the result of HGetAll changed the hash")
+	}
+	if _, exp, _ := tc.GetWithExpiration("u"); !exp.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("hash expires at %v; want the expiration it was created with", exp)
+	}
+
+	if n, err := tc.HDel("u", "name", "missing"); n != 1 || err != nil {
+		t.Errorf("HDel returned %d, %v; want 1", n, err)
+	}
+	if n, _ := tc.HDel("u", "name"); n != 0 {
+		t.Errorf("HDel deleted %d missing fields", n)
+	}
+	tc.HDel("u", "age")
+	if _, found := tc.Get("u"); found {
+		t.Error("empty hash was not deleted")
+	}
+
+	if n, err := tc.HDel("missing", "f"); n != 0 || err != nil {
+		t.Errorf("HDel of a missing hash returned %d, %v", n, err)
+	}
+	if _, found := tc.Get("missing"); found {
+		t.Error("HDel of a missing hash created it")
+	}
+
+	tc.Set("s", "string", DefaultExpiration)
+	if err := tc.HSet("s", "f", 1, DefaultExpiration); err == nil {
+		t.Error("HSet on a string succeeded")
+	}
+	if _, found := tc.HGet("s", "f"); found {
+		t.Error("HGet on a string found a field")
+	}
+}
+
+func TestHashConcurrent(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tc.HSet("h", string(rune('a'+i)), i, DefaultExpiration)
+		}(i)
+	}
+	wg.Wait()
+	if n := len(tc.HGetAll("h")); n != 50 {
+		t.Errorf("hash has %d fields; want 50", n)
+	}
+}
+
+func TestHashEvictionCallback(t *testing.T) {
+	var tc *Cache
+	called := false
+	tc = New(Expiration(DefaultExpiration), HardCacheSize(1), EvictionCallback(func(k string, _ interface{}) {
+		// Would deadlock if the callback ran while HSet held the lock.
+		tc.HDel("other", "f")
+		called = true
+	}))
+	tc.HSet("a", "f", 1, DefaultExpiration)
+	tc.HSet("b", "f", 2, DefaultExpiration)
+	if !called {
+		t.Error("eviction callback not called")
+	}
+	tc.HDel("b", "f")
+	if _, found := tc.Get("b"); found {
+		t.Error("b not deleted with its last field")
+	}
+}
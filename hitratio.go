@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Also track hits and misses over the last d (rounded to a tenth of d), and
+// report them in the Window fields of Stats, so that the hit ratio reflects
+// current traffic rather than the whole lifetime of the cache.
+func HitRatioWindow(d time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.HitRatioWindow = d
+		return nil
+	}
+}
+
+// The number of buckets a hit ratio window is divided into.
+const windowBuckets = 10
+
+type windowBucket struct {
+	hits   uint64
+	misses uint64
+	// The bucket's index since the epoch, in units of the bucket width.
+	epoch int64
+}
+
+// A hitWindow counts hits and misses in a ring of buckets, each covering a
+// tenth of the window.
+type hitWindow struct {
+	width   int64
+	buckets [windowBuckets]windowBucket
+	mu      sync.Mutex
+}
+
+func newHitWindow(d time.Duration) *hitWindow {
+	width := int64(d) / windowBuckets
+	if width < 1 {
+		width = 1
+	}
+	return &hitWindow{width: width}
+}
+
+// Returns the bucket for the time now, clearing it if it was last used for an
+// earlier period.
+func (w *hitWindow) bucket(now int64) *windowBucket {
+	epoch := now / w.width
+	b := &w.buckets[epoch%windowBuckets]
+	if atomic.LoadInt64(&b.epoch) != epoch {
+		w.mu.Lock()
+		if b.epoch != epoch {
+			atomic.StoreUint64(&b.hits, 0)
+			atomic.StoreUint64(&b.misses, 0)
+			atomic.StoreInt64(&b.epoch, epoch)
+		}
+		w.mu.Unlock()
+	}
+	return b
+}
+
+func (w *hitWindow) hit(now int64) {
+	atomic.AddUint64(&w.bucket(now).hits, 1)
+}
+
+func (w *hitWindow) miss(now int64) {
+	atomic.AddUint64(&w.bucket(now).misses, 1)
+}
+
+// Returns the number of hits and misses in the window ending at now.
+func (w *hitWindow) counts(now int64) (hits, misses uint64) {
+	epoch := now / w.width
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if e := atomic.LoadInt64(&b.epoch); e > epoch-windowBuckets && e <= epoch {
+			hits += atomic.LoadUint64(&b.hits)
+			misses += atomic.LoadUint64(&b.misses)
+		}
+	}
+	return hits, misses
+}
+
+func (c *cache) hit() {
+	c.stats.hit()
+	if c.window != nil {
+		c.window.hit(c.now().UnixNano())
+	}
+}
+
+func (c *cache) miss() {
+	c.stats.miss()
+	if c.window != nil {
+		c.window.miss(c.now().UnixNano())
+	}
+}
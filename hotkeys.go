@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+)
+
+// Track the approximate access frequency of every key, and the n most
+// frequently accessed keys, so that they can be reported by TopKeys.
+// Frequencies are estimated with a count-min sketch, and decay over time so
+// that the report reflects recent traffic. Tracking makes every Get and Set a
+// little slower.
+func HotKeys(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.HotKeys = n
+		return nil
+	}
+}
+
+// KeyStats describes one of the keys reported by TopKeys.
+type KeyStats struct {
+	Key string
+	// The estimated number of recent accesses (Gets and Sets.)
+	Accesses uint64
+	// The estimated size of the item in bytes (see MaxBytes), or 0 if the
+	// key is not in the cache.
+	Size int64
+}
+
+// The width of each row of the sketch per tracked top key.
+const hotKeysWidthFactor = 256
+
+type hotKeys struct {
+	mu        sync.Mutex
+	seed      maphash.Seed
+	counters  []uint32
+	width     uint64
+	additions int
+	resetAt   int
+	top       map[string]uint32
+	size      int
+	// The smallest count in top, if it is full.
+	min uint32
+}
+
+func newHotKeys(n int) *hotKeys {
+	width := uint64(1024)
+	for width < uint64(n*hotKeysWidthFactor) {
+		width <<= 1
+	}
+	return &hotKeys{
+		seed:     maphash.MakeSeed(),
+		counters: make([]uint32, sketchDepth*width),
+		width:    width,
+		resetAt:  10 * int(width),
+		top:      make(map[string]uint32, n),
+		size:     n,
+	}
+}
+
+func (h *hotKeys) record(k string) {
+	var (
+		hash = maphash.String(h.seed, k)
+		h1   = hash & 0xffffffff
+		h2   = hash >> 32
+	)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	est := ^uint32(0)
+	for i := uint64(0); i < sketchDepth; i++ {
+		j := i*h.width + (h1+i*h2)&(h.width-1)
+		h.counters[j]++
+		if h.counters[j] < est {
+			est = h.counters[j]
+		}
+	}
+	h.additions++
+	if h.additions >= h.resetAt {
+		h.decay()
+	}
+	if _, ok := h.top[k]; ok || len(h.top) < h.size {
+		h.top[k] = est
+		h.updateMin()
+		return
+	}
+	if est <= h.min {
+		return
+	}
+	for tk, n := range h.top {
+		if n == h.min {
+			delete(h.top, tk)
+			break
+		}
+	}
+	h.top[k] = est
+	h.updateMin()
+}
+
+// Must be called with h.mu held.
+func (h *hotKeys) decay() {
+	for i := range h.counters {
+		h.counters[i] /= 2
+	}
+	for k, n := range h.top {
+		h.top[k] = n / 2
+	}
+	h.additions /= 2
+}
+
+// Must be called with h.mu held.
+func (h *hotKeys) updateMin() {
+	if len(h.top) < h.size {
+		h.min = 0
+		return
+	}
+	h.min = ^uint32(0)
+	for _, n := range h.top {
+		if n < h.min {
+			h.min = n
+		}
+	}
+}
+
+// Returns up to n of the most frequently accessed keys, most frequently
+// accessed first. Returns nil unless the HotKeys option is set; at most as
+// many keys as given to HotKeys are tracked.
+func (c *cache) TopKeys(n int) []KeyStats {
+	h := c.hotKeys
+	if h == nil || n <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	res := make([]KeyStats, 0, len(h.top))
+	for k, v := range h.top {
+		res = append(res, KeyStats{Key: k, Accesses: uint64(v)})
+	}
+	h.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Accesses != res[j].Accesses {
+			return res[i].Accesses > res[j].Accesses
+		}
+		return res[i].Key < res[j].Key
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	for i := range res {
+		if v, found := c.getItem(res[i].Key); found {
+			res[i].Size = estimateSize(res[i].Key, v.Object)
+		}
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTopKeys(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), HotKeys(3))
+	for i := 0; i < 10; i++ {
+		tc.Set(fmt.Sprint("k", i), i, DefaultExpiration)
+	}
+	for i := 0; i < 100; i++ {
+		tc.Get("hot")
+		if i%2 == 0 {
+			tc.Get("k1")
+		}
+		if i%4 == 0 {
+			tc.Get("k2")
+		}
+		tc.Get(fmt.Sprint("cold", i))
+	}
+	top := tc.TopKeys(2)
+	if len(top) != 2 {
+		t.Fatalf("got %d keys; want 2", len(top))
+	}
+	if top[0].Key != "hot" || top[1].Key != "k1" {
+		t.Errorf("top keys are %q, %q; want hot, k1", top[0].Key, top[1].Key)
+	}
+	if top[0].Accesses < 100 {
+		t.Errorf("hot has %d accesses; want at least 100", top[0].Accesses)
+	}
+	if top[0].Size != 0 {
+		t.Errorf("hot is not in the cache but has size %d", top[0].Size)
+	}
+	if top[1].Size == 0 {
+		t.Error("k1 is in the cache but has size 0")
+	}
+	if n := len(tc.TopKeys(10)); n != 3 {
+		t.Errorf("got %d keys; want the 3 tracked", n)
+	}
+}
+
+func TestTopKeysDisabled(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Get("a")
+	if top := tc.TopKeys(5); top != nil {
+		t.Errorf("got %v without HotKeys", top)
+	}
+	if _, err := NewWithError(HotKeys(-1)); err == nil {
+		t.Error("negative HotKeys accepted")
+	}
+}
@@ -0,0 +1,267 @@
+// Package httpcache provides HTTP middleware caching responses in a
+// cache.Cache.
+//
+// Responses to GET and HEAD requests are cached under their method, host and
+// URL, plus the values of any request headers named in the response's Vary
+// header. A response is only cached if its status is 200 and its
+// Cache-Control header allows shared caching; its max-age (or s-maxage)
+// becomes the item's expiration. Responses without a max-age are cached for
+// the DefaultTTL option, if one is given, and not at all otherwise.
+//
+// As RFC 9111 requires of shared caches, responses setting cookies are never
+// cached, and responses to requests carrying an Authorization header are only
+// cached if their Cache-Control header has public, s-maxage or
+// must-revalidate.
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+type Option func(*middleware)
+
+// Cache responses without a max-age for d.
+func DefaultTTL(d time.Duration) Option {
+	return func(m *middleware) {
+		m.defaultTTL = d
+	}
+}
+
+// Prefix every key with prefix, so that several handlers can share a cache.
+func KeyPrefix(prefix string) Option {
+	return func(m *middleware) {
+		m.prefix = prefix
+	}
+}
+
+// Don't cache responses whose body is larger than n bytes.
+func MaxBodySize(n int) Option {
+	return func(m *middleware) {
+		m.maxBody = n
+	}
+}
+
+type middleware struct {
+	c          *cache.Cache
+	next       http.Handler
+	defaultTTL time.Duration
+	prefix     string
+	maxBody    int
+}
+
+// Returns middleware serving cached responses from c. Responses served from
+// the cache carry an "X-Cache: HIT" header, and others "X-Cache: MISS".
+func Middleware(c *cache.Cache, opts ...Option) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		m := &middleware{
+			c:       c,
+			next:    next,
+			maxBody: 1 << 20,
+		}
+		for _, opt := range opts {
+			opt(m)
+		}
+		return m
+	}
+}
+
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+	base := m.prefix + r.Method + " " + r.Host + " " + r.URL.String()
+	reqCC := parseCacheControl(r.Header.Get("Cache-Control"))
+	if _, noCache := reqCC["no-cache"]; !noCache {
+		if res, ok := m.lookup(base, r); ok {
+			res.write(w, r, "HIT")
+			return
+		}
+	}
+
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK, max: m.maxBody}
+	w.Header().Set("X-Cache", "MISS")
+	m.next.ServeHTTP(rec, r)
+	if rec.overflow {
+		return
+	}
+	if d, ok := m.ttl(rec, r); ok {
+		if _, noStore := reqCC["no-store"]; noStore {
+			return
+		}
+		vary := varyHeaders(rec.Header())
+		if len(vary) > 0 && vary[0] == "*" {
+			return
+		}
+		header := rec.Header().Clone()
+		header.Del("X-Cache")
+		m.c.Set(base, vary, d)
+		m.c.Set(variantKey(base, vary, r), &response{
+			status: rec.status,
+			header: header,
+			body:   rec.body.Bytes(),
+		}, d)
+	}
+}
+
+// Returns the cached response for the request, if any. The item stored
+// under base holds the response's Vary header names, from which the key of
+// the variant matching the request is derived.
+func (m *middleware) lookup(base string, r *http.Request) (*response, bool) {
+	x, found := m.c.Get(base)
+	if !found {
+		return nil, false
+	}
+	// The cache may be shared, so anything else stored under these keys is
+	// treated as a miss.
+	vary, ok := x.([]string)
+	if !ok {
+		return nil, false
+	}
+	x, found = m.c.Get(variantKey(base, vary, r))
+	if !found {
+		return nil, false
+	}
+	res, ok := x.(*response)
+	return res, ok
+}
+
+// Returns how long the recorded response to r may be cached, and whether it
+// may be cached at all.
+func (m *middleware) ttl(rec *recorder, r *http.Request) (time.Duration, bool) {
+	if rec.status != http.StatusOK {
+		return 0, false
+	}
+	if _, found := rec.Header()["Set-Cookie"]; found {
+		return 0, false
+	}
+	cc := parseCacheControl(rec.Header().Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, found := cc[directive]; found {
+			return 0, false
+		}
+	}
+	if r.Header.Get("Authorization") != "" && !sharable(cc) {
+		return 0, false
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, found := cc[directive]; found {
+			secs, err := strconv.Atoi(v)
+			if err != nil || secs <= 0 {
+				return 0, false
+			}
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if m.defaultTTL > 0 {
+		return m.defaultTTL, true
+	}
+	return 0, false
+}
+
+// Reports whether cc explicitly allows a shared cache to store a response
+// to a request carrying an Authorization header (RFC 9111, section 3.5).
+func sharable(cc map[string]string) bool {
+	for _, directive := range []string{"public", "s-maxage", "must-revalidate"} {
+		if _, found := cc[directive]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func variantKey(base string, vary []string, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(base)
+	b.WriteString("\x00")
+	for _, h := range vary {
+		b.WriteString(h + ":" + strings.Join(r.Header.Values(h), ",") + "\x00")
+	}
+	return b.String()
+}
+
+// Returns the canonical, sorted header names listed in h's Vary header.
+func varyHeaders(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name == "*" {
+				return []string{"*"}
+			} else if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseCacheControl(s string) map[string]string {
+	cc := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return cc
+}
+
+func (res *response) write(w http.ResponseWriter, r *http.Request, xcache string) {
+	h := w.Header()
+	for k, v := range res.header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set("X-Cache", xcache)
+	w.WriteHeader(res.status)
+	if r.Method != http.MethodHead {
+		w.Write(res.body)
+	}
+}
+
+// A recorder passes a response through to the client while keeping a copy
+// of its body, up to max bytes.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	max         int
+	overflow    bool
+	wroteHeader bool
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	if !rec.overflow {
+		if rec.body.Len()+len(b) > rec.max {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
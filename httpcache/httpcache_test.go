@@ -0,0 +1,160 @@
+package httpcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func get(h http.Handler, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		case "/error":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintf(w, "%s %d %s", r.URL.Path, calls, r.Header.Get("Accept-Language"))
+	})
+	c := cache.New()
+	mw := Middleware(c)(h)
+
+	first := get(mw, "/public")
+	second := get(mw, "/public")
+	if first.Body.String() != second.Body.String() || calls != 1 {
+		t.Error("Second response was not served from the cache:", second.Body.String(), calls)
+	}
+	if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+		t.Error("Wrong X-Cache headers:", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+	if second.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Error("Cached response lost its headers:", second.Header())
+	}
+	_, exp, _ := c.GetWithExpiration("GET example.com /public")
+	if until := time.Until(exp); until < 59*time.Second || until > 60*time.Second {
+		t.Error("max-age was not used as the expiration:", exp)
+	}
+
+	get(mw, "/public", "Cache-Control", "no-cache")
+	if calls != 2 {
+		t.Error("Request with no-cache was served from the cache")
+	}
+
+	calls = 0
+	get(mw, "/private")
+	get(mw, "/private")
+	get(mw, "/error")
+	get(mw, "/error")
+	get(mw, "/none")
+	get(mw, "/none")
+	if calls != 6 {
+		t.Error("Uncacheable responses were cached:", calls)
+	}
+
+	calls = 0
+	en := get(mw, "/vary", "Accept-Language", "en")
+	de := get(mw, "/vary", "Accept-Language", "de")
+	en2 := get(mw, "/vary", "Accept-Language", "en")
+	if calls != 2 || en.Body.String() != en2.Body.String() || en.Body.String() == de.Body.String() {
+		t.Error("Vary was not honored:", calls, en.Body.String(), de.Body.String(), en2.Body.String())
+	}
+}
+
+func TestMiddlewareOptions(t *testing.T) {
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, r.URL.Path)
+	})
+	c := cache.New()
+	mw := Middleware(c, DefaultTTL(time.Minute), KeyPrefix("api:"), MaxBodySize(5))(h)
+
+	get(mw, "/abc")
+	get(mw, "/abc")
+	if calls != 1 {
+		t.Error("DefaultTTL did not cache a response without max-age")
+	}
+	if _, found := c.Get("api:GET example.com /abc"); !found {
+		t.Error("KeyPrefix was not applied")
+	}
+
+	calls = 0
+	get(mw, "/toolong")
+	if rec := get(mw, "/toolong"); calls != 2 || rec.Body.String() != "/toolong" {
+		t.Error("Response over MaxBodySize was cached or truncated:", calls, rec.Body.String())
+	}
+
+	req := httptest.NewRequest("POST", "/abc", nil)
+	mw.ServeHTTP(httptest.NewRecorder(), req)
+	if calls != 3 {
+		t.Error("POST was served from the cache")
+	}
+}
+
+func TestMiddlewareSharedCacheRules(t *testing.T) {
+	calls := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/cookie":
+			w.Header().Set("Set-Cookie", "session=1")
+		case "/auth-public":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, "%s %s %d", r.Host, r.URL.Path, calls)
+	})
+	c := cache.New()
+	mw := Middleware(c)(h)
+
+	a := httptest.NewRequest("GET", "http://a.example/page", nil)
+	b := httptest.NewRequest("GET", "http://b.example/page", nil)
+	mw.ServeHTTP(httptest.NewRecorder(), a)
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, b)
+	if rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("A response for another host was served:", rec.Body.String())
+	}
+
+	get(mw, "/cookie")
+	if rec := get(mw, "/cookie"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("A response setting a cookie was cached")
+	}
+	get(mw, "/auth", "Authorization", "Bearer x")
+	if rec := get(mw, "/auth"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("A response to an authorized request was cached")
+	}
+	get(mw, "/auth-public", "Authorization", "Bearer x")
+	if rec := get(mw, "/auth-public"); rec.Header().Get("X-Cache") != "HIT" {
+		t.Error("A public response to an authorized request was not cached")
+	}
+
+	c.Set("GET example.com /other", 42, cache.DefaultExpiration)
+	if rec := get(mw, "/other"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Error("Another value under the key was not treated as a miss")
+	}
+}
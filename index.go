@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"sync"
+)
+
+// A secondary index from the values extracted from items to their keys. Like
+// tagIndex, entries may outlive the items they were added for, so GetByIndex
+// checks each item before returning it.
+type secondaryIndex struct {
+	extract func(interface{}) []string
+	mu      sync.Mutex
+	keys    map[string]map[string]struct{}
+}
+
+// Maintain an index named name, mapping the values extract returns for each
+// item's value to the item's key, so that the items can be looked up by
+// attributes of their values with GetByIndex, e.g.
+//
+//	Index("by_user", func(v interface{}) []string {
+//		return []string{v.(*Session).UserID}
+//	})
+//
+// The index is updated whenever an item is set or deleted, so extract must be
+// fast, and must return the same values for the same value; it must not call
+// the cache. Values modified in place, rather than replaced with Set, are
+// not reindexed.
+func Index(name string, extract func(v interface{}) []string) CacheOption {
+	return func(m *CacheOptions) error {
+		if m.Indexes == nil {
+			m.Indexes = make(map[string]func(interface{}) []string)
+		}
+		m.Indexes[name] = extract
+		return nil
+	}
+}
+
+func newIndexes(extractors map[string]func(interface{}) []string) map[string]*secondaryIndex {
+	indexes := make(map[string]*secondaryIndex, len(extractors))
+	for name, extract := range extractors {
+		indexes[name] = &secondaryIndex{
+			extract: extract,
+			keys:    make(map[string]map[string]struct{}),
+		}
+	}
+	return indexes
+}
+
+func (ix *secondaryIndex) add(k string, x interface{}) {
+	values := ix.extract(x)
+	if len(values) == 0 {
+		return
+	}
+	ix.mu.Lock()
+	for _, v := range values {
+		ks, ok := ix.keys[v]
+		if !ok {
+			ks = make(map[string]struct{})
+			ix.keys[v] = ks
+		}
+		ks[k] = struct{}{}
+	}
+	ix.mu.Unlock()
+}
+
+func (ix *secondaryIndex) remove(k string, x interface{}) {
+	values := ix.extract(x)
+	if len(values) == 0 {
+		return
+	}
+	ix.mu.Lock()
+	for _, v := range values {
+		if ks, ok := ix.keys[v]; ok {
+			delete(ks, k)
+			if len(ks) == 0 {
+				delete(ix.keys, v)
+			}
+		}
+	}
+	ix.mu.Unlock()
+}
+
+func (ix *secondaryIndex) reset() {
+	ix.mu.Lock()
+	ix.keys = make(map[string]map[string]struct{})
+	ix.mu.Unlock()
+}
+
+// Update the indexes for x stored under k, replacing old if replaced is true.
+func (c *cache) reindex(k string, old interface{}, replaced bool, x interface{}) {
+	if replaced && sameValue(old, x) {
+		return
+	}
+	for _, ix := range c.indexes {
+		if replaced {
+			ix.remove(k, old)
+		}
+		ix.add(k, x)
+	}
+}
+
+func (c *cache) unindex(k string, x interface{}) {
+	for _, ix := range c.indexes {
+		ix.remove(k, x)
+	}
+}
+
+// Returns the keys and values of the unexpired items for which the extractor
+// of the index name (see Index) returned value, or nil if there is no such
+// index.
+func (c *cache) GetByIndex(name, value string) map[string]interface{} {
+	ix, found := c.indexes[name]
+	if !found {
+		return nil
+	}
+	ix.mu.Lock()
+	keys := make([]string, 0, len(ix.keys[value]))
+	for k := range ix.keys[value] {
+		keys = append(keys, k)
+	}
+	ix.mu.Unlock()
+	m := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		v, found := c.getItem(k)
+		if !found || c.expired(v) || !hasTag(ix.extract(v.Object), value) {
+			continue
+		}
+		m[k] = c.copyOut(v.Object)
+	}
+	return m
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type session struct {
+	user string
+}
+
+func byUser(v interface{}) []string {
+	if s, ok := v.(*session); ok {
+		return []string{s.user}
+	}
+	return nil
+}
+
+func TestIndex(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), Index("by_user", byUser))
+	tc.Set("s1", &session{"42"}, DefaultExpiration)
+	tc.Set("s2", &session{"42"}, time.Second)
+	tc.Set("s3", &session{"7"}, DefaultExpiration)
+	tc.Set("other", 1, DefaultExpiration)
+
+	if m := tc.GetByIndex("by_user", "42"); len(m) != 2 || m["s1"] == nil || m["s2"] == nil {
+		t.Errorf("got %v; want s1 and s2", m)
+	}
+	clock.Advance(2 * time.Second)
+	if m := tc.GetByIndex("by_user", "42"); len(m) != 1 || m["s1"] == nil {
+		t.Errorf("got %v after s2 expired; want s1", m)
+	}
+
+	tc.Set("s1", &session{"7"}, DefaultExpiration)
+	if m := tc.GetByIndex("by_user", "42"); len(m) != 0 {
+		t.Errorf("got %v after s1 moved to another user; want nothing", m)
+	}
+	tc.Delete("s3")
+	if m := tc.GetByIndex("by_user", "7"); len(m) != 1 || m["s1"] == nil {
+		t.Errorf("got %v; want s1", m)
+	}
+	tc.Flush()
+	if m := tc.GetByIndex("by_user", "7"); len(m) != 0 {
+		t.Errorf("got %v after Flush", m)
+	}
+	if m := tc.GetByIndex("missing", "7"); m != nil {
+		t.Errorf("got %v from a missing index; want nil", m)
+	}
+}
+
+func TestIndexInitialItems(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), Index("by_user", byUser),
+		InitialItems(map[string]Item{"s1": {Object: &session{"42"}}}))
+	if m := tc.GetByIndex("by_user", "42"); len(m) != 1 {
+		t.Errorf("got %v; want the initial item", m)
+	}
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Stop the janitor when ctx is done. The cache itself remains usable, but
+// expired items are no longer removed automatically.
+func Context(ctx context.Context) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Context = ctx
+		return nil
+	}
+}
+
+// Bound the work done by each run of the janitor to checking at most maxItems
+// items for expiration, or to spending at most maxTime doing so; either may be
+// 0 for no limit. A run that stops early leaves the remaining items for the
+// next one, so that a sweep of a large cache is spread over several runs
+// rather than causing one long pause. Expired items are still never returned
+// by Get while they wait to be removed.
+//
+// At the start of each sweep the janitor makes a list of the keys in the
+// cache, which still takes time proportional to its size, but much less than
+// checking the items themselves. Items added during a sweep are checked by
+// the next one. The size limits (CacheSize, MaxBytes, etc.) are enforced at
+// the end of every run as usual.
+func JanitorBudget(maxItems int, maxTime time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.JanitorMaxItems = maxItems
+		m.JanitorMaxTime = maxTime
+		return nil
+	}
+}
+
+// Let the janitor adjust its interval to the rate at which items expire,
+// between min and max, instead of always running every CleanupInterval. The
+// interval is halved after a run that finds many expired items (a quarter or
+// more of those checked, or more than a bounded run could handle; see
+// JanitorBudget), and doubled after a run that finds none. The janitor starts
+// at CleanupInterval, or min if CleanupInterval is not set, and runs even if
+// CleanupInterval is 0.
+func AdaptiveCleanup(min, max time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.CleanupMin = min
+		m.CleanupMax = max
+		return nil
+	}
+}
+
+// Suspend the janitor, e.g. during bulk loads or traffic spikes, until
+// ResumeJanitor is called. Expired items are still never returned by Get, but
+// they, and items over the cache's size limit, are not removed in the
+// meantime. Does nothing if the cache has no janitor.
+func (c *cache) PauseJanitor() {
+	if c.janitor != nil {
+		atomic.StoreUint32(&c.janitor.paused, 1)
+		c.debug("cache: janitor paused")
+	}
+}
+
+// Resume a janitor suspended with PauseJanitor.
+func (c *cache) ResumeJanitor() {
+	if c.janitor != nil {
+		atomic.StoreUint32(&c.janitor.paused, 0)
+		c.debug("cache: janitor resumed")
+	}
+}
+
+// Returns the current interval between runs, which changes over time with
+// AdaptiveCleanup.
+func (j *janitor) interval() time.Duration {
+	return time.Duration(atomic.LoadInt64((*int64)(&j.Interval)))
+}
+
+func clampInterval(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	} else if d > max {
+		return max
+	}
+	return d
+}
+
+// Adjust the interval after a run that deleted expired of the scanned items,
+// as described for AdaptiveCleanup.
+func (j *janitor) adapt(c *cache, expired, scanned int) {
+	cur := j.interval()
+	d := cur
+	if len(j.cursor) > 0 || (expired > 0 && expired*4 >= scanned) {
+		d /= 2
+	} else if expired == 0 {
+		d *= 2
+	}
+	if d = clampInterval(d, c.CleanupMin, c.CleanupMax); d == cur {
+		return
+	}
+	j.ticker.Stop()
+	j.ticker = c.newTicker(d)
+	atomic.StoreInt64((*int64)(&j.Interval), int64(d))
+	c.debug("cache: janitor interval changed", "interval", d)
+}
+
+// JanitorStats describes the state of a cache's janitor and its most recent
+// run.
+type JanitorStats struct {
+	// Whether the cache has a janitor that has not been stopped, by Close,
+	// the finalizer or the Context option.
+	Running bool
+	Paused  bool
+	// The time between runs, which changes over time with AdaptiveCleanup.
+	Interval time.Duration
+	// The number of completed runs, and when the last one finished. LastRun
+	// is zero if there has not been a run yet.
+	Runs            uint64
+	LastRun         time.Time
+	LastRunDuration time.Duration
+	// The number of items examined, deleted because they had expired, and
+	// evicted to enforce the cache's size limits by the last run.
+	Scanned int
+	Expired int
+	Evicted int
+}
+
+// Returns the state of the janitor and the results of its most recent run.
+// All fields are zero if the cache has no janitor.
+func (c *cache) JanitorStats() JanitorStats {
+	j := c.janitor
+	if j == nil {
+		return JanitorStats{}
+	}
+	s := JanitorStats{
+		Running:         atomic.LoadUint32(&j.stopped) == 0,
+		Paused:          atomic.LoadUint32(&j.paused) == 1,
+		Interval:        j.interval(),
+		Runs:            atomic.LoadUint64(&j.runs),
+		LastRunDuration: time.Duration(atomic.LoadInt64(&j.lastDuration)),
+		Scanned:         int(atomic.LoadInt64(&j.scanned)),
+		Expired:         int(atomic.LoadInt64(&j.expired)),
+		Evicted:         int(atomic.LoadInt64(&j.evicted)),
+	}
+	if t := atomic.LoadInt64(&j.lastRun); t != 0 {
+		s.LastRun = time.Unix(0, t)
+	}
+	return s
+}
+
+// The number of intervals the janitor may go without a tick before Healthy
+// considers it stuck.
+const janitorStuckIntervals = 3
+
+// Reports whether the cache's janitor is doing its job: it has not been
+// stopped (see JanitorStats.Running), and it has not gone several intervals
+// without being woken, e.g. because a run is stuck. A paused janitor is
+// healthy. Returns true if the cache was created
+// without a janitor, and false once it has been closed.
+func (c *cache) Healthy() bool {
+	if c.isClosed() {
+		return false
+	}
+	j := c.janitor
+	if j == nil {
+		return true
+	}
+	if atomic.LoadUint32(&j.stopped) == 1 {
+		return false
+	}
+	last := atomic.LoadInt64(&j.lastTick)
+	return c.now().UnixNano()-last <= janitorStuckIntervals*int64(j.interval())
+}
+
+// How many items a bounded sweep checks between looking at the clock.
+const sweepCheckEvery = 64
+
+// Continue the current sweep for expired items within the bounds set by
+// JanitorBudget, starting a new one if the last has finished. Returns the
+// number of items deleted and checked.
+func (j *janitor) sweep(c *cache) (int, int) {
+	if len(j.cursor) == 0 {
+		c.items.Range(func(key, _ interface{}) bool {
+			j.cursor = append(j.cursor, key.(string))
+			return true
+		})
+	}
+	var (
+		evictedItems []KeyValue
+		removed      int
+		scanned      int
+		start        = time.Now()
+		now          = c.now().UnixNano()
+	)
+	for len(j.cursor) > 0 {
+		if c.JanitorMaxItems > 0 && scanned >= c.JanitorMaxItems {
+			break
+		}
+		if c.JanitorMaxTime > 0 && scanned%sweepCheckEvery == 0 && scanned > 0 &&
+			time.Since(start) >= c.JanitorMaxTime {
+			break
+		}
+		k := j.cursor[len(j.cursor)-1]
+		j.cursor[len(j.cursor)-1] = ""
+		j.cursor = j.cursor[:len(j.cursor)-1]
+		scanned++
+		v, found := c.getItem(k)
+		if !found || v.Expiration <= 0 || now <= v.Expiration {
+			continue
+		}
+		ov, evicted := c.delete(k, EventExpire)
+		if evicted {
+			evictedItems = append(evictedItems, KeyValue{k, ov, EventExpire})
+		}
+		removed++
+	}
+	if len(j.cursor) == 0 {
+		// Release the memory of a large sweep.
+		j.cursor = nil
+	}
+	c.stats.evicted(removed)
+	c.leases.deleteExpired(now)
+	c.refreshes.prune(c)
+	if removed > 0 {
+		c.debug("cache: deleted expired items", "count", removed, "remaining", len(j.cursor))
+	}
+	c.evictMany(evictedItems)
+	return removed, scanned
+}
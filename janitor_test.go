@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestJanitorContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tc := New(Expiration(1*time.Millisecond), CleanupInterval(1*time.Millisecond), Context(ctx))
+	cancel()
+	<-time.After(5 * time.Millisecond)
+	tc.Set("foo", "bar", DefaultExpiration)
+	<-time.After(10 * time.Millisecond)
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("Janitor ran after the context was cancelled")
+	}
+	if err := tc.Close(); err != nil {
+		t.Error("Error closing cache with stopped janitor:", err)
+	}
+}
+
+func TestPauseJanitor(t *testing.T) {
+	tc := New(Expiration(1*time.Millisecond), CleanupInterval(1*time.Millisecond))
+	tc.PauseJanitor()
+	tc.Set("foo", "bar", DefaultExpiration)
+	<-time.After(10 * time.Millisecond)
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("Janitor ran while paused")
+	}
+	tc.ResumeJanitor()
+	<-time.After(10 * time.Millisecond)
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("Janitor did not run after being resumed")
+	}
+}
+
+// Wait until the janitor of tc has completed n runs.
+func waitJanitorRuns(t *testing.T, tc *Cache, n uint64) JanitorStats {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s := tc.JanitorStats()
+		if s.Runs >= n {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor completed %d runs; want %d", s.Runs, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJanitorStats(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(time.Minute), CleanupInterval(time.Second), WithClock(clock))
+	if s := tc.JanitorStats(); !s.Running || s.Runs != 0 || !s.LastRun.IsZero() || s.Interval != time.Second {
+		t.Errorf("unexpected stats before the first run: %+v", s)
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, NoExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	clock.Advance(2 * time.Minute)
+	s := waitJanitorRuns(t, tc, 1)
+	if s.Scanned != 3 || s.Expired != 2 || s.Evicted != 0 {
+		t.Errorf("got %d scanned, %d expired, %d evicted; want 3, 2, 0", s.Scanned, s.Expired, s.Evicted)
+	}
+	if !s.LastRun.Equal(clock.Now()) {
+		t.Errorf("LastRun is %v; want %v", s.LastRun, clock.Now())
+	}
+	if !tc.Healthy() {
+		t.Error("running janitor is not healthy")
+	}
+	tc.Close()
+	if s := tc.JanitorStats(); s.Running {
+		t.Error("janitor still running after Close")
+	}
+	if tc.Healthy() {
+		t.Error("closed cache is healthy")
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	if tc := New(Expiration(DefaultExpiration)); !tc.Healthy() {
+		t.Error("cache without a janitor is not healthy")
+	}
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), CleanupInterval(time.Second), WithClock(clock))
+	tc.PauseJanitor()
+	clock.Advance(10 * time.Second)
+	waitHealthy := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for tc.Healthy() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Healthy() is %v; want %v", !want, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitHealthy(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tc = New(Expiration(DefaultExpiration), CleanupInterval(time.Second), WithClock(clock), Context(ctx))
+	cancel()
+	waitHealthy(false)
+	if s := tc.JanitorStats(); s.Running {
+		t.Error("janitor still running after its context was cancelled")
+	}
+}
+
+func TestJanitorBudget(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(time.Minute), CleanupInterval(time.Second), WithClock(clock), JanitorBudget(4, 0))
+	for i := 0; i < 10; i++ {
+		tc.Set(fmt.Sprint(i), i, DefaultExpiration)
+	}
+	clock.Advance(2 * time.Minute)
+	s := waitJanitorRuns(t, tc, 1)
+	if s.Scanned != 4 || s.Expired != 4 {
+		t.Errorf("first run checked %d and deleted %d items; want 4 and 4", s.Scanned, s.Expired)
+	}
+	if n := tc.ItemCount(); n != 6 {
+		t.Errorf("%d items left after the first run; want 6", n)
+	}
+	clock.Advance(time.Second)
+	waitJanitorRuns(t, tc, 2)
+	clock.Advance(time.Second)
+	s = waitJanitorRuns(t, tc, 3)
+	if s.Scanned != 2 {
+		t.Errorf("last run checked %d items; want the remaining 2", s.Scanned)
+	}
+	if n := tc.ItemCount(); n != 0 {
+		t.Errorf("%d items left after the sweep", n)
+	}
+	if _, err := NewWithError(JanitorBudget(-1, 0)); err == nil {
+		t.Error("negative JanitorBudget accepted")
+	}
+}
+
+func TestAdaptiveCleanup(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(time.Minute), WithClock(clock), AdaptiveCleanup(time.Second, 8*time.Second))
+	if s := tc.JanitorStats(); !s.Running || s.Interval != time.Second {
+		t.Fatalf("janitor not started at the minimum interval: %+v", s)
+	}
+	// Idle runs back off to the maximum.
+	for i, want := range []time.Duration{2, 4, 8, 8} {
+		clock.Advance(tc.JanitorStats().Interval)
+		if s := waitJanitorRuns(t, tc, uint64(i+1)); s.Interval != want*time.Second {
+			t.Errorf("interval after idle run %d is %v; want %v", i+1, s.Interval, want*time.Second)
+		}
+	}
+	// Runs finding mostly expired items speed up again.
+	for i := 0; i < 10; i++ {
+		tc.Set(fmt.Sprint(i), i, time.Second)
+	}
+	clock.Advance(8 * time.Second)
+	if s := waitJanitorRuns(t, tc, 5); s.Interval != 4*time.Second || s.Expired != 10 {
+		t.Errorf("interval after busy run is %v with %d expired; want 4s and 10", s.Interval, s.Expired)
+	}
+	if _, err := NewWithError(AdaptiveCleanup(2*time.Second, time.Second)); err == nil {
+		t.Error("AdaptiveCleanup with min > max accepted")
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type jsonlItem struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	// Seconds until the item expires, or nil if it never expires.
+	TTL      *float64   `json:"ttl"`
+	Accessed *time.Time `json:"accessed,omitempty"`
+}
+
+// Write every unexpired item in the cache to w as a JSON object on a line of
+// its own, with the fields "key", "value", "ttl" (the number of seconds until
+// the item expires, or null if it never does) and "accessed" (the time the
+// item was last accessed, if it is tracked.) Unlike Save, the output is meant
+// for inspecting the cache with tools like jq and for feeding it to other
+// systems.
+func (c *cache) ExportJSONL(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var err error
+	now := c.now()
+	c.rangeItems(func(k string, v Item) bool {
+		if c.expired(v) {
+			return true
+		}
+		line := jsonlItem{Key: k, Value: v.Object}
+		if v.Expiration > 0 {
+			ttl := time.Unix(0, v.Expiration).Sub(now).Seconds()
+			line.TTL = &ttl
+		}
+		if v.Accessed > 0 {
+			accessed := time.Unix(0, v.Accessed)
+			line.Accessed = &accessed
+		}
+		err = enc.Encode(line)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Add the items written by ExportJSONL (or another tool producing the same
+// format) to the cache, replacing any existing items with the same keys.
+// Values are decoded into the generic types of encoding/json: numbers become
+// float64, objects become map[string]interface{}, and so on. Each item is
+// imported as if by Set: it is subject to MaxKeyLength, MaxValueBytes and
+// HardCacheSize, and is passed to the EventSink and watchers and logged to
+// the WAL. It also goes through any Middleware. Items without an "accessed"
+// field get the time they were imported, as Set would give them.
+func (c *cache) ImportJSONL(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var in jsonlItem
+		if err := dec.Decode(&in); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("Line %d: %v", line, err)
+		}
+		d := NoExpiration
+		if in.TTL != nil {
+			d = time.Duration(*in.TTL * float64(time.Second))
+			if d <= 0 {
+				continue
+			}
+		}
+		if c.isClosed() {
+			return ErrClosed
+		}
+		var extra Item
+		if in.Accessed != nil {
+			extra.Accessed = in.Accessed.UnixNano()
+		}
+		c.setItem(in.Key, in.Value, d, extra)
+	}
+}
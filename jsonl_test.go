@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportJSONL(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(10))
+	tc.Set("a", "x", time.Hour)
+	tc.Set("b", 2, NoExpiration)
+	tc.Set("expired", 3, time.Nanosecond)
+	<-time.After(time.Millisecond)
+	buf := &bytes.Buffer{}
+	if err := tc.ExportJSONL(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("Expected 2 lines, got", lines)
+	}
+	for _, l := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &m); err != nil {
+			t.Fatal(err)
+		}
+		switch m["key"] {
+		case "a":
+			if ttl, ok := m["ttl"].(float64); !ok || ttl < 3590 || ttl > 3600 || m["accessed"] == nil {
+				t.Error("Wrong line for a:", l)
+			}
+		case "b":
+			if m["ttl"] != nil || m["value"] != float64(2) {
+				t.Error("Wrong line for b:", l)
+			}
+		}
+	}
+
+	oc := New(Expiration(DefaultExpiration))
+	oc.Set("a", "old", DefaultExpiration)
+	if err := oc.ImportJSONL(buf); err != nil {
+		t.Fatal(err)
+	}
+	if x, exp, _ := oc.GetWithExpiration("a"); x != "x" || time.Until(exp) < 59*time.Minute {
+		t.Error("a was not imported with its TTL:", x, exp)
+	}
+	if _, exp, found := oc.GetWithExpiration("b"); !found || !exp.IsZero() {
+		t.Error("b was not imported without an expiration:", exp, found)
+	}
+
+	err := oc.ImportJSONL(strings.NewReader(`{"key":"c","value":1}` + "\n" + `{"key":`))
+	if err == nil || !strings.HasPrefix(err.Error(), "Line 2:") {
+		t.Error("Expected an error for line 2, got", err)
+	}
+	if _, found := oc.Get("c"); !found {
+		t.Error("Line before the error was not imported")
+	}
+}
+
+func TestImportJSONLLimits(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), HardCacheSize(2), MaxKeyLength(3))
+	ch, stop := tc.Watch("a")
+	defer stop()
+	in := `{"key":"a","value":1}
+{"key":"long","value":2}
+{"key":"b","value":3}
+{"key":"c","value":4}
+`
+	if err := tc.ImportJSONL(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("%d items imported into a cache limited to 2", n)
+	}
+	if _, found := tc.Get("long"); found {
+		t.Error("Imported a key longer than MaxKeyLength")
+	}
+	if e := receive(t, ch); e.Type != EventSet || e.Key != "a" {
+		t.Error("Got event", e)
+	}
+}
+
+func TestImportJSONLSetPath(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	var keys []string
+	record := Middleware{Set: func(next SetHandler) SetHandler {
+		return func(k string, x interface{}, d time.Duration) {
+			keys = append(keys, k)
+			next(k, x, d)
+		}
+	}}
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), WithClock(clock), Use(record))
+	in := `{"key":"a","value":1}
+{"key":"b","value":2,"accessed":"1970-01-01T00:00:10Z"}
+`
+	if err := tc.ImportJSONL(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Error("Set middleware saw", keys)
+	}
+	items := tc.Items()
+	if a := items["a"].Accessed; a != clock.Now().UnixNano() {
+		t.Error("a without an accessed time was imported with Accessed", a)
+	}
+	if b := items["b"].Accessed; b != time.Unix(10, 0).UnixNano() {
+		t.Error("b was imported with Accessed", b)
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// The separator placed between the parts of keys built with Key and
+// KeyBuilder. It is the same as the one Namespace puts after its name, so
+// Key("user", 123) can be used in the namespace "user" as Key(123).
+const KeySeparator = ':'
+
+var keyBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 64)
+		return &b
+	},
+}
+
+// Returns the parts joined with KeySeparator, for building keys like
+// "user:123:profile" without the parsing and extra allocations of
+// fmt.Sprintf. Strings, byte slices, integers and bools are formatted
+// directly into a pooled buffer, fmt.Stringers with their String method, and
+// anything else as with fmt.Sprint.
+func Key(parts ...interface{}) string {
+	bp := keyBuffers.Get().(*[]byte)
+	b := (*bp)[:0]
+	for i, p := range parts {
+		if i > 0 {
+			b = append(b, KeySeparator)
+		}
+		b = appendKeyPart(b, p)
+	}
+	k := string(b)
+	*bp = b
+	keyBuffers.Put(bp)
+	return k
+}
+
+func appendKeyPart(b []byte, p interface{}) []byte {
+	switch v := p.(type) {
+	case string:
+		return append(b, v...)
+	case []byte:
+		return append(b, v...)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case fmt.Stringer:
+		return append(b, v.String()...)
+	default:
+		return fmt.Append(b, v)
+	}
+}
+
+// A KeyBuilder builds keys part by part, like Key, but without boxing the
+// parts in interfaces. A KeyBuilder can be reused: after Reset (or Prefix),
+// building another key reuses its buffer, so only the final String allocates.
+// The zero value is an empty builder ready to use. A KeyBuilder must not be
+// copied after first use, and is not safe for concurrent use.
+type KeyBuilder struct {
+	buf    []byte
+	prefix int
+}
+
+// Returns a builder whose keys all start with the given parts, joined with
+// KeySeparator. Reset keeps the prefix.
+func NewKeyBuilder(prefix ...string) *KeyBuilder {
+	b := &KeyBuilder{}
+	for _, p := range prefix {
+		b.Str(p)
+	}
+	b.prefix = len(b.buf)
+	return b
+}
+
+func (b *KeyBuilder) sep() {
+	if len(b.buf) > 0 {
+		b.buf = append(b.buf, KeySeparator)
+	}
+}
+
+// Append a string part to the key.
+func (b *KeyBuilder) Str(s string) *KeyBuilder {
+	b.sep()
+	b.buf = append(b.buf, s...)
+	return b
+}
+
+// Append a signed integer part to the key.
+func (b *KeyBuilder) Int(n int64) *KeyBuilder {
+	b.sep()
+	b.buf = strconv.AppendInt(b.buf, n, 10)
+	return b
+}
+
+// Append an unsigned integer part to the key.
+func (b *KeyBuilder) Uint(n uint64) *KeyBuilder {
+	b.sep()
+	b.buf = strconv.AppendUint(b.buf, n, 10)
+	return b
+}
+
+// Returns the key built so far.
+func (b *KeyBuilder) String() string {
+	return string(b.buf)
+}
+
+// Returns the prefix given to NewKeyBuilder, including the trailing
+// separator if it isn't empty, for use with KeysWithPrefix and
+// DeleteByPrefix.
+func (b *KeyBuilder) Prefix() string {
+	if b.prefix == 0 {
+		return ""
+	}
+	return string(b.buf[:b.prefix]) + string(KeySeparator)
+}
+
+// Discard the parts added since NewKeyBuilder, keeping the prefix and the
+// buffer.
+func (b *KeyBuilder) Reset() *KeyBuilder {
+	b.buf = b.buf[:b.prefix]
+	return b
+}
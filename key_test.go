@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	cases := []struct {
+		parts []interface{}
+		want  string
+	}{
+		{nil, ""},
+		{[]interface{}{"user", 123, "profile"}, "user:123:profile"},
+		{[]interface{}{[]byte("b"), int64(-1), uint64(2), uint8(3), true}, "b:-1:2:3:true"},
+		{[]interface{}{time.Second, 1.5}, "1s:1.5"},
+	}
+	for _, c := range cases {
+		if got := Key(c.parts...); got != c.want {
+			t.Errorf("Key(%v) = %q; want %q", c.parts, got, c.want)
+		}
+	}
+}
+
+func TestKeyBuilder(t *testing.T) {
+	b := NewKeyBuilder("user")
+	if k := b.Uint(123).Str("profile").String(); k != "user:123:profile" {
+		t.Errorf("got %q; want user:123:profile", k)
+	}
+	if k := b.Reset().Int(-4).String(); k != "user:-4" {
+		t.Errorf("got %q after Reset; want user:-4", k)
+	}
+	if p := b.Prefix(); p != "user:" {
+		t.Errorf("Prefix() = %q; want user:", p)
+	}
+	var zero KeyBuilder
+	if k := zero.Str("a").Int(1).String(); k != "a:1" || zero.Prefix() != "" {
+		t.Errorf("zero builder built %q with prefix %q", k, zero.Prefix())
+	}
+
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set(b.Reset().Uint(1).String(), 1, DefaultExpiration)
+	tc.Set(b.Reset().Uint(2).String(), 2, DefaultExpiration)
+	tc.Set("other", 3, DefaultExpiration)
+	if n := tc.DeleteByPrefix(b.Prefix()); n != 2 {
+		t.Errorf("deleted %d items by prefix; want 2", n)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset().Uint(12345).Str("profile")
+	})
+	if allocs != 0 {
+		t.Errorf("building a key allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// A Keyed cache maps keys of any comparable type, such as uint64 IDs or
+// structs, to values of type V, without converting the keys to strings or
+// boxing the values. It is a lighter alternative to Cache for workloads that
+// don't need its other features: it supports expiration and a size limit,
+// enforced on every Set by evicting the least recently used item, but no
+// janitor, callbacks, persistence or tags. Expired items are removed when
+// they are found by Get, evicted, or removed by DeleteExpired. A Keyed cache is
+// safe for concurrent use.
+type Keyed[K comparable, V any] struct {
+	mu         sync.Mutex
+	items      map[K]*list.Element
+	lru        *list.List // of *keyedItem[K, V], most recently used first
+	size       int
+	expiration time.Duration
+	clock      Clock
+}
+
+type keyedItem[K comparable, V any] struct {
+	key        K
+	value      V
+	expiration int64
+}
+
+// Returns a new Keyed cache. Of the options, only Expiration, CacheSize and
+// WithClock are used; the others are ignored.
+func NewKeyed[K comparable, V any](options ...CacheOption) (*Keyed[K, V], error) {
+	opts := GetDefaultOptions()
+	for _, opt := range options {
+		if err := opt(opts); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Keyed[K, V]{
+		items:      make(map[K]*list.Element),
+		lru:        list.New(),
+		size:       opts.CacheSize,
+		expiration: opts.Expiration,
+		clock:      opts.Clock,
+	}, nil
+}
+
+func (c *Keyed[K, V]) now() int64 {
+	if c.clock != nil {
+		return c.clock.Now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+// Add an item to the cache, replacing any existing item. As with Cache.Set,
+// d may be DefaultExpiration or NoExpiration. If the cache is full, the least
+// recently used item is evicted.
+func (c *Keyed[K, V]) Set(k K, x V, d time.Duration) {
+	if d == DefaultExpiration {
+		d = c.expiration
+	}
+	var e int64
+	if d > 0 {
+		e = c.now() + int64(d)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.items[k]; found {
+		it := el.Value.(*keyedItem[K, V])
+		it.value, it.expiration = x, e
+		c.lru.MoveToFront(el)
+		return
+	}
+	if c.size > 0 && len(c.items) >= c.size {
+		c.removeElement(c.lru.Back())
+	}
+	c.items[k] = c.lru.PushFront(&keyedItem[K, V]{key: k, value: x, expiration: e})
+}
+
+// Get an item from the cache. Returns the item or the zero value of V, and a
+// bool indicating whether the key was found.
+func (c *Keyed[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, found := c.items[k]
+	if !found {
+		var zero V
+		return zero, false
+	}
+	it := el.Value.(*keyedItem[K, V])
+	if it.expiration > 0 && c.now() > it.expiration {
+		c.removeElement(el)
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(el)
+	return it.value, true
+}
+
+// Delete an item from the cache. Does nothing if the key is not in the cache.
+func (c *Keyed[K, V]) Delete(k K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.items[k]; found {
+		c.removeElement(el)
+	}
+}
+
+// Must be called with c.mu held.
+func (c *Keyed[K, V]) removeElement(el *list.Element) {
+	it := c.lru.Remove(el).(*keyedItem[K, V])
+	delete(c.items, it.key)
+}
+
+// Delete all expired items from the cache. Returns the number of items
+// deleted.
+func (c *Keyed[K, V]) DeleteExpired() int {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if it := el.Value.(*keyedItem[K, V]); it.expiration > 0 && now > it.expiration {
+			c.removeElement(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
+
+// Returns the number of items in the cache. This may include items that have
+// expired, but have not yet been cleaned up.
+func (c *Keyed[K, V]) ItemCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Delete all items from the cache.
+func (c *Keyed[K, V]) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]*list.Element)
+	c.lru.Init()
+}
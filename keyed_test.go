@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyed(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc, err := NewKeyed[uint64, string](Expiration(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.Set(1, "one", DefaultExpiration)
+	tc.Set(2, "two", NoExpiration)
+	if x, found := tc.Get(1); !found || x != "one" {
+		t.Errorf("got %q, %v; want one", x, found)
+	}
+	if _, found := tc.Get(3); found {
+		t.Error("missing key was found")
+	}
+	clock.Advance(2 * time.Minute)
+	if _, found := tc.Get(1); found {
+		t.Error("expired item was found")
+	}
+	if x, found := tc.Get(2); !found || x != "two" {
+		t.Errorf("got %q, %v; want two", x, found)
+	}
+	tc.Set(3, "three", DefaultExpiration)
+	clock.Advance(2 * time.Minute)
+	if n := tc.DeleteExpired(); n != 1 || tc.ItemCount() != 1 {
+		t.Errorf("DeleteExpired deleted %d items, leaving %d; want 1 and 1", n, tc.ItemCount())
+	}
+	tc.Delete(2)
+	if tc.ItemCount() != 0 {
+		t.Error("item was not deleted")
+	}
+}
+
+func TestKeyedCacheSize(t *testing.T) {
+	type key struct{ a, b int }
+	tc, _ := NewKeyed[key, int](CacheSize(2))
+	tc.Set(key{1, 1}, 1, DefaultExpiration)
+	tc.Set(key{2, 2}, 2, DefaultExpiration)
+	tc.Get(key{1, 1})
+	tc.Set(key{3, 3}, 3, DefaultExpiration)
+	if _, found := tc.Get(key{2, 2}); found {
+		t.Error("least recently used item was not evicted")
+	}
+	for _, k := range []key{{1, 1}, {3, 3}} {
+		if _, found := tc.Get(k); !found {
+			t.Errorf("%v was evicted", k)
+		}
+	}
+	tc.Flush()
+	if tc.ItemCount() != 0 {
+		t.Error("items left after Flush")
+	}
+}
+
+func TestKeyedGetDoesNotAllocate(t *testing.T) {
+	tc, _ := NewKeyed[uint64, int](CacheSize(10))
+	tc.Set(42, 1, DefaultExpiration)
+	if allocs := testing.AllocsPerRun(100, func() { tc.Get(42) }); allocs != 0 {
+		t.Errorf("Get allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/heap"
+	"path"
+	"strings"
+)
+
+// Returns the keys of all unexpired items in the cache, in no particular
+// order.
+func (c *cache) Keys() []string {
+	return c.keys(func(string) bool { return true })
+}
+
+// Returns the keys of all unexpired items in the cache that start with
+// prefix, in no particular order.
+func (c *cache) KeysWithPrefix(prefix string) []string {
+	return c.keys(func(k string) bool { return strings.HasPrefix(k, prefix) })
+}
+
+// Returns the keys of all unexpired items in the cache that match the shell
+// pattern, in no particular order. See path.Match for the pattern syntax; note
+// that '*' does not match '/'. Returns an error if the pattern is malformed.
+func (c *cache) KeysMatching(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return c.keys(func(k string) bool {
+		ok, _ := path.Match(pattern, k)
+		return ok
+	}), nil
+}
+
+func (c *cache) keys(match func(k string) bool) []string {
+	var keys []string
+	now := c.now().UnixNano()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		k := key.(string)
+		// "Inlining" of !Expired
+		if (v.Expiration == 0 || now <= v.Expiration) && match(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	return keys
+}
+
+// A max-heap of keys, holding the smallest keys seen so far.
+type keyHeap []string
+
+func (h keyHeap) Len() int            { return len(h) }
+func (h keyHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h keyHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x interface{}) { *h = append(*h, x.(string)) }
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Returns up to limit unexpired items, in key order, starting at cursor, and
+// the cursor of the next page, which is empty once there are no more items.
+// Pass an empty cursor to get the first page. Only limit keys are held in
+// memory at a time, so even very large caches can be paged through, but
+// every call visits every item. Items set or deleted while paging may or may
+// not be included.
+func (c *cache) ItemsPage(cursor string, limit int) (map[string]Item, string) {
+	if limit <= 0 {
+		return map[string]Item{}, ""
+	}
+	h := make(keyHeap, 0, limit)
+	now := c.now().UnixNano()
+	c.items.Range(func(key, value interface{}) bool {
+		k := key.(string)
+		v := itemOf(value)
+		if k < cursor || (v.Expiration > 0 && now > v.Expiration) {
+			return true
+		}
+		if len(h) < limit {
+			heap.Push(&h, k)
+		} else if k < h[0] {
+			h[0] = k
+			heap.Fix(&h, 0)
+		}
+		return true
+	})
+
+	page := make(map[string]Item, len(h))
+	var last string
+	for _, k := range h {
+		if v, found := c.items.Load(k); found {
+			page[k] = itemOf(v)
+		}
+		if k > last {
+			last = k
+		}
+	}
+	if len(h) < limit {
+		return page, ""
+	}
+	// The smallest key after last.
+	return page, last + "\x00"
+}
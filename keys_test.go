@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestKeys(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("user:1", 1, DefaultExpiration)
+	tc.Set("user:2", 2, DefaultExpiration)
+	tc.Set("user:3", 3, 1*time.Millisecond)
+	tc.Set("session:1", 4, DefaultExpiration)
+	<-time.After(5 * time.Millisecond)
+
+	keys := tc.Keys()
+	sort.Strings(keys)
+	if len(keys) != 3 || keys[0] != "session:1" || keys[1] != "user:1" || keys[2] != "user:2" {
+		t.Error("Keys returned the wrong keys:", keys)
+	}
+
+	keys = tc.KeysWithPrefix("user:")
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Error("KeysWithPrefix returned the wrong keys:", keys)
+	}
+
+	keys, err := tc.KeysMatching("*:1")
+	if err != nil {
+		t.Fatal("Error matching keys:", err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "session:1" || keys[1] != "user:1" {
+		t.Error("KeysMatching returned the wrong keys:", keys)
+	}
+
+	if _, err := tc.KeysMatching("["); err == nil {
+		t.Error("Malformed pattern was accepted")
+	}
+}
+
+func TestItemsPage(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	for i := 0; i < 25; i++ {
+		tc.Set(fmt.Sprintf("k%02d", i), i, DefaultExpiration)
+	}
+	tc.Set("", "empty", DefaultExpiration)
+	tc.Set("expired", 0, time.Nanosecond)
+	<-time.After(time.Millisecond)
+
+	var keys []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 4 {
+			t.Fatal("Too many pages")
+		}
+		page, next := tc.ItemsPage(cursor, 10)
+		var pageKeys []string
+		for k := range page {
+			pageKeys = append(pageKeys, k)
+		}
+		sort.Strings(pageKeys)
+		keys = append(keys, pageKeys...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(keys) != 26 || keys[0] != "" || keys[1] != "k00" || keys[25] != "k24" {
+		t.Error("Wrong keys from paging:", keys)
+	}
+	if page, next := tc.ItemsPage("", 0); len(page) != 0 || next != "" {
+		t.Error("Expected an empty page for a limit of 0")
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type lease struct {
+	token      uint64
+	expiration int64
+}
+
+// Leases held with Lock, by key. The zero value is ready to use.
+type leaseTable struct {
+	mu     sync.Mutex
+	leases map[string]lease
+	next   uint64
+}
+
+// Acquire a lease on k, e.g. to make sure that only one goroutine recomputes
+// the value of k when it is missing, like the leases of memcached. The lease
+// is independent of the item with key k, which need not exist.
+//
+// If nobody holds the lease, ok is true, and unlock must be called to release
+// it once the value has been recomputed. If the holder does not release the
+// lease within ttl (e.g. because it crashed), the lease expires and can be
+// acquired by somebody else; calling the first holder's unlock after that has
+// no effect. If ttl is not positive, the lease never expires.
+//
+// If the lease is held, ok is false and unlock is nil. Lock never blocks;
+// callers that fail to acquire the lease typically wait a little and then
+// look for the recomputed value in the cache.
+func (c *cache) Lock(k string, ttl time.Duration) (unlock func(), ok bool) {
+	if c.isClosed() {
+		return nil, false
+	}
+	now := c.now()
+	t := &c.leases
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, held := t.leases[k]; held && (l.expiration == 0 || now.UnixNano() <= l.expiration) {
+		return nil, false
+	}
+	if t.leases == nil {
+		t.leases = make(map[string]lease)
+	}
+	t.next++
+	l := lease{token: t.next}
+	if ttl > 0 {
+		l.expiration = now.Add(ttl).UnixNano()
+	}
+	t.leases[k] = l
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			if cur, held := t.leases[k]; held && cur.token == l.token {
+				delete(t.leases, k)
+			}
+			t.mu.Unlock()
+		})
+	}, true
+}
+
+// Forget leases that have expired without being released.
+func (t *leaseTable) deleteExpired(now int64) {
+	t.mu.Lock()
+	for k, l := range t.leases {
+		if l.expiration > 0 && now > l.expiration {
+			delete(t.leases, k)
+		}
+	}
+	t.mu.Unlock()
+}
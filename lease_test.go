@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	unlock, ok := tc.Lock("k", time.Minute)
+	if !ok {
+		t.Fatal("first Lock failed")
+	}
+	if _, ok := tc.Lock("k", time.Minute); ok {
+		t.Error("second Lock succeeded while the lease was held")
+	}
+	if _, ok := tc.Lock("other", time.Minute); !ok {
+		t.Error("Lock of another key failed")
+	}
+	unlock()
+	unlock2, ok := tc.Lock("k", time.Minute)
+	if !ok {
+		t.Fatal("Lock after unlock failed")
+	}
+	// Calling unlock again must not release the new holder's lease.
+	unlock()
+	if _, ok := tc.Lock("k", time.Minute); ok {
+		t.Error("a stale unlock released the lease")
+	}
+	unlock2()
+}
+
+func TestLockExpires(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	unlock, _ := tc.Lock("k", time.Second)
+	clock.Advance(2 * time.Second)
+	unlock2, ok := tc.Lock("k", time.Second)
+	if !ok {
+		t.Fatal("Lock of an expired lease failed")
+	}
+	unlock()
+	if _, ok := tc.Lock("k", time.Second); ok {
+		t.Error("the expired holder's unlock released the new lease")
+	}
+	unlock2()
+
+	tc.Lock("k", time.Second)
+	clock.Advance(2 * time.Second)
+	tc.DeleteExpired()
+	if n := len(tc.leases.leases); n != 0 {
+		t.Error(n, "expired leases remain after DeleteExpired")
+	}
+}
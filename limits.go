@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooLarge is returned by Add, Replace and SetIfVersion if the key is
+// longer than MaxKeyLength or the value larger than MaxValueBytes.
+var ErrTooLarge = errors.New("cache: key or value too large")
+
+// Reject keys longer than n bytes, or allow keys of any length if n is 0.
+// Set and the other methods without an error result silently ignore items
+// with longer keys, leaving any existing item unchanged; Add, Replace and
+// SetIfVersion return ErrTooLarge.
+func MaxKeyLength(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MaxKeyLength = n
+		return nil
+	}
+}
+
+// Reject values larger than n bytes, as estimated for MaxBytes (see Sized),
+// or allow values of any size if n is 0. Oversized values are rejected like
+// keys longer than MaxKeyLength. This protects a shared cache from a single
+// producer storing runaway values; the MaxValueSize middleware does the same
+// for Set alone.
+func MaxValueBytes(n int64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MaxValueBytes = n
+		return nil
+	}
+}
+
+// Returns ErrTooLarge if k or x exceeds MaxKeyLength or MaxValueBytes.
+func (c *cache) checkSize(k string, x interface{}) error {
+	if c.MaxKeyLength > 0 && len(k) > c.MaxKeyLength {
+		return fmt.Errorf("%w: key of %d bytes, limit %d", ErrTooLarge, len(k), c.MaxKeyLength)
+	}
+	if c.MaxValueBytes > 0 {
+		if n := estimateSize("", x) - itemOverhead; n > c.MaxValueBytes {
+			return fmt.Errorf("%w: value of %d bytes, limit %d", ErrTooLarge, n, c.MaxValueBytes)
+		}
+	}
+	return nil
+}
+
+// Reports whether any of items exceeds MaxKeyLength or MaxValueBytes.
+func (c *cache) anyTooLarge(items map[string]interface{}) bool {
+	if c.MaxKeyLength <= 0 && c.MaxValueBytes <= 0 {
+		return false
+	}
+	for k, v := range items {
+		if c.checkSize(k, v) != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaxKeyLength(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxKeyLength(4))
+	tc.Set("abcd", 1, DefaultExpiration)
+	tc.Set("abcde", 2, DefaultExpiration)
+	tc.SetMulti(map[string]interface{}{"efgh": 3, "efghi": 4}, DefaultExpiration)
+	if n := tc.ItemCount(); n != 2 {
+		t.Errorf("cache has %d items; want 2", n)
+	}
+	if _, found := tc.Get("abcde"); found {
+		t.Error("item with a long key was stored")
+	}
+	if err := tc.Add("abcdef", 1, DefaultExpiration); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Add returned %v; want ErrTooLarge", err)
+	}
+}
+
+func TestMaxValueBytes(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxValueBytes(8))
+	tc.Set("a", "small", DefaultExpiration)
+	tc.Set("a", strings.Repeat("x", 9), DefaultExpiration)
+	if x, _ := tc.Get("a"); x != "small" {
+		t.Errorf("got %v; oversized value replaced the existing item", x)
+	}
+	tc.SetWithTags("b", []byte("too large!"), DefaultExpiration, "t")
+	if _, found := tc.Get("b"); found {
+		t.Error("SetWithTags stored an oversized value")
+	}
+	if err := tc.Replace("a", strings.Repeat("x", 9), DefaultExpiration); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Replace returned %v; want ErrTooLarge", err)
+	}
+	if err := tc.SetIfVersion("c", strings.Repeat("x", 9), 0, DefaultExpiration); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("SetIfVersion returned %v; want ErrTooLarge", err)
+	}
+	if _, err := NewWithError(MaxValueBytes(-1)); err == nil {
+		t.Error("negative MaxValueBytes was accepted")
+	}
+}
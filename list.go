@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limit the lists built with LPush and RPush to n elements, or leave them
+// unbounded if n is 0: pushing onto a full list drops an element from its
+// other end, so that e.g. RPush keeps the n most recent events.
+func MaxListLength(n int) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MaxListLength = n
+		return nil
+	}
+}
+
+// Prepend x to the list stored under k, a []interface{}, like Redis' LPUSH.
+// If k is not in the cache (or has expired), it is set to a new list with the
+// expiration d; otherwise its expiration is unchanged. Returns the length of
+// the list, or an error if the value of k is not a list.
+//
+// Like hashes (see HSet), lists are never modified in place, which suits
+// short lists such as capped buffers of recent events.
+func (c *cache) LPush(k string, x interface{}, d time.Duration) (int, error) {
+	return c.push(k, x, d, true)
+}
+
+// Append x to the list stored under k, like Redis' RPUSH. See LPush.
+func (c *cache) RPush(k string, x interface{}, d time.Duration) (int, error) {
+	return c.push(k, x, d, false)
+}
+
+func (c *cache) push(k string, x interface{}, d time.Duration, front bool) (int, error) {
+	n := 0
+	err := c.modify(k, d, func(old interface{}, found bool) (interface{}, bool, error) {
+		l, err := listOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		if max := c.MaxListLength; max > 0 && len(l) >= max {
+			// Drop elements from the other end.
+			if front {
+				l = l[:max-1]
+			} else {
+				l = l[len(l)-max+1:]
+			}
+		}
+		nl := make([]interface{}, 0, len(l)+1)
+		if front {
+			nl = append(append(nl, x), l...)
+		} else {
+			nl = append(append(nl, l...), x)
+		}
+		n = len(nl)
+		return nl, false, nil
+	})
+	return n, err
+}
+
+// Remove and return the first element of the list stored under k, like
+// Redis' LPOP, deleting k if the list becomes empty. found is false if the
+// list is empty or missing, or the value of k is not a list.
+func (c *cache) LPop(k string) (x interface{}, found bool) {
+	return c.pop(k, true)
+}
+
+// Remove and return the last element of the list stored under k. See LPop.
+func (c *cache) RPop(k string) (x interface{}, found bool) {
+	return c.pop(k, false)
+}
+
+func (c *cache) pop(k string, front bool) (x interface{}, found bool) {
+	c.modify(k, DefaultExpiration, func(old interface{}, ok bool) (interface{}, bool, error) {
+		l, isList := old.([]interface{})
+		if !ok || !isList || len(l) == 0 {
+			return nil, false, errUnchanged
+		}
+		if front {
+			x, l = l[0], l[1:]
+		} else {
+			x, l = l[len(l)-1], l[:len(l)-1]
+		}
+		found = true
+		// The remaining elements are shared with the old list, which is
+		// never modified.
+		return l, len(l) == 0, nil
+	})
+	return x, found
+}
+
+// Trim the list stored under k to the elements from start to stop,
+// inclusive, like Redis' LTRIM: negative indexes count from the end of the
+// list, -1 being the last element, and out of range indexes are clamped. If
+// no elements are left, k is deleted. Returns an error if the value of k is
+// not a list.
+func (c *cache) LTrim(k string, start, stop int) error {
+	return c.modify(k, DefaultExpiration, func(old interface{}, found bool) (interface{}, bool, error) {
+		if !found {
+			return nil, false, errUnchanged
+		}
+		l, err := listOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		start, stop := listRange(len(l), start, stop)
+		if start == 0 && stop == len(l) {
+			return nil, false, errUnchanged
+		}
+		nl := l[start:stop]
+		return nl, len(nl) == 0, nil
+	})
+}
+
+// Returns a copy of the elements of the list stored under k from start to
+// stop, inclusive, with the same indexes as LTrim, or nil if k is not in the
+// cache or is not a list. LRange(k, 0, -1) returns the whole list.
+func (c *cache) LRange(k string, start, stop int) []interface{} {
+	item, found := c.lookup(k)
+	if !found {
+		return nil
+	}
+	l, ok := item.Object.([]interface{})
+	if !ok {
+		return nil
+	}
+	start, stop = listRange(len(l), start, stop)
+	return append([]interface{}(nil), l[start:stop]...)
+}
+
+// Converts the inclusive Redis-style indexes start and stop into a slice of a
+// list of length n.
+func listRange(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	stop++
+	if stop > n {
+		stop = n
+	}
+	if stop < 0 {
+		stop = 0
+	}
+	if start > stop {
+		start = stop
+	}
+	return start, stop
+}
+
+// Returns x, the value of k, as a list, or an empty list if k was not found.
+func listOf(k string, x interface{}, found bool) ([]interface{}, error) {
+	if !found {
+		return nil, nil
+	}
+	l, ok := x.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("The value for %s is not a []interface{}", k)
+	}
+	return l, nil
+}
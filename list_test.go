@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.RPush("l", 2, DefaultExpiration)
+	tc.RPush("l", 3, DefaultExpiration)
+	if n, err := tc.LPush("l", 1, DefaultExpiration); n != 3 || err != nil {
+		t.Errorf("LPush returned %d, %v; want 3", n, err)
+	}
+	if l := tc.LRange("l", 0, -1); !reflect.DeepEqual(l, []interface{}{1, 2, 3}) {
+		t.Errorf("got %v; want [1 2 3]", l)
+	}
+	if x, found := tc.LPop("l"); !found || x != 1 {
+		t.Errorf("LPop returned %v, %v; want 1", x, found)
+	}
+	if x, found := tc.RPop("l"); !found || x != 3 {
+		t.Errorf("RPop returned %v, %v; want 3", x, found)
+	}
+	tc.RPop("l")
+	if _, found := tc.Get("l"); found {
+		t.Error("empty list was not deleted")
+	}
+	if _, found := tc.LPop("l"); found {
+		t.Error("LPop of a missing list found an element")
+	}
+
+	tc.Set("s", "string", DefaultExpiration)
+	if _, err := tc.RPush("s", 1, DefaultExpiration); err == nil {
+		t.Error("RPush on a string succeeded")
+	}
+}
+
+func TestLTrim(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	for i := 0; i < 5; i++ {
+		tc.RPush("l", i, DefaultExpiration)
+	}
+	tc.LTrim("l", 1, -2)
+	if l := tc.LRange("l", 0, -1); !reflect.DeepEqual(l, []interface{}{1, 2, 3}) {
+		t.Errorf("got %v; want [1 2 3]", l)
+	}
+	if l := tc.LRange("l", -100, 100); len(l) != 3 {
+		t.Errorf("got %v for out of range indexes; want the whole list", l)
+	}
+	if l := tc.LRange("l", 0, -100); len(l) != 0 {
+		t.Errorf("got %v; want nothing", l)
+	}
+	tc.LTrim("l", 5, 10)
+	if _, found := tc.Get("l"); found {
+		t.Error("list trimmed to nothing was not deleted")
+	}
+}
+
+func TestMaxListLength(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxListLength(3))
+	for i := 0; i < 5; i++ {
+		tc.RPush("recent", i, DefaultExpiration)
+	}
+	if l := tc.LRange("recent", 0, -1); !reflect.DeepEqual(l, []interface{}{2, 3, 4}) {
+		t.Errorf("got %v; want the 3 most recent elements", l)
+	}
+	tc.LPush("recent", 9, DefaultExpiration)
+	if l := tc.LRange("recent", 0, -1); !reflect.DeepEqual(l, []interface{}{9, 2, 3}) {
+		t.Errorf("got %v; want [9 2 3]", l)
+	}
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"log/slog"
+)
+
+// Log janitor runs, expirations and evictions, persistence and configuration
+// changes to l, at debug level. Failures that cannot be reported otherwise,
+// such as those of background snapshots, are logged as warnings.
+func Logger(l *slog.Logger) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Logger = l
+		return nil
+	}
+}
+
+func (c *cache) debug(msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Debug(msg, args...)
+	}
+}
+
+func (c *cache) warn(msg string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Warn(msg, args...)
+	}
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(1), Logger(l))
+	tc.Set("a", 1, time.Second)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	clock.Advance(2 * time.Second)
+
+	tc.DeleteExpired()
+	tc.DeleteLRU()
+	tc.Configure(AccessedResolution(time.Second))
+	out := buf.String()
+	for _, want := range []string{
+		`msg="cache: deleted expired items" count=1`,
+		`msg="cache: evicted items" count=1`,
+		`msg="cache: configuration changed"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestLoggerQuietAboveDebug(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	tc := New(Expiration(DefaultExpiration), CacheSize(1), Logger(l))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	tc.DeleteLRU()
+	if buf.Len() != 0 {
+		t.Error("logged at the default level:", buf.String())
+	}
+}
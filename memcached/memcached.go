@@ -0,0 +1,282 @@
+// Package memcached serves a cache.Cache over the memcached text protocol,
+// so that other processes and existing memcached clients can share an
+// in-process cache.
+//
+// The get, gets, set, add, replace, delete, incr, decr, touch, version and
+// quit commands are supported. Items stored through the server are kept in
+// the cache as Values; Go code sharing the cache should do the same.
+//
+// An exptime of 0 means the item never expires, as in memcached, rather than
+// the cache's default expiration. Exptimes of up to 30 days are relative to
+// now, and larger ones are Unix timestamps, read against the cache's Clock.
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// Value is an item stored through the memcached protocol.
+type Value struct {
+	Flags uint32
+	Data  []byte
+}
+
+// Maximum size of a value accepted by set, add and replace.
+const maxValueSize = 1 << 20
+
+// Exptimes greater than this are absolute Unix timestamps.
+const relativeExptimeLimit = 60 * 60 * 24 * 30
+
+var errClientError = errors.New("CLIENT_ERROR bad command line format")
+
+// Server serves a cache over the memcached text protocol.
+type Server struct {
+	c *cache.Cache
+
+	// Serializes read-modify-write commands (add, replace, incr, decr and
+	// touch) with each other and with set and delete.
+	mu sync.Mutex
+}
+
+// Returns a server exposing c.
+func New(c *cache.Cache) *Server {
+	return &Server{c: c}
+}
+
+// Listen on the TCP network address addr and serve connections.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Accept connections on l and serve each of them in its own goroutine. Serve
+// returns when l is closed.
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn)
+		}()
+	}
+}
+
+// Serve commands read from rw until it is closed or the client quits.
+func (s *Server) ServeConn(rw io.ReadWriter) {
+	r := bufio.NewReader(rw)
+	w := bufio.NewWriter(rw)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			w.WriteString("ERROR\r\n")
+			w.Flush()
+			continue
+		}
+		quit, err := s.command(fields, r, w)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return
+			}
+			w.WriteString(err.Error() + "\r\n")
+		}
+		if err := w.Flush(); err != nil || quit {
+			return
+		}
+	}
+}
+
+func (s *Server) command(fields []string, r *bufio.Reader, w *bufio.Writer) (bool, error) {
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case "get", "gets":
+		if len(args) == 0 {
+			return false, errors.New("ERROR")
+		}
+		for _, k := range args {
+			x, found := s.c.Get(k)
+			v, ok := x.(Value)
+			if !found || !ok {
+				continue
+			}
+			w.WriteString("VALUE " + k + " " + strconv.FormatUint(uint64(v.Flags), 10) + " " + strconv.Itoa(len(v.Data)))
+			if cmd == "gets" {
+				w.WriteString(" 0")
+			}
+			w.WriteString("\r\n")
+			w.Write(v.Data)
+			w.WriteString("\r\n")
+		}
+		w.WriteString("END\r\n")
+	case "set", "add", "replace":
+		return false, s.store(cmd, args, r, w)
+	case "delete":
+		if len(args) < 1 {
+			return false, errClientError
+		}
+		s.mu.Lock()
+		_, found := s.c.Get(args[0])
+		if found {
+			s.c.Delete(args[0])
+		}
+		s.mu.Unlock()
+		reply(w, args[1:], found, "DELETED", "NOT_FOUND")
+	case "incr", "decr":
+		if len(args) < 2 {
+			return false, errClientError
+		}
+		delta, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return false, errors.New("CLIENT_ERROR invalid numeric delta argument")
+		}
+		n, found, err := s.incr(args[0], delta, cmd == "decr")
+		if err != nil {
+			return false, err
+		}
+		reply(w, args[2:], found, strconv.FormatUint(n, 10), "NOT_FOUND")
+	case "touch":
+		if len(args) < 2 {
+			return false, errClientError
+		}
+		exptime, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return false, errClientError
+		}
+		s.mu.Lock()
+		x, found := s.c.Get(args[0])
+		if found {
+			s.set(args[0], x, exptime)
+		}
+		s.mu.Unlock()
+		reply(w, args[2:], found, "TOUCHED", "NOT_FOUND")
+	case "version":
+		w.WriteString("VERSION go-cache\r\n")
+	case "quit":
+		return true, nil
+	default:
+		return false, errors.New("ERROR")
+	}
+	return false, nil
+}
+
+func (s *Server) store(cmd string, args []string, r *bufio.Reader, w *bufio.Writer) error {
+	if len(args) < 4 {
+		return errClientError
+	}
+	flags, err1 := strconv.ParseUint(args[1], 10, 32)
+	exptime, err2 := strconv.ParseInt(args[2], 10, 64)
+	size, err3 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		return errClientError
+	}
+	if size > maxValueSize {
+		// Discard the data block so that the connection stays in sync.
+		if _, err := r.Discard(size + 2); err != nil {
+			return err
+		}
+		return errors.New("SERVER_ERROR object too large for cache")
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if string(data[size:]) != "\r\n" {
+		return errors.New("CLIENT_ERROR bad data chunk")
+	}
+	v := Value{Flags: uint32(flags), Data: data[:size]}
+
+	s.mu.Lock()
+	_, found := s.c.Get(args[0])
+	stored := cmd == "set" || (cmd == "add" && !found) || (cmd == "replace" && found)
+	if stored {
+		s.set(args[0], v, exptime)
+	}
+	s.mu.Unlock()
+	reply(w, args[4:], stored, "STORED", "NOT_STORED")
+	return nil
+}
+
+func (s *Server) incr(k string, delta uint64, decr bool) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	x, exp, found := s.c.GetWithExpiration(k)
+	v, ok := x.(Value)
+	if !found || !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseUint(string(v.Data), 10, 64)
+	if err != nil {
+		return 0, false, errors.New("CLIENT_ERROR cannot increment or decrement non-numeric value")
+	}
+	switch {
+	case !decr:
+		n += delta
+	case delta > n:
+		n = 0
+	default:
+		n -= delta
+	}
+	d := cache.NoExpiration
+	if !exp.IsZero() {
+		d = exp.Sub(s.c.Now())
+		if d <= 0 {
+			s.c.Delete(k)
+			return 0, false, nil
+		}
+	}
+	s.c.Set(k, Value{Flags: v.Flags, Data: []byte(strconv.FormatUint(n, 10))}, d)
+	return n, true, nil
+}
+
+// Store x under k with a memcached exptime. Must be called with s.mu held.
+func (s *Server) set(k string, x interface{}, exptime int64) {
+	var d time.Duration
+	switch {
+	case exptime == 0:
+		d = cache.NoExpiration
+	case exptime < 0:
+		s.c.Delete(k)
+		return
+	case exptime > relativeExptimeLimit:
+		d = time.Unix(exptime, 0).Sub(s.c.Now())
+		if d <= 0 {
+			s.c.Delete(k)
+			return
+		}
+	default:
+		d = time.Duration(exptime) * time.Second
+	}
+	s.c.Set(k, x, d)
+}
+
+// Write ok or notOK, unless the command's remaining arguments ask for no
+// reply.
+func reply(w *bufio.Writer, rest []string, success bool, ok, notOK string) {
+	if len(rest) > 0 && rest[0] == "noreply" {
+		return
+	}
+	if success {
+		w.WriteString(ok + "\r\n")
+	} else {
+		w.WriteString(notOK + "\r\n")
+	}
+}
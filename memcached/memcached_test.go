@@ -0,0 +1,109 @@
+package memcached
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func session(t *testing.T, c *cache.Cache) (func(cmd string) string, func()) {
+	client, server := net.Pipe()
+	go New(c).ServeConn(server)
+	r := bufio.NewReader(client)
+	return func(cmd string) string {
+			if _, err := client.Write([]byte(cmd)); err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					t.Fatal(err)
+				}
+				out.WriteString(line)
+				if !strings.HasPrefix(cmd, "get") || line == "END\r\n" {
+					return out.String()
+				}
+			}
+		}, func() {
+			client.Close()
+		}
+}
+
+func TestServer(t *testing.T) {
+	c := cache.New()
+	do, done := session(t, c)
+	defer done()
+
+	tests := []struct {
+		cmd, want string
+	}{
+		{"set foo 5 0 3\r\nbar\r\n", "STORED\r\n"},
+		{"get foo missing\r\n", "VALUE foo 5 3\r\nbar\r\nEND\r\n"},
+		{"gets foo\r\n", "VALUE foo 5 3 0\r\nbar\r\nEND\r\n"},
+		{"add foo 0 0 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"replace nope 0 0 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"add n 0 100 2\r\n10\r\n", "STORED\r\n"},
+		{"incr n 5\r\n", "15\r\n"},
+		{"decr n 20\r\n", "0\r\n"},
+		{"incr foo 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+		{"incr nope 1\r\n", "NOT_FOUND\r\n"},
+		{"touch foo 100\r\n", "TOUCHED\r\n"},
+		{"delete foo\r\n", "DELETED\r\n"},
+		{"delete foo\r\n", "NOT_FOUND\r\n"},
+		{"set quiet 0 0 1 noreply\r\nq\r\nversion\r\n", "VERSION go-cache\r\n"},
+		{"bogus\r\n", "ERROR\r\n"},
+		{"set foo x 0 1\r\n", "CLIENT_ERROR bad command line format\r\n"},
+	}
+	for _, tt := range tests {
+		if got := do(tt.cmd); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.cmd, got, tt.want)
+		}
+	}
+
+	x, exp, found := c.GetWithExpiration("n")
+	if !found || string(x.(Value).Data) != "0" || exp.IsZero() {
+		t.Error("Counter was not stored with its expiration:", x, exp)
+	}
+	if x, found := c.Get("quiet"); !found || string(x.(Value).Data) != "q" {
+		t.Error("noreply set was not stored:", x)
+	}
+}
+
+func TestServerClock(t *testing.T) {
+	clock := cache.NewFakeClock(time.Unix(1e9, 0))
+	c := cache.New(cache.Expiration(cache.DefaultExpiration), cache.WithClock(clock))
+	do, done := session(t, c)
+	defer done()
+
+	// Absolute exptimes are read against the cache's clock, not time.Now.
+	abs := strconv.FormatInt(clock.Now().Add(time.Hour).Unix(), 10)
+	if got := do("set a 0 " + abs + " 1\r\n1\r\n"); got != "STORED\r\n" {
+		t.Fatal("set with an absolute exptime:", got)
+	}
+	if _, exp, found := c.GetWithExpiration("a"); !found || !exp.Equal(clock.Now().Add(time.Hour)) {
+		t.Error("a was stored with expiration", exp, found)
+	}
+	past := strconv.FormatInt(clock.Now().Add(-time.Hour).Unix(), 10)
+	if got := do("touch a " + past + "\r\n"); got != "TOUCHED\r\n" {
+		t.Fatal("touch with a past exptime:", got)
+	}
+	if _, found := c.Get("a"); found {
+		t.Error("a was kept after being touched with a past exptime")
+	}
+
+	if got := do("set n 0 10 1\r\n5\r\n"); got != "STORED\r\n" {
+		t.Fatal("set n:", got)
+	}
+	if got := do("incr n 1\r\n"); got != "6\r\n" {
+		t.Error("incr n:", got)
+	}
+	if _, exp, _ := c.GetWithExpiration("n"); !exp.Equal(clock.Now().Add(10 * time.Second)) {
+		t.Error("incr changed the expiration of n to", exp)
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Check the size of the Go heap (runtime.MemStats.HeapAlloc) every interval,
+// and while it is over maxHeap bytes, evict a quarter of the cache's items
+// (see EmergencyEvict) each time, to keep a traffic spike from getting the
+// process killed for running out of memory. The heap only shrinks once the
+// garbage collector has run, so the interval should leave it time to do so;
+// a few seconds is usually right. Reading the heap size briefly stops the
+// world, so the interval should not be much shorter.
+//
+// To react to an external signal instead, such as a cgroup memory
+// notification, call EmergencyEvict directly.
+func MemoryLimit(maxHeap uint64, interval time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.MemoryLimit = maxHeap
+		m.MemoryInterval = interval
+		return nil
+	}
+}
+
+// The fraction of the items evicted by each check of MemoryLimit that finds
+// the heap too large.
+const memoryEvictFraction = 0.25
+
+// Evict the given fraction (between 0 and 1) of the cache's unexpired,
+// unpinned items at once, chosen by the eviction policy, after deleting the
+// expired ones. Returns the number of items evicted. Evicted items are passed
+// to the eviction callback as usual. Items are only evicted in LRU order if the
+// cache tracks access times, i.e. if it has a size limit or a MemoryLimit.
+func (c *cache) EmergencyEvict(fraction float64) int {
+	if fraction <= 0 {
+		return 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	c.DeleteExpired()
+	n := int(float64(atomic.LoadInt64(&c.count))*fraction + 0.5)
+	evicted, keys := c.deleteLRUAmount(n)
+	if len(keys) > 0 {
+		c.warn("cache: emergency eviction", "count", len(keys))
+	}
+	c.evictMany(evicted)
+	return len(keys)
+}
+
+type memWatcher struct {
+	ticker Ticker
+	stop   chan bool
+	exited chan struct{} // closed when Run returns
+	heap   func() uint64
+}
+
+func (w *memWatcher) Run(c *cache) {
+	defer close(w.exited)
+	for {
+		select {
+		case <-w.ticker.C():
+			if w.heap() > c.MemoryLimit {
+				c.EmergencyEvict(memoryEvictFraction)
+			}
+		case <-w.stop:
+			w.ticker.Stop()
+			return
+		}
+	}
+}
+
+func runMemWatcher(c *cache, interval time.Duration) {
+	w := &memWatcher{
+		ticker: c.newTicker(interval),
+		stop:   make(chan bool),
+		exited: make(chan struct{}),
+		heap:   heapAlloc,
+	}
+	c.memory = w
+	go w.Run(c)
+}
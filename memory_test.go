@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEmergencyEvict(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CacheSize(10))
+	for i := 0; i < 8; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	if n := tc.EmergencyEvict(0.5); n != 4 {
+		t.Errorf("evicted %d items; want 4", n)
+	}
+	for i := 4; i < 8; i++ {
+		if _, found := tc.Get(strconv.Itoa(i)); !found {
+			t.Errorf("recently used item %d was evicted", i)
+		}
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	var heap uint64 = 1 << 30
+	defer func(f func() uint64) { heapAlloc = f }(heapAlloc)
+	heapAlloc = func() uint64 { return atomic.LoadUint64(&heap) }
+
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), MemoryLimit(1<<20, time.Second))
+	defer tc.Close()
+	for i := 0; i < 100; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	waitCount := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for tc.ItemCount() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d items in the cache; want %d", tc.ItemCount(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	clock.Advance(time.Second)
+	waitCount(75)
+	atomic.StoreUint64(&heap, 1<<10)
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if n := tc.ItemCount(); n != 75 {
+		t.Errorf("%d items in the cache under the memory limit; want 75", n)
+	}
+	if _, err := NewWithError(MemoryLimit(1<<20, 0)); err == nil {
+		t.Error("MemoryLimit without an interval accepted")
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Decides what Merge does with items whose keys already exist, and haven't
+// expired, in the cache. It has the same values as LoadPolicy: SkipExisting,
+// Overwrite and NewestWins.
+type MergePolicy = LoadPolicy
+
+// Returns a new cache with the same options as c and a copy of its unexpired
+// items. The new cache is independent of c, but shares the values of the
+// items with it. Options tying a cache to outside resources are not copied:
+// the clone has no PersistPath (and so no WAL or periodic snapshots), no
+// EventSink, and is not published with expvar. The built-in policies and
+// admitters that keep state about the cache's keys (CLOCK and TinyLFU) are
+// copied along with their state; a custom Policy or Admitter is shared by
+// both caches, and must be safe for that if it keeps state of its own.
+func (c *Cache) Clone() *Cache {
+	opts := *c.CacheOptions
+	opts.PersistPath = ""
+	opts.PersistInterval = 0
+	opts.WAL = false
+	opts.ExpvarName = ""
+	opts.EventSink = nil
+	opts.InitialItems = nil
+	if p, ok := opts.Policy.(stateCopier); ok {
+		opts.Policy = p.copyState().(EvictionPolicy)
+	}
+	if a, ok := opts.Admission.(stateCopier); ok {
+		opts.Admission = a.copyState().(Admitter)
+	}
+	opts.Middleware = append([]Middleware(nil), opts.Middleware...)
+	opts.Quotas = append([]PrefixQuota(nil), opts.Quotas...)
+	opts.PrefixExpirations = append([]PrefixExpiration(nil), opts.PrefixExpirations...)
+	opts.EncryptionKey = append([]byte(nil), opts.EncryptionKey...)
+	if opts.Indexes != nil {
+		indexes := make(map[string]func(interface{}) []string, len(opts.Indexes))
+		for name, fn := range opts.Indexes {
+			indexes[name] = fn
+		}
+		opts.Indexes = indexes
+	}
+	// Without PersistPath and WAL, creating the cache cannot fail.
+	nc, _ := newCache(sync.Map{}, &opts)
+	nc.Merge(c, Overwrite)
+	return nc
+}
+
+// Implemented by the policies and admitters that keep state about a cache's
+// keys, so that Clone can give the clone a copy of its own.
+type stateCopier interface {
+	copyState() interface{}
+}
+
+// Copy the unexpired items of other into c, resolving conflicts with items
+// already in c using policy. Returns the number of items copied. Items that
+// are set or deleted in other during the merge may or may not be copied.
+func (c *cache) Merge(other *Cache, policy MergePolicy) int {
+	if c.isClosed() {
+		return 0
+	}
+	n := 0
+	now := other.now().UnixNano()
+	other.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		if v.Expiration > 0 && now > v.Expiration {
+			return true
+		}
+		k := key.(string)
+		ov, found := c.getItem(k)
+		if found && !c.expired(ov) && !policy.replaces(ov, v) {
+			return true
+		}
+		if found {
+			c.tags.remove(k, ov.Tags)
+		}
+		if len(v.Tags) > 0 {
+			v.Tags = append([]string(nil), v.Tags...)
+		}
+		v.Version = c.nextVersion()
+		v.access = nil
+		c.store(k, v)
+		c.notify(EventSet, k, v.Object)
+		n++
+		return true
+	})
+	return n
+}
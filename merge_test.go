@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	tc := New(Expiration(time.Minute), CacheSize(10))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.SetWithTags("b", 2, NoExpiration, "t")
+
+	nc := tc.Clone()
+	tc.Set("a", 10, DefaultExpiration)
+	tc.Set("c", 3, DefaultExpiration)
+	if x, _ := nc.Get("a"); x != 1 {
+		t.Error("a is", x, "in the clone")
+	}
+	if _, found := nc.Get("c"); found {
+		t.Error("c was added to the clone")
+	}
+	if nc.CacheSize != 10 || nc.Expiration != time.Minute {
+		t.Error("the clone has different options")
+	}
+	if n := nc.InvalidateTag("t"); n != 1 {
+		t.Error("InvalidateTag deleted", n, "items from the clone instead of 1")
+	}
+	if _, found := tc.Get("b"); !found {
+		t.Error("invalidating a tag in the clone deleted b from the original")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	a := New(Expiration(DefaultExpiration), WithClock(clock))
+	b := New(Expiration(DefaultExpiration), WithClock(clock))
+	a.Set("shared", "a", time.Minute)
+	a.Set("onlyA", 1, DefaultExpiration)
+	b.Set("shared", "b", time.Hour)
+	b.Set("onlyB", 2, DefaultExpiration)
+	b.Set("expired", 3, time.Second)
+	clock.Advance(2 * time.Second)
+
+	c := a.Clone()
+	if n := c.Merge(b, SkipExisting); n != 1 {
+		t.Error("Merge with SkipExisting copied", n, "items instead of 1")
+	}
+	if x, _ := c.Get("shared"); x != "a" {
+		t.Error("shared is", x, "with SkipExisting")
+	}
+	if _, found := c.Get("expired"); found {
+		t.Error("an expired item was merged")
+	}
+
+	c = a.Clone()
+	c.Merge(b, Overwrite)
+	if x, _ := c.Get("shared"); x != "b" {
+		t.Error("shared is", x, "with Overwrite")
+	}
+
+	c = b.Clone()
+	c.Merge(a, NewestWins)
+	if x, _ := c.Get("shared"); x != "b" {
+		t.Error("shared is", x, "with NewestWins")
+	}
+	if c.ItemCount() != 3 {
+		t.Error("the merged cache has", c.ItemCount(), "items instead of 3")
+	}
+}
+
+func TestCloneOwnState(t *testing.T) {
+	sink := make(chan Event, 10)
+	tc := New(Expiration(DefaultExpiration), CacheSize(10), Policy(CLOCK()), EventSink(sink),
+		Quota("q:", 1, 0))
+	tc.Set("a", 1, DefaultExpiration)
+	<-sink
+	nc := tc.Clone()
+	if nc.Policy == tc.Policy {
+		t.Error("the clone shares the CLOCK policy")
+	}
+	nc.Set("b", 2, DefaultExpiration)
+	select {
+	case e := <-sink:
+		t.Error("the clone sent an event to the original's sink:", e)
+	default:
+	}
+	nc.Quotas[0].MaxItems = 5
+	if tc.Quotas[0].MaxItems != 1 {
+		t.Error("the clone shares the Quotas of the original")
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"log"
+	"time"
+)
+
+// The core operations of a cache, as seen by a Middleware.
+type (
+	GetHandler    func(k string) (interface{}, bool)
+	SetHandler    func(k string, x interface{}, d time.Duration)
+	DeleteHandler func(k string)
+)
+
+// A Middleware wraps the Get, Set and Delete methods of a cache. Each field,
+// if not nil, is given the next handler in the chain and returns the handler
+// to use in its place; it may inspect or transform the arguments and results,
+// or not call next at all. SetWithCost, SetWithPriority, SetWithTags and
+// ImportJSONL go through the Set middleware too. Other methods (GetWithExpiration, Add,
+// Increment, the janitor, etc.) bypass the middleware.
+type Middleware struct {
+	Get    func(next GetHandler) GetHandler
+	Set    func(next SetHandler) SetHandler
+	Delete func(next DeleteHandler) DeleteHandler
+}
+
+// Wrap the operations of the cache with the given middleware. Middleware is
+// applied in the order it is added, so the first one added sees every call
+// first.
+func Use(mw ...Middleware) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Middleware = append(m.Middleware, mw...)
+		return nil
+	}
+}
+
+type chain struct {
+	get    GetHandler
+	set    SetHandler
+	delete DeleteHandler
+	mws    []Middleware
+}
+
+func newChain(c *cache, mws []Middleware) *chain {
+	ch := &chain{
+		get:    c.doGet,
+		delete: c.doDelete,
+		mws:    mws,
+	}
+	ch.set = ch.setTo(c.doSet)
+	for i := len(mws) - 1; i >= 0; i-- {
+		mw := mws[i]
+		if mw.Get != nil {
+			ch.get = mw.Get(ch.get)
+		}
+		if mw.Delete != nil {
+			ch.delete = mw.Delete(ch.delete)
+		}
+	}
+	return ch
+}
+
+// Returns the chain's Set handler, but ending in last rather than doSet.
+func (ch *chain) setTo(last SetHandler) SetHandler {
+	h := last
+	for i := len(ch.mws) - 1; i >= 0; i-- {
+		if mw := ch.mws[i]; mw.Set != nil {
+			h = mw.Set(h)
+		}
+	}
+	return h
+}
+
+// Returns a Middleware logging every Get (including whether it was a hit or a
+// miss), Set and Delete to l.
+func LoggingMiddleware(l *log.Logger) Middleware {
+	return Middleware{
+		Get: func(next GetHandler) GetHandler {
+			return func(k string) (interface{}, bool) {
+				x, found := next(k)
+				if found {
+					l.Printf("cache: get %q: hit", k)
+				} else {
+					l.Printf("cache: get %q: miss", k)
+				}
+				return x, found
+			}
+		},
+		Set: func(next SetHandler) SetHandler {
+			return func(k string, x interface{}, d time.Duration) {
+				l.Printf("cache: set %q (%v)", k, d)
+				next(k, x, d)
+			}
+		},
+		Delete: func(next DeleteHandler) DeleteHandler {
+			return func(k string) {
+				l.Printf("cache: delete %q", k)
+				next(k)
+			}
+		},
+	}
+}
+
+// Returns a Middleware ignoring calls to Set with values larger than max
+// bytes, as estimated for MaxBytes (see Sized). Any existing item with the
+// same key is left unchanged.
+func MaxValueSize(max int64) Middleware {
+	return Middleware{
+		Set: func(next SetHandler) SetHandler {
+			return func(k string, x interface{}, d time.Duration) {
+				if estimateSize("", x)-itemOverhead > max {
+					return
+				}
+				next(k, x, d)
+			}
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return Middleware{
+			Get: func(next GetHandler) GetHandler {
+				return func(k string) (interface{}, bool) {
+					calls = append(calls, name)
+					return next(k)
+				}
+			},
+		}
+	}
+	tc := New(Expiration(DefaultExpiration), Use(mw("a"), mw("b")), Use(mw("c")))
+	tc.Get("foo")
+	if strings.Join(calls, "") != "abc" {
+		t.Error("middleware was called in order", calls)
+	}
+}
+
+func TestMiddlewareTransform(t *testing.T) {
+	upper := Middleware{
+		Set: func(next SetHandler) SetHandler {
+			return func(k string, x interface{}, d time.Duration) {
+				if s, ok := x.(string); ok {
+					x = strings.ToUpper(s)
+				}
+				next(k, x, d)
+			}
+		},
+	}
+	tc := New(Expiration(DefaultExpiration), Use(upper))
+	tc.Set("foo", "bar", DefaultExpiration)
+	if x, _ := tc.Get("foo"); x != "BAR" {
+		t.Error("foo is", x, "instead of BAR")
+	}
+	tc.SetWithCost("cost", "bar", 5, DefaultExpiration)
+	if item, _ := tc.GetItem("cost"); item.Object != "BAR" || item.Cost != 5 {
+		t.Error("SetWithCost stored", item.Object, item.Cost)
+	}
+	tc.SetWithPriority("priority", "bar", HighPriority, DefaultExpiration)
+	if item, _ := tc.GetItem("priority"); item.Object != "BAR" || item.Priority != HighPriority {
+		t.Error("SetWithPriority stored", item.Object, item.Priority)
+	}
+	tc.SetWithTags("tags", "bar", DefaultExpiration, "t")
+	if item, _ := tc.GetItem("tags"); item.Object != "BAR" || len(item.Tags) != 1 {
+		t.Error("SetWithTags stored", item.Object, item.Tags)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	tc := New(Expiration(DefaultExpiration), Use(LoggingMiddleware(log.New(&buf, "", 0))))
+	tc.Set("foo", 1, DefaultExpiration)
+	tc.Get("foo")
+	tc.Get("bar")
+	tc.Delete("foo")
+	want := "cache: set \"foo\" (0s)\ncache: get \"foo\": hit\ncache: get \"bar\": miss\ncache: delete \"foo\"\n"
+	if buf.String() != want {
+		t.Errorf("logged %q instead of %q", buf.String(), want)
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), Use(MaxValueSize(4)))
+	tc.Set("small", "abcd", DefaultExpiration)
+	tc.Set("large", "abcde", DefaultExpiration)
+	if _, found := tc.Get("small"); !found {
+		t.Error("small was not found")
+	}
+	if _, found := tc.Get("large"); found {
+		t.Error("large was found, but it is over the limit")
+	}
+}
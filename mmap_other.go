@@ -0,0 +1,13 @@
+//go:build !unix
+
+package cache
+
+// Where mmap is not available, off-heap memory is allocated on the Go heap
+// after all. It is still a single object per chunk.
+func mmap(n int) ([]byte, error) {
+	return make([]byte, n), nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package cache
+
+import (
+	"syscall"
+)
+
+// Allocate n bytes of memory outside the Go heap.
+func mmap(n int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, n, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+// Release memory allocated with mmap.
+func munmap(b []byte) error {
+	return syscall.Munmap(b[:cap(b)])
+}
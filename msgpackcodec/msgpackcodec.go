@@ -0,0 +1,28 @@
+// Package msgpackcodec provides a cache.Codec serializing snapshots with
+// MessagePack, which is more compact than JSON and readable from most
+// languages:
+//
+//	c := cache.New(cache.WithCodec(msgpackcodec.Codec))
+package msgpackcodec
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+type codec struct{}
+
+// Codec serializes snapshots with MessagePack. As with cache.JSONCodec,
+// values are decoded into generic types rather than their original Go types.
+var Codec cache.Codec = codec{}
+
+func (codec) NewEncoder(w io.Writer) cache.Encoder {
+	return msgpack.NewEncoder(w)
+}
+
+func (codec) NewDecoder(r io.Reader) cache.Decoder {
+	return msgpack.NewDecoder(r)
+}
@@ -0,0 +1,29 @@
+package msgpackcodec
+
+import (
+	"bytes"
+	"testing"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func TestCodec(t *testing.T) {
+	tc := cache.New(cache.WithCodec(Codec))
+	tc.Set("foo", "bar", cache.DefaultExpiration)
+	tc.Set("baz", "qux", cache.DefaultExpiration)
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	oc := cache.New(cache.WithCodec(Codec))
+	if err := oc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := oc.Get("foo"); x != "bar" {
+		t.Error("foo was not loaded:", x)
+	}
+	if n := oc.ItemCount(); n != 2 {
+		t.Error("Expected 2 items, got", n)
+	}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// A Namespace is a view of a cache in which every key is transparently
+// prefixed with the namespace's name and a colon. Namespaces share the
+// storage, size limits and janitor of their cache, but have their own default
+// expiration and hit/miss statistics, and can be flushed independently. A
+// namespace can also be given its own size limit with SetCacheSize.
+//
+// Colons and backslashes in the name are escaped in the prefix (as \c and
+// \\), so that no namespace's prefix is a prefix of another's: namespaces
+// "a" and "a:b" never see each other's items.
+type Namespace struct {
+	stats      cacheStats
+	expiration int64
+	size       int64
+	c          *cache
+	prefix     string
+}
+
+// Returns the namespace with the given name, creating it if it doesn't exist.
+// Calling Namespace again with the same name returns the same namespace.
+func (c *cache) Namespace(name string) *Namespace {
+	if ns, found := c.namespaces.Load(name); found {
+		return ns.(*Namespace)
+	}
+	ns, _ := c.namespaces.LoadOrStore(name, &Namespace{
+		c:      c,
+		prefix: namespacePrefix(name),
+	})
+	return ns.(*Namespace)
+}
+
+var namespaceEscaper = strings.NewReplacer(`\`, `\\`, ":", `\c`)
+
+// Returns the prefix of the keys in the namespace name. The escaped name has
+// no colons, so the prefix ends at its first colon.
+func namespacePrefix(name string) string {
+	return namespaceEscaper.Replace(name) + ":"
+}
+
+// Returns the namespace for the tenant id, for caches shared by several
+// tenants. It is the same as Namespace(id); use SetCacheSize and
+// SetExpiration to give each tenant its own limits, and Stats and Flush to
+// observe and clear its items separately.
+func (c *cache) Tenant(id string) *Namespace {
+	return c.Namespace(id)
+}
+
+// Limit the number of items in the namespace to n, or remove the limit if n is
+// 0. Like CacheSize, the limit is enforced by DeleteLRU (and the janitor),
+// which evicts the namespace's items according to the cache's eviction policy
+// until it is within the limit, without evicting the items of other
+// namespaces. Evictions are counted in the namespace's Stats as well as the
+// cache's.
+func (n *Namespace) SetCacheSize(size int) {
+	atomic.StoreInt64(&n.size, int64(size))
+	if size > 0 {
+		atomic.StoreUint32(&n.c.nsLimits, 1)
+	}
+}
+
+// Evict items until every namespace is within the limit set with
+// SetCacheSize.
+func (c *cache) deleteNamespaceLRU() ([]KeyValue, []string) {
+	var (
+		evicted []KeyValue
+		keys    []string
+	)
+	c.namespaces.Range(func(_, value interface{}) bool {
+		n := value.(*Namespace)
+		size := atomic.LoadInt64(&n.size)
+		if size <= 0 {
+			return true
+		}
+		e, k := c.deleteQuota(PrefixQuota{Prefix: n.prefix, MaxItems: int(size)})
+		n.stats.evicted(len(k))
+		evicted = append(evicted, e...)
+		keys = append(keys, k...)
+		return true
+	})
+	return evicted, keys
+}
+
+// Set the default expiration for items added to the namespace with
+// DefaultExpiration. If d is DefaultExpiration, the cache's default
+// expiration is used.
+func (n *Namespace) SetExpiration(d time.Duration) {
+	atomic.StoreInt64(&n.expiration, int64(d))
+}
+
+func (n *Namespace) duration(d time.Duration) time.Duration {
+	if d == DefaultExpiration {
+		return time.Duration(atomic.LoadInt64(&n.expiration))
+	}
+	return d
+}
+
+// Add an item to the namespace, replacing any existing item. See Cache.Set.
+func (n *Namespace) Set(k string, x interface{}, d time.Duration) {
+	n.c.Set(n.prefix+k, x, n.duration(d))
+}
+
+// Add an item to the namespace only if an item doesn't already exist for the
+// given key, or if the existing item has expired. See Cache.Add.
+func (n *Namespace) Add(k string, x interface{}, d time.Duration) error {
+	return n.c.Add(n.prefix+k, x, n.duration(d))
+}
+
+// Set a new value for the key only if it already exists in the namespace, and
+// the existing item hasn't expired. See Cache.Replace.
+func (n *Namespace) Replace(k string, x interface{}, d time.Duration) error {
+	return n.c.Replace(n.prefix+k, x, n.duration(d))
+}
+
+// Get an item from the namespace. See Cache.Get.
+func (n *Namespace) Get(k string) (interface{}, bool) {
+	x, found := n.c.Get(n.prefix + k)
+	if found {
+		n.stats.hit()
+	} else {
+		n.stats.miss()
+	}
+	return x, found
+}
+
+// Get an item and its expiration time from the namespace. See
+// Cache.GetWithExpiration.
+func (n *Namespace) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	x, exp, found := n.c.GetWithExpiration(n.prefix + k)
+	if found {
+		n.stats.hit()
+	} else {
+		n.stats.miss()
+	}
+	return x, exp, found
+}
+
+// Delete an item from the namespace. See Cache.Delete.
+func (n *Namespace) Delete(k string) {
+	n.c.Delete(n.prefix + k)
+}
+
+// Returns the keys of all unexpired items in the namespace, without the
+// namespace's prefix.
+func (n *Namespace) Keys() []string {
+	keys := n.c.KeysWithPrefix(n.prefix)
+	for i, k := range keys {
+		keys[i] = k[len(n.prefix):]
+	}
+	return keys
+}
+
+// Returns the number of items in the namespace. This may include items that
+// have expired, but have not yet been cleaned up.
+func (n *Namespace) ItemCount() int {
+	count := 0
+	n.c.items.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), n.prefix) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// Delete all items in the namespace, calling the eviction callback for each
+// of them. Returns the number of items deleted.
+func (n *Namespace) Flush() int {
+	return n.c.DeleteByPrefix(n.prefix)
+}
+
+// Returns the hit and miss counters of the namespace, and the number of items
+// evicted to enforce its SetCacheSize limit. Other evictions and janitor runs
+// are only tracked for the cache as a whole.
+func (n *Namespace) Stats() Stats {
+	return Stats{
+		Hits:      atomic.LoadUint64(&n.stats.hits),
+		Misses:    atomic.LoadUint64(&n.stats.misses),
+		Evictions: atomic.LoadUint64(&n.stats.evictions),
+	}
+}
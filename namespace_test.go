@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	users := tc.Namespace("users")
+	if tc.Namespace("users") != users {
+		t.Error("Namespace returned a different namespace for the same name")
+	}
+	sessions := tc.Namespace("sessions")
+
+	users.Set("1", "alice", DefaultExpiration)
+	sessions.Set("1", "token", DefaultExpiration)
+
+	x, found := users.Get("1")
+	if !found || x.(string) != "alice" {
+		t.Error("users:1 is not alice:", x)
+	}
+	if x, found := tc.Get("users:1"); !found || x.(string) != "alice" {
+		t.Error("Namespaced key was not prefixed:", x)
+	}
+	users.Get("2")
+
+	if s := users.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", s.Hits, s.Misses)
+	}
+	if keys := users.Keys(); len(keys) != 1 || keys[0] != "1" {
+		t.Error("Keys returned the wrong keys:", keys)
+	}
+
+	if n := users.Flush(); n != 1 {
+		t.Error("Flush did not delete 1 item:", n)
+	}
+	if users.ItemCount() != 0 {
+		t.Error("users is not empty after Flush")
+	}
+	if _, found := sessions.Get("1"); !found {
+		t.Error("Flushing users deleted an item from sessions")
+	}
+}
+
+func TestNamespaceExpiration(t *testing.T) {
+	tc := New(Expiration(1 * time.Hour))
+	ns := tc.Namespace("short")
+	ns.SetExpiration(1 * time.Minute)
+	ns.Set("a", 1, DefaultExpiration)
+	_, exp, _ := ns.GetWithExpiration("a")
+	if exp.After(time.Now().Add(2 * time.Minute)) {
+		t.Error("Namespace default expiration was not used:", exp)
+	}
+
+	ns.SetExpiration(DefaultExpiration)
+	ns.Set("b", 2, DefaultExpiration)
+	_, exp, _ = ns.GetWithExpiration("b")
+	if exp.Before(time.Now().Add(30 * time.Minute)) {
+		t.Error("Cache default expiration was not used:", exp)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	a, b := tc.Tenant("a"), tc.Tenant("b")
+	if a != tc.Namespace("a") {
+		t.Error("Tenant and Namespace returned different handles")
+	}
+	a.SetCacheSize(2)
+	b.SetExpiration(time.Minute)
+	for _, k := range []string{"1", "2", "3"} {
+		a.Set(k, k, DefaultExpiration)
+		b.Set(k, k, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	tc.DeleteLRU()
+	if n := a.ItemCount(); n != 2 {
+		t.Errorf("tenant a has %d items; want 2", n)
+	}
+	if _, found := a.Get("1"); found {
+		t.Error("a's least recently used item was not evicted")
+	}
+	if n := b.ItemCount(); n != 3 {
+		t.Errorf("tenant b has %d items; want 3", n)
+	}
+	if s := a.Stats(); s.Evictions != 1 || s.Misses != 1 {
+		t.Errorf("tenant a has %d evictions and %d misses; want 1 and 1", s.Evictions, s.Misses)
+	}
+	if s := b.Stats(); s.Evictions != 0 {
+		t.Errorf("tenant b has %d evictions; want 0", s.Evictions)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, found := b.Get("1"); found {
+		t.Error("tenant b's default expiration was not used")
+	}
+	if n := a.Flush(); n != 2 || b.ItemCount() != 3 {
+		t.Error("flushing tenant a affected tenant b")
+	}
+}
+
+func TestNestedNamespaceNames(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	a, ab := tc.Tenant("a"), tc.Tenant("a:b")
+	ab.Set("1", 1, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	a.Set("1", 1, DefaultExpiration)
+	a.Set("b:2", 2, DefaultExpiration)
+	if n := a.ItemCount(); n != 2 {
+		t.Errorf("tenant a has %d items; want 2", n)
+	}
+	if _, found := ab.Get("2"); found {
+		t.Error("tenant a:b sees tenant a's item b:2")
+	}
+
+	a.SetCacheSize(1)
+	tc.DeleteLRU()
+	if n := ab.ItemCount(); n != 1 {
+		t.Errorf("evicting from tenant a left tenant a:b with %d items; want 1", n)
+	}
+	a.Flush()
+	if _, found := ab.Get("1"); !found {
+		t.Error("flushing tenant a deleted tenant a:b's item")
+	}
+}
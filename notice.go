@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"time"
+)
+
+// Call cb for every item shortly before it expires: the janitor calls it once
+// for each item expiring within before of one of its runs, so the callback
+// comes between before and before plus CleanupInterval ahead of the
+// expiration. This gives applications a chance to renew leases or refresh
+// data before the item is gone. An item that is replaced (e.g. with a new
+// expiration) is notified again when the new item nears its expiration.
+//
+// ExpiryNotice requires the janitor, i.e. CleanupInterval or AdaptiveCleanup,
+// and visits every item on each run. The callback is called by the janitor
+// goroutine, which doesn't do anything else until it returns.
+func ExpiryNotice(before time.Duration, cb func(k string, x interface{}, expiration time.Time)) CacheOption {
+	return func(m *CacheOptions) error {
+		m.ExpiryNotice = before
+		m.ExpiryCallback = cb
+		return nil
+	}
+}
+
+// Call ExpiryCallback for the items expiring within ExpiryNotice that it
+// hasn't been called for yet. Only called by the janitor.
+func (j *janitor) noticeExpiring(c *cache) {
+	now := c.now().UnixNano()
+	horizon := now + int64(c.ExpiryNotice)
+	type notice struct {
+		k string
+		v Item
+	}
+	var due []notice
+	noticed := make(map[string]uint64, len(j.noticed))
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		if v.Expiration == 0 || v.Expiration <= now || v.Expiration > horizon {
+			return true
+		}
+		k := key.(string)
+		noticed[k] = v.Version
+		if version, ok := j.noticed[k]; !ok || version != v.Version {
+			due = append(due, notice{k, v})
+		}
+		return true
+	})
+	// Only the items still within the notice period are remembered.
+	j.noticed = noticed
+	for _, n := range due {
+		c.ExpiryCallback(n.k, n.v.Object, time.Unix(0, n.v.Expiration))
+	}
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryNotice(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	notices := make(chan string, 10)
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), CleanupInterval(time.Second),
+		ExpiryNotice(5*time.Second, func(k string, x interface{}, exp time.Time) {
+			notices <- k
+		}))
+	defer tc.Close()
+	tc.Set("a", 1, 10*time.Second)
+	tc.Set("b", 2, time.Minute)
+	tc.Set("c", 3, NoExpiration)
+
+	for i := 0; i < 4; i++ {
+		clock.Advance(time.Second)
+		waitJanitorRuns(t, tc, uint64(i+1))
+	}
+	select {
+	case k := <-notices:
+		t.Fatalf("%s was noticed early", k)
+	default:
+	}
+	for i := 4; i < 7; i++ {
+		clock.Advance(time.Second)
+		waitJanitorRuns(t, tc, uint64(i+1))
+	}
+	if k := <-notices; k != "a" {
+		t.Errorf("got a notice for %s; want a", k)
+	}
+	select {
+	case k := <-notices:
+		t.Errorf("got a second notice for %s", k)
+	default:
+	}
+
+	// Renewing the item notices it again before its new expiration.
+	tc.Set("a", 1, 3*time.Second)
+	clock.Advance(time.Second)
+	waitJanitorRuns(t, tc, 8)
+	if k := <-notices; k != "a" {
+		t.Errorf("got a notice for %s; want a", k)
+	}
+
+	if _, err := NewWithError(ExpiryNotice(time.Second, func(string, interface{}, time.Time) {})); err == nil {
+		t.Error("ExpiryNotice without a janitor was accepted")
+	}
+}
@@ -0,0 +1,275 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOutOfRange is returned by IncrementBounded and DecrementBounded if the
+// result would be outside the given bounds, or would overflow the item's type.
+var ErrOutOfRange = errors.New("cache: value out of range")
+
+// Number is the set of types the generic counter functions work with.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Increment the item k, whose value must be of type T, by n. Returns the
+// incremented value, or an error if the item was not found or its value is
+// not a T. This replaces the IncrementInt, IncrementInt8, ... methods:
+//
+//	n, err := cache.IncrementNumber(c, "hits", int64(1))
+func IncrementNumber[T Number](c *Cache, k string, n T) (T, error) {
+	return addNumber(c.cache, k, n, false)
+}
+
+// Decrement the item k, whose value must be of type T, by n. Returns the
+// decremented value, or an error if the item was not found or its value is
+// not a T.
+func DecrementNumber[T Number](c *Cache, k string, n T) (T, error) {
+	return addNumber(c.cache, k, n, true)
+}
+
+// Add n to (or, if sub is set, subtract it from) the item k.
+func addNumber[T Number](c *cache, k string, n T, sub bool) (T, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	// Like the other increments, the read-modify-write holds c.mu, so
+	// concurrent increments of the same item are not lost.
+	c.mu.Lock()
+	v, found := c.getItem(k)
+	if !found || c.expired(v) {
+		c.mu.Unlock()
+		return 0, fmt.Errorf("Item %s not found", k)
+	}
+	if nv, ok := addCounter(c, k, v, n, sub); ok {
+		c.mu.Unlock()
+		return nv, nil
+	}
+	rv, ok := v.Object.(T)
+	if !ok {
+		c.mu.Unlock()
+		return 0, fmt.Errorf("The value for %s is not an %T", k, n)
+	}
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
+	}
+	nv := rv + n
+	if sub {
+		nv = rv - n
+	}
+	v.Object = nv
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.mu.Unlock()
+	c.notify(EventSet, k, nv)
+	return nv, nil
+}
+
+// Increment an item of type int64 by n, or, if it is not in the cache (or has
+// expired), set it to n with the expiration d. Returns the new value. Unlike
+// with an Add followed by IncrementInt64, concurrent callers cannot lose
+// increments when the key is missing. If the item's value is not an int64, it
+// is replaced.
+func (c *cache) IncrementInt64OrSet(k string, n int64, d time.Duration) int64 {
+	c.mu.Lock()
+	nv, _ := incrementOrSet(c, k, n, d, true)
+	c.mu.Unlock()
+	return nv
+}
+
+// Increment the item k, whose value must be of type T, by n, or, if it is not
+// in the cache (or has expired), set it to n with the expiration d. Returns
+// the new value, or an error if the item's value is not a T or the cache is
+// closed.
+func IncrementOrSet[T Number](c *Cache, k string, n T, d time.Duration) (T, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return incrementOrSet(c.cache, k, n, d, false)
+}
+
+// Must be called with c.mu held.
+func incrementOrSet[T Number](c *cache, k string, n T, d time.Duration, overwrite bool) (T, error) {
+	v, found := c.getItem(k)
+	if found && !c.expired(v) {
+		if nv, ok := addCounter(c, k, v, n, false); ok {
+			return nv, nil
+		}
+		if rv, ok := v.Object.(T); ok {
+			if c.lru() {
+				v.Accessed = c.now().UnixNano()
+			}
+			nv := rv + n
+			v.Object = nv
+			v.Version = c.nextVersion()
+			c.store(k, v)
+			c.notify(EventSet, k, nv)
+			return nv, nil
+		}
+		if !overwrite {
+			return 0, fmt.Errorf("The value for %s is not an %T", k, n)
+		}
+	}
+	c.set(k, n, d)
+	return n, nil
+}
+
+// If v holds a Counter and T is int64, atomically add n to (or subtract it
+// from) the counter.
+func addCounter[T Number](c *cache, k string, v Item, n T, sub bool) (T, bool) {
+	ctr, ok := v.Object.(*Counter)
+	if !ok {
+		return 0, false
+	}
+	delta, ok := interface{}(n).(int64)
+	if !ok {
+		return 0, false
+	}
+	if sub {
+		delta = -delta
+	}
+	nv := ctr.Add(delta)
+	c.notify(EventSet, k, ctr)
+	return interface{}(nv).(T), true
+}
+
+// Increment an item of type int64 (or a Counter) by n, without letting it
+// exceed max: if the result would be greater than max, or would overflow, the
+// item is set to max instead. Returns the new value, or an error if the item
+// was not found or its value is not an int64.
+func (c *cache) IncrementInt64Capped(k string, n, max int64) (int64, error) {
+	return c.updateInt64(k, func(rv int64) (int64, error) {
+		nv := rv + n
+		if nv > max || (n > 0 && nv < rv) {
+			nv = max
+		}
+		return nv, nil
+	})
+}
+
+// Decrement an item of type int64 (or a Counter) by n, without letting it go
+// below min: if the result would be less than min, or would overflow, the
+// item is set to min instead.
+func (c *cache) DecrementInt64Floored(k string, n, min int64) (int64, error) {
+	return c.updateInt64(k, func(rv int64) (int64, error) {
+		nv := rv - n
+		if nv < min || (n > 0 && nv > rv) {
+			nv = min
+		}
+		return nv, nil
+	})
+}
+
+// Increment the item k, whose value must be of type T, by n if the result is
+// between min and max (inclusive) and does not overflow T. Otherwise the item
+// is left unchanged and ErrOutOfRange is returned. This is what quota
+// counters of unsigned types need, as they would otherwise wrap around.
+func IncrementBounded[T Number](c *Cache, k string, n, min, max T) (T, error) {
+	return addBounded(c.cache, k, n, min, max, false)
+}
+
+// Like IncrementBounded, but subtracts n.
+func DecrementBounded[T Number](c *Cache, k string, n, min, max T) (T, error) {
+	return addBounded(c.cache, k, n, min, max, true)
+}
+
+func addBounded[T Number](c *cache, k string, n, min, max T, sub bool) (T, error) {
+	check := func(rv T) (T, error) {
+		var nv T
+		var wrapped bool
+		if sub {
+			nv = rv - n
+			wrapped = (n > 0 && nv > rv) || (n < 0 && nv < rv)
+		} else {
+			nv = rv + n
+			wrapped = (n > 0 && nv < rv) || (n < 0 && nv > rv)
+		}
+		if wrapped || nv < min || nv > max || isNaN(nv) {
+			return rv, ErrOutOfRange
+		}
+		return nv, nil
+	}
+	if _, ok := interface{}(n).(int64); ok {
+		nv, err := c.updateInt64(k, func(rv int64) (int64, error) {
+			nv, err := check(interface{}(rv).(T))
+			return interface{}(nv).(int64), err
+		})
+		return interface{}(nv).(T), err
+	}
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, found := c.getItem(k)
+	if !found || c.expired(v) {
+		return 0, fmt.Errorf("Item %s not found", k)
+	}
+	rv, ok := v.Object.(T)
+	if !ok {
+		return 0, fmt.Errorf("The value for %s is not an %T", k, n)
+	}
+	nv, err := check(rv)
+	if err != nil {
+		return rv, err
+	}
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
+	}
+	v.Object = nv
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.notify(EventSet, k, nv)
+	return nv, nil
+}
+
+func isNaN[T Number](x T) bool {
+	return x != x
+}
+
+// Replace the value of the int64 item or Counter k with the result of f,
+// unless f returns an error. Counters are updated atomically; other items are
+// updated with c.mu held.
+func (c *cache) updateInt64(k string, f func(int64) (int64, error)) (int64, error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	if v, found := c.getItem(k); found && !c.expired(v) {
+		if ctr, ok := v.Object.(*Counter); ok {
+			nv, err := ctr.update(f)
+			if err == nil {
+				c.notify(EventSet, k, ctr)
+			}
+			return nv, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, found := c.getItem(k)
+	if !found || c.expired(v) {
+		return 0, fmt.Errorf("Item %s not found", k)
+	}
+	rv, ok := v.Object.(int64)
+	if !ok {
+		return 0, fmt.Errorf("The value for %s is not an int64", k)
+	}
+	nv, err := f(rv)
+	if err != nil {
+		return rv, err
+	}
+	if c.lru() {
+		v.Accessed = c.now().UnixNano()
+	}
+	v.Object = nv
+	v.Version = c.nextVersion()
+	c.store(k, v)
+	c.notify(EventSet, k, nv)
+	return nv, nil
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrementInt64OrSet(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	if n := tc.IncrementInt64OrSet("n", 5, time.Second); n != 5 {
+		t.Error("n is", n, "instead of 5")
+	}
+	if n := tc.IncrementInt64OrSet("n", 2, time.Minute); n != 7 {
+		t.Error("n is", n, "instead of 7")
+	}
+	// The expiration of the first call is kept.
+	clock.Advance(2 * time.Second)
+	if n := tc.IncrementInt64OrSet("n", 1, time.Second); n != 1 {
+		t.Error("n is", n, "instead of 1 after expiring")
+	}
+
+	tc.Set("s", "foo", DefaultExpiration)
+	if n := tc.IncrementInt64OrSet("s", 3, DefaultExpiration); n != 3 {
+		t.Error("s is", n, "instead of 3")
+	}
+}
+
+func TestIncrementInt64OrSetConcurrent(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tc.IncrementInt64OrSet("n", 1, DefaultExpiration)
+		}()
+	}
+	wg.Wait()
+	if x, _ := tc.Get("n"); x != int64(50) {
+		t.Error("n is", x, "instead of 50")
+	}
+}
+
+func TestIncrementNumberConcurrent(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("n", int64(0), DefaultExpiration)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					IncrementNumber(tc, "n", int64(1))
+				case 1:
+					tc.IncrementInt64OrSet("n", 1, DefaultExpiration)
+				case 2:
+					tc.IncrementInt64("n", 1)
+				case 3:
+					tc.Increment("n", 1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if x, _ := tc.Get("n"); x != int64(8000) {
+		t.Errorf("n is %v; want 8000", x)
+	}
+}
+
+func TestIncrementOrSet(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	if n, err := IncrementOrSet(tc, "f", 1.5, DefaultExpiration); err != nil || n != 1.5 {
+		t.Error("f is", n, err)
+	}
+	if n, err := IncrementOrSet(tc, "f", 1.0, DefaultExpiration); err != nil || n != 2.5 {
+		t.Error("f is", n, err)
+	}
+	if _, err := IncrementOrSet(tc, "f", uint8(1), DefaultExpiration); err == nil {
+		t.Error("incrementing a float64 as a uint8 did not fail")
+	}
+}
+
+func TestIncrementNumber(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("u", uint16(1), DefaultExpiration)
+	if n, err := IncrementNumber(tc, "u", uint16(2)); err != nil || n != 3 {
+		t.Error("u is", n, err)
+	}
+	if n, err := DecrementNumber(tc, "u", uint16(1)); err != nil || n != 2 {
+		t.Error("u is", n, err)
+	}
+	if _, err := IncrementNumber(tc, "u", 1); err == nil {
+		t.Error("incrementing a uint16 as an int did not fail")
+	}
+	if _, err := IncrementNumber(tc, "missing", 1); err == nil {
+		t.Error("incrementing a missing item did not fail")
+	}
+}
+
+func TestIncrementNumberNamedType(t *testing.T) {
+	type score float64
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("s", score(1.5), DefaultExpiration)
+	if n, err := IncrementNumber(tc, "s", score(1)); err != nil || n != 2.5 {
+		t.Error("s is", n, err)
+	}
+}
+
+func TestIncrementMissingDoesNotUnlock(t *testing.T) {
+	// Increment, IncrementInt8 and DecrementInt used to unlock a mutex
+	// they had not locked when the item was missing.
+	tc := New(Expiration(DefaultExpiration))
+	if err := tc.Increment("missing", 1); err == nil {
+		t.Error("Increment of a missing item did not fail")
+	}
+	if _, err := tc.IncrementInt8("missing", 1); err == nil {
+		t.Error("IncrementInt8 of a missing item did not fail")
+	}
+	if _, err := tc.DecrementInt("missing", 1); err == nil {
+		t.Error("DecrementInt of a missing item did not fail")
+	}
+	tc.Set("s", "foo", DefaultExpiration)
+	if _, err := tc.IncrementInt8("s", 1); err == nil {
+		t.Error("IncrementInt8 of a string did not fail")
+	}
+}
+
+func TestIncrementInt64Capped(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("n", int64(8), DefaultExpiration)
+	if n, err := tc.IncrementInt64Capped("n", 5, 10); err != nil || n != 10 {
+		t.Error("n is", n, err, "instead of 10")
+	}
+	tc.Set("n", int64(math.MaxInt64-1), DefaultExpiration)
+	if n, err := tc.IncrementInt64Capped("n", 5, math.MaxInt64); err != nil || n != math.MaxInt64 {
+		t.Error("n is", n, err, "after overflowing")
+	}
+	if n, err := tc.DecrementInt64Floored("n", math.MaxInt64, 0); err != nil || n != 0 {
+		t.Error("n is", n, err, "instead of 0")
+	}
+	if _, err := tc.IncrementInt64Capped("missing", 1, 10); err == nil {
+		t.Error("incrementing a missing item did not fail")
+	}
+}
+
+func TestIncrementInt64CappedCounter(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.IncrementCounter("n", 9, DefaultExpiration)
+	if n, err := tc.IncrementInt64Capped("n", 5, 10); err != nil || n != 10 {
+		t.Error("n is", n, err, "instead of 10")
+	}
+	if n := tc.Counter("n", DefaultExpiration).Load(); n != 10 {
+		t.Error("counter is", n, "instead of 10")
+	}
+}
+
+func TestIncrementBounded(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("u", uint8(250), DefaultExpiration)
+	if n, err := IncrementBounded(tc, "u", uint8(10), 0, math.MaxUint8); err != ErrOutOfRange || n != 250 {
+		t.Error("wrapping increment returned", n, err)
+	}
+	if n, err := IncrementBounded(tc, "u", uint8(5), 0, math.MaxUint8); err != nil || n != 255 {
+		t.Error("u is", n, err, "instead of 255")
+	}
+	tc.Set("u", uint8(3), DefaultExpiration)
+	if _, err := DecrementBounded(tc, "u", uint8(4), 0, 10); err != ErrOutOfRange {
+		t.Error("wrapping decrement returned", err)
+	}
+	if x, _ := tc.Get("u"); x != uint8(3) {
+		t.Error("u is", x, "instead of 3 after a failed decrement")
+	}
+	tc.Set("i", int64(5), DefaultExpiration)
+	if _, err := IncrementBounded(tc, "i", int64(6), 0, 10); err != ErrOutOfRange {
+		t.Error("increment over max returned", err)
+	}
+	if n, err := DecrementBounded(tc, "i", int64(5), 0, 10); err != nil || n != 0 {
+		t.Error("i is", n, err, "instead of 0")
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// An OffHeapCache stores values of any type serialized with a Codec (Gob by
+// default, see WithCodec) in a BytesCache whose memory is allocated outside
+// the Go heap, so that even a cache of tens of gigabytes adds nothing to the
+// work of the garbage collector. Values are copied in and out of the cache,
+// which costs an encoding on every Set and a decoding on every Get. It shares
+// the eviction behavior and limits of BytesCache.
+type OffHeapCache struct {
+	b     *BytesCache
+	codec Codec
+}
+
+// Returns an OffHeapCache holding up to about maxBytes bytes of keys and
+// serialized values. Of the options, only Expiration, WithClock and WithCodec
+// are used; the others are ignored. The cache must be closed to release its
+// memory.
+func NewOffHeapCache(maxBytes int, options ...CacheOption) (*OffHeapCache, error) {
+	opts := GetDefaultOptions()
+	for _, opt := range options {
+		if err := opt(opts); err != nil {
+			return nil, err
+		}
+	}
+	b, err := NewBytesCache(maxBytes, append(options, OffHeap(true))...)
+	if err != nil {
+		return nil, err
+	}
+	codec := opts.Codec
+	if codec == nil {
+		codec = GobCodec
+	}
+	return &OffHeapCache{b: b, codec: codec}, nil
+}
+
+// Add an item to the cache, replacing any existing item. As with Cache.Set,
+// d may be DefaultExpiration or NoExpiration. Returns an error if x cannot be
+// serialized, or is too large to be stored.
+func (o *OffHeapCache) Set(k string, x interface{}, d time.Duration) error {
+	var buf bytes.Buffer
+	if err := o.codec.NewEncoder(&buf).Encode(x); err != nil {
+		return err
+	}
+	if !o.b.Set(k, buf.Bytes(), d) {
+		return fmt.Errorf("Item %s could not be stored (%d bytes)", k, buf.Len())
+	}
+	return nil
+}
+
+// Get an item from the cache, decoding it into v, which must be a pointer to
+// a value of the type that was stored. Returns a bool indicating whether the
+// key was found, and any error decoding it.
+func (o *OffHeapCache) Get(k string, v interface{}) (bool, error) {
+	data, found := o.b.Get(k)
+	if !found {
+		return false, nil
+	}
+	return true, o.codec.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Delete an item from the cache.
+func (o *OffHeapCache) Delete(k string) {
+	o.b.Delete(k)
+}
+
+// Returns the number of items in the cache. See BytesCache.ItemCount.
+func (o *OffHeapCache) ItemCount() int {
+	return o.b.ItemCount()
+}
+
+// Delete all items from the cache.
+func (o *OffHeapCache) Flush() {
+	o.b.Flush()
+}
+
+// Release the memory of the cache.
+func (o *OffHeapCache) Close() error {
+	return o.b.Close()
+}
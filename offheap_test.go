@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+type offHeapValue struct {
+	Name  string
+	Count int
+}
+
+func TestOffHeapCache(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	o, err := NewOffHeapCache(4<<20, Expiration(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Set("a", offHeapValue{"foo", 3}, DefaultExpiration); err != nil {
+		t.Fatal(err)
+	}
+	var v offHeapValue
+	if found, err := o.Get("a", &v); !found || err != nil || v != (offHeapValue{"foo", 3}) {
+		t.Errorf("got %+v, %v, %v; want {foo 3}", v, found, err)
+	}
+	if err := o.Set("big", make([]byte, 2<<20), DefaultExpiration); err == nil {
+		t.Error("value larger than a chunk was stored")
+	}
+	clock.Advance(2 * time.Minute)
+	if found, _ := o.Get("a", &v); found {
+		t.Error("expired item was found")
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Set("b", 1, DefaultExpiration); err == nil {
+		t.Error("Set on a closed cache succeeded")
+	}
+	if err := o.Close(); err != ErrClosed {
+		t.Errorf("second Close returned %v; want ErrClosed", err)
+	}
+}
+
+func TestBytesCacheOffHeap(t *testing.T) {
+	b, err := NewBytesCache(2<<20, OffHeap(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Set("foo", []byte("bar"), DefaultExpiration)
+	if x, found := b.Get("foo"); !found || string(x) != "bar" {
+		t.Errorf("got %q, %v; want bar", x, found)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := b.Get("foo"); found {
+		t.Error("item found after Close")
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"fmt"
+)
+
+// Returns an error if any of the options is out of range, or if options
+// conflict with each other.
+func (o *CacheOptions) validate() error {
+	if o.Expiration < 0 && o.Expiration != NoExpiration {
+		return fmt.Errorf("Expiration must be positive, DefaultExpiration or NoExpiration, not %v", o.Expiration)
+	}
+	if o.CacheSize < 0 {
+		return fmt.Errorf("CacheSize must not be negative: %d", o.CacheSize)
+	}
+	if o.MaxBytes < 0 {
+		return fmt.Errorf("MaxBytes must not be negative: %d", o.MaxBytes)
+	}
+	if o.MaxCost < 0 {
+		return fmt.Errorf("MaxCost must not be negative: %d", o.MaxCost)
+	}
+	if o.Shards < 0 {
+		return fmt.Errorf("Shards must not be negative: %d", o.Shards)
+	}
+	if o.AccessedResolution < 0 {
+		return fmt.Errorf("AccessedResolution must not be negative: %v", o.AccessedResolution)
+	}
+	if o.Compression < NoCompression || o.Compression > Snappy {
+		return fmt.Errorf("Unknown Compression: %d", o.Compression)
+	}
+	if o.PersistInterval < 0 {
+		return fmt.Errorf("PersistInterval must not be negative: %v", o.PersistInterval)
+	}
+	if o.PersistInterval > 0 && o.PersistPath == "" {
+		return fmt.Errorf("PersistInterval requires PersistPath")
+	}
+	if o.WALSyncInterval < 0 {
+		return fmt.Errorf("WALSyncInterval must not be negative: %v", o.WALSyncInterval)
+	}
+	if o.WAL && o.PersistPath == "" {
+		return fmt.Errorf("WAL requires PersistPath")
+	}
+	if o.WAL && o.EncryptionKey != nil {
+		return fmt.Errorf("WAL does not support EncryptionKey")
+	}
+	if o.CallbackWorkers < 0 {
+		return fmt.Errorf("CallbackWorkers must not be negative: %d", o.CallbackWorkers)
+	}
+	if (o.CleanupMin != 0 || o.CleanupMax != 0) && (o.CleanupMin <= 0 || o.CleanupMax < o.CleanupMin) {
+		return fmt.Errorf("Invalid AdaptiveCleanup bounds: %v, %v", o.CleanupMin, o.CleanupMax)
+	}
+	if o.JanitorMaxItems < 0 {
+		return fmt.Errorf("JanitorMaxItems must not be negative: %d", o.JanitorMaxItems)
+	}
+	if o.JanitorMaxTime < 0 {
+		return fmt.Errorf("JanitorMaxTime must not be negative: %v", o.JanitorMaxTime)
+	}
+	if (o.SizeMin != 0 || o.SizeMax != 0) && (o.SizeMin < 0 || o.SizeMax < o.SizeMin || o.SizeMax == 0) {
+		return fmt.Errorf("Invalid AdaptiveSize bounds: %d, %d", o.SizeMin, o.SizeMax)
+	}
+	if o.MemoryLimit > 0 && o.MemoryInterval <= 0 {
+		return fmt.Errorf("MemoryLimit requires a positive interval: %v", o.MemoryInterval)
+	}
+	if o.HitRatioWindow < 0 {
+		return fmt.Errorf("HitRatioWindow must not be negative: %v", o.HitRatioWindow)
+	}
+	for _, r := range o.PrefixExpirations {
+		if r.Expiration <= 0 && r.Expiration != NoExpiration {
+			return fmt.Errorf("Expiration for %q must be positive or NoExpiration, not %v", r.Prefix, r.Expiration)
+		}
+	}
+	for _, q := range o.Quotas {
+		if q.MaxItems < 0 || q.MaxBytes < 0 {
+			return fmt.Errorf("Quota for %q must not be negative: %d items, %d bytes", q.Prefix, q.MaxItems, q.MaxBytes)
+		}
+	}
+	if o.EvictionTarget < 0 || o.EvictionTarget > 1 {
+		return fmt.Errorf("EvictionTarget must be between 0 and 1: %v", o.EvictionTarget)
+	}
+	if o.ExpiryCallback != nil && o.ExpiryNotice <= 0 {
+		return fmt.Errorf("ExpiryNotice must be positive: %v", o.ExpiryNotice)
+	}
+	if o.ExpiryCallback != nil && o.CleanupInterval <= 0 && o.CleanupMax <= 0 {
+		return fmt.Errorf("ExpiryNotice requires CleanupInterval or AdaptiveCleanup")
+	}
+	if o.RefreshAhead < 0 || o.RefreshAhead > 1 {
+		return fmt.Errorf("RefreshAhead must be between 0 and 1: %v", o.RefreshAhead)
+	}
+	if o.BloomFilter < 0 {
+		return fmt.Errorf("BloomFilter must not be negative: %d", o.BloomFilter)
+	}
+	if o.MaxListLength < 0 {
+		return fmt.Errorf("MaxListLength must not be negative: %d", o.MaxListLength)
+	}
+	if o.MaxKeyLength < 0 {
+		return fmt.Errorf("MaxKeyLength must not be negative: %d", o.MaxKeyLength)
+	}
+	if o.MaxValueBytes < 0 {
+		return fmt.Errorf("MaxValueBytes must not be negative: %d", o.MaxValueBytes)
+	}
+	if o.HardCacheSize < 0 {
+		return fmt.Errorf("HardCacheSize must not be negative: %d", o.HardCacheSize)
+	}
+	if o.EvictionSamples < 0 {
+		return fmt.Errorf("EvictionSamples must not be negative: %d", o.EvictionSamples)
+	}
+	if o.HotKeys < 0 {
+		return fmt.Errorf("HotKeys must not be negative: %d", o.HotKeys)
+	}
+	if o.CallbackQueue < 0 {
+		return fmt.Errorf("CallbackQueue must not be negative: %d", o.CallbackQueue)
+	}
+	if o.CallbackPolicy != BlockWhenFull && o.CallbackPolicy != DropWhenFull {
+		return fmt.Errorf("Unknown CallbackPolicy: %d", o.CallbackPolicy)
+	}
+	if o.Policy == nil {
+		return fmt.Errorf("Policy must not be nil")
+	}
+	bounded := o.CacheSize > 0 || o.MaxBytes > 0 || o.MaxCost > 0
+	if o.Admission != nil && !bounded {
+		return fmt.Errorf("AdmissionPolicy requires CacheSize, MaxBytes or MaxCost")
+	}
+	if _, ok := o.Policy.(AccessTracker); ok && o.AccessedResolution > 0 {
+		return fmt.Errorf("AccessedResolution has no effect with a policy that tracks accesses itself")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewWithError(t *testing.T) {
+	tc, err := NewWithError(Expiration(DefaultExpiration), CacheSize(10))
+	if err != nil || tc == nil {
+		t.Fatal("Couldn't create cache with valid options:", err)
+	}
+
+	for _, opts := range [][]CacheOption{
+		{CacheSize(-1)},
+		{MaxBytes(-1)},
+		{MaxCost(-1)},
+		{Shards(-1)},
+		{Expiration(-5 * time.Second)},
+		{AccessedResolution(-1)},
+		{Policy(nil)},
+		{AdmissionPolicy(TinyLFU(10))},
+		{CacheSize(10), Policy(CLOCK()), AccessedResolution(time.Second)},
+	} {
+		if tc, err := NewWithError(opts...); err == nil || tc != nil {
+			t.Error("Invalid options were accepted:", opts)
+		}
+		if tc := New(opts...); tc != nil {
+			t.Error("New returned a cache for invalid options:", opts)
+		}
+	}
+
+	failing := errors.New("failing option")
+	_, err = NewWithError(func(*CacheOptions) error { return failing })
+	if err != failing {
+		t.Error("Error from a failing option was not returned:", err)
+	}
+}
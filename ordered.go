@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Keep the keys of the cache in a sorted index (a skip list), so that
+// RangeKeys and SortedKeys visit them in order without sorting the whole
+// cache. This suits keys that sort by time, e.g. "2024-06-01T12:00:00/...",
+// which can then be scanned by prefix or interval. It costs some memory per
+// item, and a little time whenever an item is added or deleted.
+func OrderedKeys(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.OrderedKeys = b
+		return nil
+	}
+}
+
+const maxSkipLevel = 24
+
+type skipNode struct {
+	key  string
+	next []*skipNode
+}
+
+// A sorted set of keys. The zero value is not ready to use; see newKeyList.
+type keyList struct {
+	mu    sync.RWMutex
+	head  *skipNode
+	level int
+}
+
+func newKeyList() *keyList {
+	return &keyList{
+		head:  &skipNode{next: make([]*skipNode, maxSkipLevel)},
+		level: 1,
+	}
+}
+
+// Fills update with the last node before k on every level, and returns the
+// node at or after k on the lowest level.
+func (l *keyList) find(k string, update []*skipNode) *skipNode {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key < k {
+			x = x.next[i]
+		}
+		if update != nil {
+			update[i] = x
+		}
+	}
+	return x.next[0]
+}
+
+func (l *keyList) insert(k string) {
+	var update [maxSkipLevel]*skipNode
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if x := l.find(k, update[:]); x != nil && x.key == k {
+		return
+	}
+	level := 1
+	for level < maxSkipLevel && rand.Intn(4) == 0 {
+		level++
+	}
+	for ; l.level < level; l.level++ {
+		update[l.level] = l.head
+	}
+	x := &skipNode{key: k, next: make([]*skipNode, level)}
+	for i := 0; i < level; i++ {
+		x.next[i] = update[i].next[i]
+		update[i].next[i] = x
+	}
+}
+
+// Removes k, unless keep, called with the list locked, returns true. This
+// lets a deletion check that the key has not been set again since, as the
+// insertion for that would have come before or after the check.
+func (l *keyList) delete(k string, keep func() bool) {
+	var update [maxSkipLevel]*skipNode
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	x := l.find(k, update[:])
+	if x == nil || x.key != k || keep() {
+		return
+	}
+	for i := 0; i < len(x.next); i++ {
+		update[i].next[i] = x.next[i]
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+}
+
+func (l *keyList) reset() {
+	l.mu.Lock()
+	l.head = &skipNode{next: make([]*skipNode, maxSkipLevel)}
+	l.level = 1
+	l.mu.Unlock()
+}
+
+// Appends up to n keys at or after from, and before to (unless to is empty),
+// to keys.
+func (l *keyList) scan(keys []string, from, to string, n int) []string {
+	l.mu.RLock()
+	for x := l.find(from, nil); x != nil && len(keys) < n; x = x.next[0] {
+		if to != "" && x.key >= to {
+			break
+		}
+		keys = append(keys, x.key)
+	}
+	l.mu.RUnlock()
+	return keys
+}
+
+// The number of keys RangeKeys reads from the index at a time.
+const rangeBatch = 256
+
+// Calls fn, in key order, for every unexpired item whose key is at least from
+// and less than to, or any key at least from if to is empty, until fn
+// returns false. With OrderedKeys, the keys are read from the index a batch
+// at a time, so fn may modify the cache, and items set or deleted during the
+// iteration may or may not be visited. Without it, the matching keys are
+// collected and sorted first.
+//
+// To visit the keys with a given prefix, use RangePrefix.
+func (c *cache) RangeKeys(from, to string, fn func(k string, v interface{}, exp time.Time) bool) {
+	c.rangeKeys(from, to, func(k string, v Item) bool {
+		var exp time.Time
+		if v.Expiration > 0 {
+			exp = time.Unix(0, v.Expiration)
+		}
+		return fn(k, c.copyOut(v.Object), exp)
+	})
+}
+
+func (c *cache) rangeKeys(from, to string, fn func(k string, v Item) bool) {
+	visit := func(k string) bool {
+		v, found := c.getItem(k)
+		if !found || c.expired(v) {
+			return true
+		}
+		return fn(k, v)
+	}
+	if c.ordered == nil {
+		keys := c.keys(func(k string) bool { return k >= from && (to == "" || k < to) })
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !visit(k) {
+				return
+			}
+		}
+		return
+	}
+	batch := make([]string, 0, rangeBatch)
+	for {
+		batch = c.ordered.scan(batch[:0], from, to, rangeBatch)
+		for _, k := range batch {
+			if !visit(k) {
+				return
+			}
+		}
+		if len(batch) < rangeBatch {
+			return
+		}
+		// The smallest key after the last one.
+		from = batch[len(batch)-1] + "\x00"
+	}
+}
+
+// Like RangeKeys, for the keys starting with prefix, e.g. "2024-06-01".
+func (c *cache) RangePrefix(prefix string, fn func(k string, v interface{}, exp time.Time) bool) {
+	c.RangeKeys(prefix, prefixEnd(prefix), fn)
+}
+
+// Returns the smallest string greater than every string starting with
+// prefix, or "" if there is none.
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
+// Returns the keys of all unexpired items in the cache, in sorted order.
+func (c *cache) SortedKeys() []string {
+	if c.ordered == nil {
+		keys := c.Keys()
+		sort.Strings(keys)
+		return keys
+	}
+	var keys []string
+	c.rangeKeys("", "", func(k string, _ Item) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func testRangeKeys(t *testing.T, opts ...CacheOption) {
+	tc := New(append([]CacheOption{Expiration(DefaultExpiration)}, opts...)...)
+	var all []string
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("2024-06-%02d/%03d", i%30+1, i)
+		all = append(all, k)
+		tc.Set(k, i, DefaultExpiration)
+	}
+	tc.Set("2024-06-01/expired", 0, time.Nanosecond)
+	sort.Strings(all)
+	time.Sleep(time.Millisecond)
+
+	var got []string
+	tc.RangePrefix("2024-06-01", func(k string, v interface{}, _ time.Time) bool {
+		got = append(got, k)
+		return true
+	})
+	var want []string
+	for _, k := range all {
+		if k[:10] == "2024-06-01" {
+			want = append(want, k)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RangePrefix visited %v; want %v", got, want)
+	}
+
+	got = got[:0]
+	tc.RangeKeys("2024-06-10", "2024-06-11", func(k string, _ interface{}, _ time.Time) bool {
+		got = append(got, k)
+		return len(got) < 3
+	})
+	if len(got) != 3 || got[0] != "2024-06-10/009" {
+		t.Errorf("RangeKeys visited %v", got)
+	}
+
+	tc.Delete(all[0])
+	if keys := tc.SortedKeys(); !reflect.DeepEqual(keys, all[1:]) {
+		t.Errorf("SortedKeys returned %d keys, starting with %v", len(keys), keys[:3])
+	}
+	tc.Flush()
+	if keys := tc.SortedKeys(); len(keys) != 0 {
+		t.Errorf("SortedKeys returned %v after Flush", keys)
+	}
+}
+
+func TestRangeKeys(t *testing.T) {
+	testRangeKeys(t)
+}
+
+func TestRangeKeysOrdered(t *testing.T) {
+	testRangeKeys(t, OrderedKeys(true))
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for prefix, want := range map[string]string{"": "", "ab": "ac", "a\xff": "b", "\xff\xff": ""} {
+		if got := prefixEnd(prefix); got != want {
+			t.Errorf("prefixEnd(%q) = %q; want %q", prefix, got, want)
+		}
+	}
+}
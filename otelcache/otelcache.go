@@ -0,0 +1,106 @@
+// Package otelcache wraps a cache.Cache with OpenTelemetry instrumentation.
+//
+// Every Get, Set and Delete made through the wrapper records its latency in a
+// histogram, Get additionally counts hits and misses, and if the context
+// passed to an operation carries a recording span, an event describing the
+// operation is added to it.
+package otelcache
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// Cache is an instrumented cache. Methods of the underlying cache that are
+// not overridden here are not instrumented.
+type Cache struct {
+	*cache.Cache
+
+	duration metric.Float64Histogram
+	hits     metric.Int64Counter
+	misses   metric.Int64Counter
+}
+
+var (
+	opGet    = metric.WithAttributes(attribute.String("cache.operation", "get"))
+	opSet    = metric.WithAttributes(attribute.String("cache.operation", "set"))
+	opDelete = metric.WithAttributes(attribute.String("cache.operation", "delete"))
+)
+
+// Returns an instrumented wrapper around c which creates its instruments
+// using meter.
+func New(c *cache.Cache, meter metric.Meter) (*Cache, error) {
+	duration, err := meter.Float64Histogram("go_cache.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of cache operations."))
+	if err != nil {
+		return nil, err
+	}
+	hits, err := meter.Int64Counter("go_cache.hits",
+		metric.WithDescription("Number of lookups that found an unexpired item."))
+	if err != nil {
+		return nil, err
+	}
+	misses, err := meter.Int64Counter("go_cache.misses",
+		metric.WithDescription("Number of lookups that did not find an unexpired item."))
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{
+		Cache:    c,
+		duration: duration,
+		hits:     hits,
+		misses:   misses,
+	}, nil
+}
+
+// Get an item from the cache. See cache.Cache.Get.
+func (c *Cache) Get(ctx context.Context, k string) (interface{}, bool) {
+	start := time.Now()
+	x, found := c.Cache.Get(k)
+	c.duration.Record(ctx, time.Since(start).Seconds(), opGet)
+	if found {
+		c.hits.Add(ctx, 1)
+	} else {
+		c.misses.Add(ctx, 1)
+	}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.AddEvent("cache.get", trace.WithAttributes(
+			attribute.String("cache.key", k),
+			attribute.Bool("cache.hit", found),
+		))
+	}
+	return x, found
+}
+
+// Add an item to the cache, replacing any existing item. See
+// cache.Cache.Set.
+func (c *Cache) Set(ctx context.Context, k string, x interface{}, d time.Duration) {
+	start := time.Now()
+	c.Cache.Set(k, x, d)
+	c.duration.Record(ctx, time.Since(start).Seconds(), opSet)
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.AddEvent("cache.set", trace.WithAttributes(
+			attribute.String("cache.key", k),
+			attribute.Int64("cache.ttl_ms", d.Milliseconds()),
+		))
+	}
+}
+
+// Delete an item from the cache. See cache.Cache.Delete.
+func (c *Cache) Delete(ctx context.Context, k string) {
+	start := time.Now()
+	c.Cache.Delete(k)
+	c.duration.Record(ctx, time.Since(start).Seconds(), opDelete)
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.AddEvent("cache.delete", trace.WithAttributes(
+			attribute.String("cache.key", k),
+		))
+	}
+}
@@ -0,0 +1,33 @@
+package otelcache
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric/noop"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func TestCache(t *testing.T) {
+	tc, err := New(cache.New(), noop.NewMeterProvider().Meter("test"))
+	if err != nil {
+		t.Fatal("Couldn't create instruments:", err)
+	}
+	ctx := context.Background()
+
+	tc.Set(ctx, "foo", "bar", cache.DefaultExpiration)
+	x, found := tc.Get(ctx, "foo")
+	if !found || x.(string) != "bar" {
+		t.Error("foo was not bar:", x)
+	}
+
+	tc.Delete(ctx, "foo")
+	if _, found := tc.Get(ctx, "foo"); found {
+		t.Error("foo was found, but it should have been deleted")
+	}
+
+	if s := tc.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", s.Hits, s.Misses)
+	}
+}
@@ -0,0 +1,234 @@
+// Package peer lets a fleet of processes share the work of loading values
+// into their caches, in the style of groupcache.
+//
+// Every key is owned by one peer, chosen by consistent hashing. When a Group
+// misses in its local cache, it asks the owner of the key for the value over
+// HTTP, and only the owner calls the Getter to load it from the origin.
+// Concurrent misses for the same key within a process are collapsed into one
+// load or fetch, so the origin sees roughly one load per key for the whole
+// fleet.
+package peer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// DefaultBasePath is the path under which groups are served by ServeHTTP.
+const DefaultBasePath = "/_peer/"
+
+// A Getter loads the value for key from the origin.
+type Getter func(ctx context.Context, key string) ([]byte, error)
+
+type Option func(*Group)
+
+// Use client to fetch values from peers, instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(g *Group) {
+		g.client = client
+	}
+}
+
+// Serve and fetch groups under path, instead of DefaultBasePath. The path
+// must be the same on every peer.
+func WithBasePath(path string) Option {
+	return func(g *Group) {
+		g.basePath = path
+	}
+}
+
+// Place every peer at n points on the consistent hash ring. The default is
+// 50.
+func WithReplicas(n int) Option {
+	return func(g *Group) {
+		g.replicas = n
+	}
+}
+
+// Store loaded and fetched values for d, instead of the cache's default
+// expiration.
+func WithExpiration(d time.Duration) Option {
+	return func(g *Group) {
+		g.expiration = d
+	}
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+// A Group is a named set of keys loaded by the same Getter and shared with
+// the groups of the same name on other peers.
+type Group struct {
+	name       string
+	c          *cache.Cache
+	self       string
+	getter     Getter
+	client     *http.Client
+	basePath   string
+	replicas   int
+	expiration time.Duration
+
+	mu    sync.RWMutex
+	ring  *Ring
+	calls map[string]*call
+}
+
+// Returns a group called name which caches values in c and loads them with
+// getter. self is this process's base URL, e.g. "http://10.0.0.1:8080", as
+// it appears in the peer list passed to SetPeers. Until SetPeers is called,
+// every key is loaded locally.
+func NewGroup(name string, c *cache.Cache, self string, getter Getter, opts ...Option) *Group {
+	g := &Group{
+		name:       name,
+		c:          c,
+		self:       self,
+		getter:     getter,
+		client:     http.DefaultClient,
+		basePath:   DefaultBasePath,
+		replicas:   50,
+		expiration: cache.DefaultExpiration,
+		calls:      make(map[string]*call),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.ring = NewRing(g.replicas)
+	return g
+}
+
+// Replace the set of peers sharing the group, each given by its base URL.
+// The list should include this process.
+func (g *Group) SetPeers(peers ...string) {
+	ring := NewRing(g.replicas, peers...)
+	g.mu.Lock()
+	g.ring = ring
+	g.mu.Unlock()
+}
+
+// Returns the peer owning key.
+func (g *Group) owner(key string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.ring.Get(key)
+}
+
+// Returns the value for key from the local cache, the peer owning key, or
+// the Getter, in that order. If the owner cannot be reached, the value is
+// loaded locally. A value in the cache which is not a []byte (e.g. one set
+// there by other code) is ignored and replaced.
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	if x, found := g.c.Get(key); found {
+		if v, ok := x.([]byte); ok {
+			return v, nil
+		}
+	}
+	return g.do(key, func() ([]byte, error) {
+		if owner := g.owner(key); owner != "" && owner != g.self {
+			if v, err := g.fetch(ctx, owner, key); err == nil {
+				return v, nil
+			}
+		}
+		return g.getter(ctx, key)
+	})
+}
+
+// Load key locally, for a request from a peer which believes this process
+// owns it.
+func (g *Group) load(ctx context.Context, key string) ([]byte, error) {
+	if x, found := g.c.Get(key); found {
+		if v, ok := x.([]byte); ok {
+			return v, nil
+		}
+	}
+	return g.do(key, func() ([]byte, error) {
+		return g.getter(ctx, key)
+	})
+}
+
+// Call fn once for all concurrent callers asking for key, storing the result
+// in the cache if it succeeds. If fn panics, every caller gets an error.
+func (g *Group) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if cl, found := g.calls[key]; found {
+		g.mu.Unlock()
+		cl.wg.Wait()
+		return cl.val, cl.err
+	}
+	cl := &call{}
+	cl.wg.Add(1)
+	g.calls[key] = cl
+	g.mu.Unlock()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				cl.val, cl.err = nil, fmt.Errorf("Loading %s panicked: %v", key, r)
+			}
+		}()
+		cl.val, cl.err = fn()
+	}()
+	if cl.err == nil {
+		g.c.Set(key, cl.val, g.expiration)
+	}
+	cl.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return cl.val, cl.err
+}
+
+func (g *Group) fetch(ctx context.Context, peer, key string) ([]byte, error) {
+	u := strings.TrimSuffix(peer, "/") + g.basePath + url.PathEscape(g.name) + "/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Peer %s returned %s for %s", peer, res.Status, key)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Serves the group's values to peers. The handler should be mounted at the
+// group's base path.
+func (g *Group) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.EscapedPath(), g.basePath)
+	name, key, ok := strings.Cut(p, "/")
+	if !ok || r.Method != http.MethodGet {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if name, _ = url.PathUnescape(name); name != g.name {
+		http.NotFound(w, r)
+		return
+	}
+	key, err := url.PathUnescape(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	v, err := g.load(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(v)
+}
@@ -0,0 +1,124 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func TestGroup(t *testing.T) {
+	var loads int32
+	getter := func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		if key == "bad" {
+			return nil, errors.New("bad key")
+		}
+		return []byte("value of " + key), nil
+	}
+
+	var groups []*Group
+	var urls []string
+	for i := 0; i < 3; i++ {
+		srv := httptest.NewUnstartedServer(nil)
+		url := "http://" + srv.Listener.Addr().String()
+		g := NewGroup("test", cache.New(), url, getter)
+		srv.Config.Handler = g
+		srv.Start()
+		defer srv.Close()
+		groups = append(groups, g)
+		urls = append(urls, url)
+	}
+	for _, g := range groups {
+		g.SetPeers(urls...)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		k := "key" + strconv.Itoa(i)
+		for _, g := range groups {
+			v, err := g.Get(ctx, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(v) != "value of "+k {
+				t.Errorf("Got %q for %s", v, k)
+			}
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 20 {
+		t.Errorf("Expected 20 loads across the fleet, got %d", n)
+	}
+
+	if _, err := groups[0].Get(ctx, "bad"); err == nil {
+		t.Error("Expected an error from the getter")
+	}
+}
+
+func TestGroupUnreachablePeer(t *testing.T) {
+	g := NewGroup("test", cache.New(), "http://self", func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	g.SetPeers("http://127.0.0.1:1")
+	v, err := g.Get(context.Background(), "foo")
+	if err != nil || string(v) != "foo" {
+		t.Error("Expected a local load when the owner is unreachable:", string(v), err)
+	}
+}
+
+func TestGroupNotBytes(t *testing.T) {
+	c := cache.New()
+	c.Set("foo", "not bytes", cache.DefaultExpiration)
+	g := NewGroup("test", c, "http://self", func(ctx context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	v, err := g.Get(context.Background(), "foo")
+	if err != nil || string(v) != "foo" {
+		t.Error("Expected a load for a value that is not a []byte:", string(v), err)
+	}
+}
+
+func TestGroupGetterPanic(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	g := NewGroup("test", cache.New(), "http://self", func(ctx context.Context, key string) ([]byte, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			panic("boom")
+		}
+		return []byte(key), nil
+	})
+	ctx := context.Background()
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.Get(ctx, "foo")
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := g.Get(ctx, "foo")
+		errs <- err
+	}()
+	<-time.After(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("Expected an error from a panicking getter")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Callers were not released after the getter panicked")
+		}
+	}
+	if v, err := g.Get(ctx, "foo"); err != nil || string(v) != "foo" {
+		t.Error("Expected a new load after the panic:", string(v), err)
+	}
+}
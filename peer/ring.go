@@ -0,0 +1,49 @@
+package peer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// A Ring assigns keys to peers using consistent hashing, so that adding or
+// removing a peer only moves the keys of its neighbours on the ring.
+type Ring struct {
+	replicas int
+	hashes   []uint32
+	peers    map[uint32]string
+}
+
+// Returns a ring in which every peer is placed at replicas points. More
+// replicas spread keys more evenly at the cost of memory.
+func NewRing(replicas int, peers ...string) *Ring {
+	if replicas < 1 {
+		replicas = 1
+	}
+	r := &Ring{
+		replicas: replicas,
+		peers:    make(map[uint32]string, replicas*len(peers)),
+	}
+	for _, p := range peers {
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + p))
+			r.hashes = append(r.hashes, h)
+			r.peers[h] = p
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Returns the peer owning key, or "" if the ring is empty.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.peers[r.hashes[i]]
+}
@@ -0,0 +1,34 @@
+package peer
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	if p := NewRing(10).Get("foo"); p != "" {
+		t.Error("Empty ring returned a peer:", p)
+	}
+
+	r := NewRing(50, "a", "b", "c")
+	owners := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		k := strconv.Itoa(i)
+		owners[k] = r.Get(k)
+		counts[owners[k]]++
+	}
+	for _, p := range []string{"a", "b", "c"} {
+		if counts[p] < 150 {
+			t.Errorf("Peer %s owns only %d of 1000 keys", p, counts[p])
+		}
+	}
+
+	// Removing a peer only moves its own keys.
+	r = NewRing(50, "a", "b")
+	for k, owner := range owners {
+		if owner != "c" && r.Get(k) != owner {
+			t.Errorf("Key %s moved from %s to %s", k, owner, r.Get(k))
+		}
+	}
+}
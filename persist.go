@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Persist the cache to path: New loads the snapshot at path, if it exists,
+// and Close writes a final one. With PersistInterval, snapshots are also
+// written periodically in the background.
+func PersistPath(path string) CacheOption {
+	return func(m *CacheOptions) error {
+		m.PersistPath = path
+		return nil
+	}
+}
+
+// Write a snapshot to the PersistPath every d.
+func PersistInterval(d time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.PersistInterval = d
+		return nil
+	}
+}
+
+// Write a snapshot of the cache to its PersistPath now. The snapshot is
+// written to a temporary file in the same directory, which then replaces the
+// old snapshot, so that a crash never leaves a partial snapshot behind. With
+// WAL, the log is compacted.
+func (c *cache) Persist() error {
+	if c.PersistPath == "" {
+		return fmt.Errorf("Cache has no PersistPath")
+	}
+	start := time.Now()
+	if c.wal != nil {
+		if err := c.wal.rotate(); err != nil {
+			return err
+		}
+	}
+	if err := c.saveFileAtomic(c.PersistPath); err != nil {
+		return err
+	}
+	if c.wal != nil {
+		os.Remove(c.walPath() + ".old")
+	}
+	c.debug("cache: persisted snapshot", "path", c.PersistPath, "duration", time.Since(start))
+	return nil
+}
+
+func (c *cache) saveFileAtomic(fname string) error {
+	dir, base := filepath.Split(fname)
+	if dir == "" {
+		dir = "."
+	}
+	fp, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := fp.Name()
+	if err = c.Save(fp); err == nil {
+		err = fp.Sync()
+	}
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, fname)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// Load the snapshot at the cache's PersistPath, if it exists. The items it
+// holds are already persisted, and nobody can be watching yet, so they are
+// loaded without notifying.
+func (c *cache) loadPersisted() error {
+	fp, err := os.Open(c.PersistPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.debug("cache: no snapshot to load", "path", c.PersistPath)
+		return nil
+	}
+	if err == nil {
+		err = c.load(fp, SkipExisting, false)
+		fp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("Couldn't load %s: %w", c.PersistPath, err)
+	}
+	c.debug("cache: loaded snapshot", "path", c.PersistPath, "items", c.itemCount())
+	return nil
+}
+
+type persister struct {
+	ticker Ticker
+	stop   chan bool
+	exited chan struct{} // closed when Run returns
+}
+
+func (p *persister) Run(c *cache) {
+	defer close(p.exited)
+	ticker := p.ticker
+	for {
+		select {
+		case <-ticker.C():
+			// There is nobody to report the error to but the log; the
+			// next run or Close will try again.
+			if err := c.Persist(); err != nil {
+				c.warn("cache: couldn't persist snapshot", "path", c.PersistPath, "err", err)
+			}
+		case <-p.stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func runPersister(c *cache, interval time.Duration) {
+	// The ticker is created here rather than in Run, so that with a
+	// FakeClock it exists as soon as the cache is returned.
+	p := &persister{
+		ticker: c.newTicker(interval),
+		stop:   make(chan bool),
+		exited: make(chan struct{}),
+	}
+	c.persister = p
+	go p.Run(c)
+}
+
+// Store the time remaining until each item expires in snapshots, instead of
+// its absolute expiration time. Load then rebases the expirations on the time
+// the snapshot is loaded, so that items don't expire while the cache isn't
+// running, and clock differences between the saving and loading hosts don't
+// matter. Load handles snapshots of either kind regardless of the option.
+func RelativeTTL(b bool) CacheOption {
+	return func(m *CacheOptions) error {
+		m.RelativeTTL = b
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	tc := New(Expiration(DefaultExpiration), PersistPath(path))
+	if tc == nil {
+		t.Fatal("Couldn't create a cache with a missing snapshot")
+	}
+	tc.Set("foo", "bar", DefaultExpiration)
+	if err := tc.Close(); err != nil {
+		t.Fatal("Close didn't write a snapshot:", err)
+	}
+
+	oc := New(Expiration(DefaultExpiration), PersistPath(path))
+	if x, found := oc.Get("foo"); !found || x != "bar" {
+		t.Error("Snapshot was not loaded by New:", x, found)
+	}
+
+	os.WriteFile(path, []byte("garbage"), 0644)
+	if _, err := NewWithError(PersistPath(path)); err == nil {
+		t.Error("Expected an error loading a corrupt snapshot")
+	}
+	if _, err := NewWithError(PersistInterval(time.Second)); err == nil {
+		t.Error("Expected an error for PersistInterval without PersistPath")
+	}
+	if err := New().Persist(); err == nil {
+		t.Error("Expected an error from Persist without PersistPath")
+	}
+}
+
+func TestPersistInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.snap")
+	clock := NewFakeClock(time.Now())
+	tc := New(Expiration(DefaultExpiration), PersistPath(path), PersistInterval(time.Minute), WithClock(clock))
+	defer tc.Close()
+	tc.Set("foo", "bar", DefaultExpiration)
+
+	clock.Advance(time.Minute)
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	oc := New(PersistPath(path))
+	if x, found := oc.Get("foo"); !found || x != "bar" {
+		t.Error("Snapshot was not written in the background:", x, found)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Error("Temporary files were left behind:", entries)
+	}
+}
+
+func TestRelativeTTL(t *testing.T) {
+	fc := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), RelativeTTL(true), WithClock(fc))
+	tc.Set("a", 1, 10*time.Minute)
+	tc.Set("b", 2, NoExpiration)
+	tc.Set("expired", 3, time.Second)
+	fc.Advance(2 * time.Second)
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Load 20 minutes later, without the option.
+	later := NewFakeClock(time.Unix(1000, 0).Add(20 * time.Minute))
+	oc := New(Expiration(DefaultExpiration), WithClock(later))
+	if err := oc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	_, exp, found := oc.GetWithExpiration("a")
+	if want := later.Now().Add(10*time.Minute - 2*time.Second); !found || exp.Sub(want) > time.Microsecond || want.Sub(exp) > time.Microsecond {
+		t.Error("a was not rebased on the load time:", exp, found)
+	}
+	if _, exp, found := oc.GetWithExpiration("b"); !found || !exp.IsZero() {
+		t.Error("b should never expire:", exp, found)
+	}
+	if _, found := oc.Get("expired"); found {
+		t.Error("Expired item was saved")
+	}
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Keys exempt from eviction. The zero value is ready to use.
+type pinSet struct {
+	mu   sync.RWMutex
+	keys map[string]struct{}
+}
+
+// Exempt the item with key k from eviction by DeleteLRU and DeleteLRUAmount
+// (and therefore by the janitor), even if the cache is over its size limit.
+// The item still expires, and can still be deleted explicitly.
+//
+// The pin belongs to the key rather than to the current item, so it applies
+// to items set under k later, and remains until Unpin is called.
+func (c *cache) Pin(k string) {
+	c.pins.mu.Lock()
+	if c.pins.keys == nil {
+		c.pins.keys = make(map[string]struct{})
+	}
+	c.pins.keys[k] = struct{}{}
+	c.pins.mu.Unlock()
+}
+
+// Make the item with key k subject to eviction again.
+func (c *cache) Unpin(k string) {
+	c.pins.mu.Lock()
+	delete(c.pins.keys, k)
+	c.pins.mu.Unlock()
+}
+
+// Reports whether k has been pinned.
+func (c *cache) Pinned(k string) bool {
+	c.pins.mu.RLock()
+	_, ok := c.pins.keys[k]
+	c.pins.mu.RUnlock()
+	return ok
+}
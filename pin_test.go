@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), WithClock(clock))
+	tc.Set("flags", 1, DefaultExpiration)
+	tc.Pin("flags")
+	clock.Advance(time.Millisecond)
+	tc.Set("b", 2, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("c", 3, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("d", 4, DefaultExpiration)
+
+	if n := tc.DeleteLRU(); n != 2 {
+		t.Error("DeleteLRU deleted", n, "items instead of 2")
+	}
+	if _, found := tc.Get("flags"); !found {
+		t.Error("the pinned item was evicted")
+	}
+	if _, found := tc.Get("d"); !found {
+		t.Error("d was evicted instead of b and c")
+	}
+
+	tc.Unpin("flags")
+	if tc.Pinned("flags") {
+		t.Error("flags is still pinned after Unpin")
+	}
+	clock.Advance(time.Millisecond)
+	tc.Get("d")
+	tc.Set("e", 5, DefaultExpiration)
+	tc.DeleteLRU()
+	if _, found := tc.Get("flags"); found {
+		t.Error("flags was not evicted after Unpin")
+	}
+}
+
+func TestPinnedItemsStillExpire(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.Pin("a")
+	tc.Set("a", 1, time.Second)
+	tc.Set("b", 2, DefaultExpiration)
+	clock.Advance(2 * time.Second)
+	if n := tc.DeleteExpired(); n != 1 {
+		t.Error("DeleteExpired deleted", n, "items instead of 1")
+	}
+	tc.Pin("b")
+	tc.Delete("b")
+	if _, found := tc.Get("b"); found {
+		t.Error("Delete did not delete the pinned item")
+	}
+}
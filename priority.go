@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// The priority of an item decides which items are evicted first when the
+// cache is over its size limit: all LowPriority items are evicted before any
+// NormalPriority item, which are all evicted before any HighPriority item,
+// regardless of the eviction policy. Within a priority, the policy decides.
+type Priority int8
+
+const (
+	LowPriority    Priority = -1
+	NormalPriority Priority = 0
+	HighPriority   Priority = 1
+)
+
+// Add an item to the cache with the given priority, replacing any existing
+// item. Items added in any other way have NormalPriority.
+func (c *cache) SetWithPriority(k string, x interface{}, p Priority, d time.Duration) {
+	c.setItem(k, x, d, Item{Priority: p})
+}
+
+// Move lower priority candidates before higher priority ones, keeping the
+// order chosen by the policy within each priority.
+func orderByPriority(candidates []Candidate) {
+	mixed := false
+	for _, v := range candidates {
+		if v.Item.Priority != NormalPriority {
+			mixed = true
+			break
+		}
+	}
+	if !mixed {
+		return
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Item.Priority < candidates[j].Item.Priority
+	})
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), CacheSize(2), WithClock(clock))
+	tc.SetWithPriority("expensive", 1, HighPriority, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.Set("normal", 2, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.SetWithPriority("cheap1", 3, LowPriority, DefaultExpiration)
+	clock.Advance(time.Millisecond)
+	tc.SetWithPriority("cheap2", 4, LowPriority, DefaultExpiration)
+
+	keys := tc.DeleteLRUKeys()
+	if len(keys) != 2 || keys[0] != "cheap1" || keys[1] != "cheap2" {
+		t.Error("evicted", keys, "instead of [cheap1 cheap2]")
+	}
+
+	clock.Advance(time.Millisecond)
+	tc.Set("newer", 5, DefaultExpiration)
+	keys = tc.DeleteLRUKeys()
+	if len(keys) != 1 || keys[0] != "normal" {
+		t.Error("evicted", keys, "instead of [normal]")
+	}
+}
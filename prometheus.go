@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type collector struct {
+	c *Cache
+
+	items      *prometheus.Desc
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	evictions  *prometheus.Desc
+	janitorRun *prometheus.Desc
+	size       *prometheus.Desc
+}
+
+// Returns a prometheus.Collector exposing the item count, hit, miss and
+// eviction counters, the duration of the last janitor run, and the estimated
+// memory usage of c. Every metric carries a "cache" label set to name, so
+// that several caches can be registered in the same process.
+//
+// Collecting the item count and memory estimate requires a pass over every
+// item in the cache, so scrape intervals should not be too short for very
+// large caches.
+func Collector(c *Cache, name string) prometheus.Collector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("go_cache", "", metric), help, nil, labels)
+	}
+	return &collector{
+		c:          c,
+		items:      desc("items", "Number of items in the cache, including expired items that have not yet been cleaned up."),
+		hits:       desc("hits_total", "Number of lookups that found an unexpired item."),
+		misses:     desc("misses_total", "Number of lookups that did not find an unexpired item."),
+		evictions:  desc("evictions_total", "Number of items removed because they expired or the cache was over its size limit."),
+		janitorRun: desc("janitor_run_duration_seconds", "Duration of the most recent janitor run."),
+		size:       desc("estimated_size_bytes", "Estimated memory used by the items in the cache."),
+	}
+}
+
+func (col *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- col.items
+	ch <- col.hits
+	ch <- col.misses
+	ch <- col.evictions
+	ch <- col.janitorRun
+	ch <- col.size
+}
+
+func (col *collector) Collect(ch chan<- prometheus.Metric) {
+	s := col.c.Stats()
+	ch <- prometheus.MustNewConstMetric(col.items, prometheus.GaugeValue, float64(col.c.ItemCount()))
+	ch <- prometheus.MustNewConstMetric(col.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(col.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(col.evictions, prometheus.CounterValue, float64(s.Evictions))
+	ch <- prometheus.MustNewConstMetric(col.janitorRun, prometheus.GaugeValue, s.JanitorRunDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(col.size, prometheus.GaugeValue, float64(col.c.estimatedSize()))
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("foo", "bar", DefaultExpiration)
+	tc.Get("foo")
+
+	col := Collector(tc, "test")
+
+	descs := make(chan *prometheus.Desc, 10)
+	col.Describe(descs)
+	close(descs)
+	nd := 0
+	for range descs {
+		nd++
+	}
+
+	metrics := make(chan prometheus.Metric, 10)
+	col.Collect(metrics)
+	close(metrics)
+	nm := 0
+	for range metrics {
+		nm++
+	}
+
+	if nd != 6 || nm != 6 {
+		t.Errorf("Expected 6 descriptions and 6 metrics, got %d and %d", nd, nm)
+	}
+}
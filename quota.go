@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"strings"
+)
+
+// A PrefixQuota limits the items whose keys start with Prefix to MaxItems
+// items and MaxBytes bytes (as estimated for MaxBytes); either may be 0 for no
+// limit.
+type PrefixQuota struct {
+	Prefix   string
+	MaxItems int
+	MaxBytes int64
+}
+
+// Limit the items whose keys start with prefix to maxItems items and maxBytes
+// bytes, e.g. Quota("sessions:", 100000, 0), so that the keys of one tenant or
+// kind of data cannot crowd out everyone else's. Either limit may be 0. Like
+// CacheSize, quotas are enforced by DeleteLRU (and the janitor), which evicts
+// the items of a prefix that is over its quota according to the eviction
+// policy, independently of the cache's other limits. Prefixes may overlap;
+// an item counts towards every quota whose prefix it has. To give a Namespace
+// a quota, use its name followed by a colon as the prefix.
+func Quota(prefix string, maxItems int, maxBytes int64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Quotas = append(m.Quotas, PrefixQuota{prefix, maxItems, maxBytes})
+		return nil
+	}
+}
+
+// Evict items until those with the prefix of q are within its limits.
+func (c *cache) deleteQuota(q PrefixQuota) ([]KeyValue, []string) {
+	var (
+		evicted []KeyValue
+		keys    []string
+	)
+	trim := func(max int64, weigh func(k string, v Item) int64) {
+		candidates, total := c.evictionCandidates(func(k string, v Item) int64 {
+			if !strings.HasPrefix(k, q.Prefix) {
+				return 0
+			}
+			return weigh(k, v)
+		})
+		n := 0
+		for _, v := range candidates {
+			if strings.HasPrefix(v.Key, q.Prefix) {
+				candidates[n] = v
+				n++
+			}
+		}
+		e, k := c.trimCandidates(candidates[:n], total, max)
+		evicted = append(evicted, e...)
+		keys = append(keys, k...)
+	}
+	if q.MaxItems > 0 {
+		trim(int64(q.MaxItems), func(string, Item) int64 { return 1 })
+	}
+	if q.MaxBytes > 0 {
+		trim(q.MaxBytes, func(k string, v Item) int64 { return estimateSize(k, v.Object) })
+	}
+	return evicted, keys
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock),
+		Quota("sessions:", 3, 0),
+		Quota("thumbs:", 0, 2*(itemOverhead+8+100)))
+	for i := 0; i < 5; i++ {
+		tc.Set("sessions:"+strconv.Itoa(i), i, DefaultExpiration)
+		tc.Set("thumbs:"+strconv.Itoa(i), strings.Repeat("x", 100), DefaultExpiration)
+		tc.Set("other:"+strconv.Itoa(i), i, DefaultExpiration)
+		clock.Advance(time.Millisecond)
+	}
+	if n := tc.DeleteLRU(); n != 5 {
+		t.Errorf("evicted %d items; want 5", n)
+	}
+	for _, k := range []string{"sessions:2", "sessions:3", "sessions:4", "thumbs:3", "thumbs:4", "other:0"} {
+		if _, found := tc.Get(k); !found {
+			t.Errorf("%s was evicted", k)
+		}
+	}
+	if n := tc.Namespace("other").ItemCount(); n != 5 {
+		t.Errorf("%d items left without a quota; want 5", n)
+	}
+	if _, err := NewWithError(Quota("x:", -1, 0)); err == nil {
+		t.Error("negative quota accepted")
+	}
+}
@@ -0,0 +1,105 @@
+// Package ratelimit provides rate limiters keeping their counts in a
+// cache.Cache, so that the cache's expiration takes care of forgetting idle
+// keys.
+//
+// A fixed-window limiter counts the requests for a key in consecutive windows
+// of the given length, and allows up to limit of them per window. It is cheap,
+// but allows bursts of up to twice the limit around the boundary between two
+// windows. A sliding-window limiter avoids that by weighting the count of the
+// previous window by how much of it still overlaps the sliding window ending
+// now, at the cost of one more lookup per request.
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+type Option func(*Limiter)
+
+// Prefix every key with prefix, so that several limiters can share a cache.
+func KeyPrefix(prefix string) Option {
+	return func(l *Limiter) {
+		l.prefix = prefix
+	}
+}
+
+// Use clock to determine the current window instead of the system clock. It
+// should be the cache's clock (see cache.WithClock).
+func WithClock(clock cache.Clock) Option {
+	return func(l *Limiter) {
+		l.clock = clock
+	}
+}
+
+// A Limiter decides whether requests for a key are within a rate limit. It is
+// safe for concurrent use.
+type Limiter struct {
+	c       *cache.Cache
+	sliding bool
+	prefix  string
+	clock   cache.Clock
+}
+
+// Returns a fixed-window limiter keeping its counts in c.
+func NewFixedWindow(c *cache.Cache, opts ...Option) *Limiter {
+	return newLimiter(c, false, opts)
+}
+
+// Returns a sliding-window limiter keeping its counts in c.
+func NewSlidingWindow(c *cache.Cache, opts ...Option) *Limiter {
+	return newLimiter(c, true, opts)
+}
+
+func newLimiter(c *cache.Cache, sliding bool, opts []Option) *Limiter {
+	l := &Limiter{c: c, sliding: sliding}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *Limiter) now() time.Time {
+	if l.clock != nil {
+		return l.clock.Now()
+	}
+	return time.Now()
+}
+
+// The key of the counter for the window starting at start.
+func (l *Limiter) key(key string, start time.Time) string {
+	return l.prefix + key + "\x00" + strconv.FormatInt(start.UnixNano(), 10)
+}
+
+// Reports whether another request for key is allowed if at most limit
+// requests are allowed per window, and if so, counts it.
+func (l *Limiter) Allow(key string, limit int, window time.Duration) bool {
+	if limit <= 0 || window <= 0 {
+		return false
+	}
+	now := l.now()
+	start := now.Truncate(window)
+	if !l.sliding {
+		n := l.c.IncrementCounter(l.key(key, start), 1, start.Add(window).Sub(now))
+		return n <= int64(limit)
+	}
+
+	// Counts are kept for two windows, as the next window needs this one's.
+	k := l.key(key, start)
+	n := l.c.IncrementCounter(k, 1, start.Add(2*window).Sub(now))
+	var prev int64
+	if x, found := l.c.Get(l.key(key, start.Add(-window))); found {
+		if ctr, ok := x.(*cache.Counter); ok {
+			prev = ctr.Load()
+		}
+	}
+	overlap := 1 - float64(now.Sub(start))/float64(window)
+	if float64(prev)*overlap+float64(n) > float64(limit) {
+		// Denied requests do not count towards the limit.
+		l.c.IncrementCounter(k, -1, start.Add(2*window).Sub(now))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+func TestFixedWindow(t *testing.T) {
+	clock := cache.NewFakeClock(time.Unix(0, 0))
+	c := cache.New(cache.Expiration(cache.DefaultExpiration), cache.WithClock(clock))
+	l := NewFixedWindow(c, WithClock(clock))
+	for i := 0; i < 3; i++ {
+		if !l.Allow("k", 3, time.Second) {
+			t.Error("request", i, "was denied")
+		}
+	}
+	if l.Allow("k", 3, time.Second) {
+		t.Error("fourth request was allowed")
+	}
+	if !l.Allow("other", 3, time.Second) {
+		t.Error("request for another key was denied")
+	}
+	clock.Advance(time.Second)
+	if !l.Allow("k", 3, time.Second) {
+		t.Error("request in the next window was denied")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := cache.NewFakeClock(time.Unix(0, 0))
+	c := cache.New(cache.Expiration(cache.DefaultExpiration), cache.WithClock(clock))
+	l := NewSlidingWindow(c, WithClock(clock), KeyPrefix("rl:"))
+	clock.Advance(900 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		if !l.Allow("k", 4, time.Second) {
+			t.Error("request", i, "was denied")
+		}
+	}
+	if l.Allow("k", 4, time.Second) {
+		t.Error("fifth request was allowed")
+	}
+
+	// A quarter into the next window, 3 of the previous 4 requests still
+	// count.
+	clock.Advance(350 * time.Millisecond)
+	if !l.Allow("k", 4, time.Second) {
+		t.Error("request after the boundary was denied")
+	}
+	if l.Allow("k", 4, time.Second) {
+		t.Error("burst after the boundary was allowed")
+	}
+
+	clock.Advance(2 * time.Second)
+	if !l.Allow("k", 4, time.Second) {
+		t.Error("request after two windows was denied")
+	}
+}
+
+func TestExpiredCountsAreDeleted(t *testing.T) {
+	clock := cache.NewFakeClock(time.Unix(0, 0))
+	c := cache.New(cache.Expiration(cache.DefaultExpiration), cache.WithClock(clock))
+	l := NewFixedWindow(c, WithClock(clock))
+	l.Allow("k", 1, time.Second)
+	clock.Advance(2 * time.Second)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Error("DeleteExpired deleted", n, "counts instead of 1")
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// A KeyAccess is the key of an item and the time it was last accessed, as
+// returned by LeastRecentlyUsed and MostRecentlyUsed.
+type KeyAccess struct {
+	Key      string
+	Accessed time.Time
+}
+
+// A heap of KeyAccesses whose root is the one to be replaced first: the most
+// recently used for LeastRecentlyUsed, and the least recently used for
+// MostRecentlyUsed.
+type accessHeap struct {
+	keys   []KeyAccess
+	oldest bool
+}
+
+func (h *accessHeap) Len() int { return len(h.keys) }
+func (h *accessHeap) Less(i, j int) bool {
+	if h.oldest {
+		return h.keys[i].Accessed.After(h.keys[j].Accessed)
+	}
+	return h.keys[i].Accessed.Before(h.keys[j].Accessed)
+}
+func (h *accessHeap) Swap(i, j int)      { h.keys[i], h.keys[j] = h.keys[j], h.keys[i] }
+func (h *accessHeap) Push(x interface{}) { h.keys = append(h.keys, x.(KeyAccess)) }
+func (h *accessHeap) Pop() interface{} {
+	x := h.keys[len(h.keys)-1]
+	h.keys = h.keys[:len(h.keys)-1]
+	return x
+}
+
+// Returns the keys of the n unexpired items that were accessed the longest
+// time ago, with their Accessed times, least recently used first. In a cache
+// evicting by LRU, these are the items that will be evicted next (unless they
+// are pinned, or have a priority.) Accessed times are only tracked in caches
+// with a size limit, or with TrackAccess; otherwise the items are in no
+// particular order.
+func (c *cache) LeastRecentlyUsed(n int) []KeyAccess {
+	return c.byRecency(n, true)
+}
+
+// Like LeastRecentlyUsed, but returns the n most recently used items, most
+// recently used first.
+func (c *cache) MostRecentlyUsed(n int) []KeyAccess {
+	return c.byRecency(n, false)
+}
+
+func (c *cache) byRecency(n int, oldest bool) []KeyAccess {
+	if n <= 0 {
+		return nil
+	}
+	h := &accessHeap{keys: make([]KeyAccess, 0, n), oldest: oldest}
+	now := c.now().UnixNano()
+	c.items.Range(func(key, value interface{}) bool {
+		v := itemOf(value)
+		// "Inlining" of Expired
+		if v.Expiration > 0 && now > v.Expiration {
+			return true
+		}
+		ka := KeyAccess{key.(string), time.Unix(0, v.Accessed)}
+		if h.Len() < n {
+			heap.Push(h, ka)
+		} else if h.belongs(ka) {
+			h.keys[0] = ka
+			heap.Fix(h, 0)
+		}
+		return true
+	})
+	sort.Slice(h.keys, func(i, j int) bool {
+		if oldest {
+			return h.keys[i].Accessed.Before(h.keys[j].Accessed)
+		}
+		return h.keys[i].Accessed.After(h.keys[j].Accessed)
+	})
+	return h.keys
+}
+
+// Reports whether ka belongs in the result instead of the root of the heap.
+func (h *accessHeap) belongs(ka KeyAccess) bool {
+	if h.oldest {
+		return ka.Accessed.Before(h.keys[0].Accessed)
+	}
+	return ka.Accessed.After(h.keys[0].Accessed)
+}
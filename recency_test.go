@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLeastRecentlyUsed(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), TrackAccess(true))
+	for i := 0; i < 10; i++ {
+		tc.Set(fmt.Sprint(i), i, DefaultExpiration)
+		clock.Advance(time.Second)
+	}
+	tc.Get("0")
+	keys := func(ks []KeyAccess) string {
+		s := ""
+		for _, k := range ks {
+			s += k.Key
+		}
+		return s
+	}
+	lru := tc.LeastRecentlyUsed(3)
+	if got := keys(lru); got != "123" {
+		t.Errorf("LeastRecentlyUsed(3) returned %s; want 123", got)
+	}
+	if !lru[0].Accessed.Equal(time.Unix(1001, 0)) {
+		t.Errorf("1 was accessed at %v; want %v", lru[0].Accessed, time.Unix(1001, 0))
+	}
+	if got := keys(tc.MostRecentlyUsed(3)); got != "098" {
+		t.Errorf("MostRecentlyUsed(3) returned %s; want 098", got)
+	}
+	if n := len(tc.LeastRecentlyUsed(100)); n != 10 {
+		t.Errorf("LeastRecentlyUsed(100) returned %d keys; want 10", n)
+	}
+	if ks := tc.MostRecentlyUsed(0); ks != nil {
+		t.Errorf("MostRecentlyUsed(0) returned %v", ks)
+	}
+}
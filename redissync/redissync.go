@@ -0,0 +1,198 @@
+// Package redissync keeps several instances of a cache.Cache loosely
+// coherent using Redis pub/sub.
+//
+// Sets and deletes made through a Syncer are applied to the local cache and
+// then published on a Redis channel. Every other Syncer subscribed to the
+// channel applies the change to its own cache: by default, a published Set
+// simply invalidates the key on peers, so that they fetch the new value from
+// the source of truth the next time they need it. If a Codec is configured,
+// values are shipped with the message and peers store them directly.
+//
+// Delivery is best effort, as with any Redis pub/sub channel: messages
+// published while a peer is disconnected are lost, so items should still
+// have a sensible expiration.
+package redissync
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+// A Codec converts values to and from the bytes sent to peers.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+type Option func(*Syncer)
+
+// Ship values to peers using codec, instead of invalidating the key.
+func WithCodec(codec Codec) Option {
+	return func(s *Syncer) {
+		s.codec = codec
+	}
+}
+
+// Identify this instance as node, instead of a randomly generated ID. Node IDs
+// must be unique among the instances sharing a channel.
+func WithNodeID(node string) Option {
+	return func(s *Syncer) {
+		s.node = node
+	}
+}
+
+// Called with errors that occur while applying messages received from peers.
+// By default they are ignored.
+func WithErrorHandler(fn func(error)) Option {
+	return func(s *Syncer) {
+		s.onError = fn
+	}
+}
+
+const (
+	opSet    = "set"
+	opDelete = "delete"
+)
+
+type message struct {
+	Node  string `json:"node"`
+	Op    string `json:"op"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+	// Absolute expiration in UnixNano, or 0 for none.
+	Expiration int64 `json:"exp,omitempty"`
+}
+
+// Syncer applies changes to a cache and publishes them to its peers.
+type Syncer struct {
+	c       *cache.Cache
+	client  redis.UniversalClient
+	channel string
+	node    string
+	codec   Codec
+	onError func(error)
+}
+
+// Returns a Syncer that keeps c in sync with the peers publishing on the given
+// Redis channel. Run must be called to receive changes from peers.
+func New(c *cache.Cache, client redis.UniversalClient, channel string, opts ...Option) *Syncer {
+	s := &Syncer{
+		c:       c,
+		client:  client,
+		channel: channel,
+		onError: func(error) {},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.node == "" {
+		var b [8]byte
+		rand.Read(b[:])
+		s.node = hex.EncodeToString(b[:])
+	}
+	return s
+}
+
+// Returns the ID identifying this instance to its peers.
+func (s *Syncer) NodeID() string {
+	return s.node
+}
+
+// Add an item to the local cache, replacing any existing item, and publish
+// the change to peers.
+func (s *Syncer) Set(ctx context.Context, k string, x interface{}, d time.Duration) error {
+	s.c.Set(k, x, d)
+	msg := message{Op: opSet, Key: k}
+	if s.codec != nil {
+		v, err := s.codec.Marshal(x)
+		if err != nil {
+			// The local Set has happened, so peers must at least drop
+			// their old value.
+			if perr := s.publish(ctx, message{Op: opDelete, Key: k}); perr != nil {
+				return perr
+			}
+			return err
+		}
+		msg.Value = v
+		if _, exp, found := s.c.GetWithExpiration(k); found && !exp.IsZero() {
+			msg.Expiration = exp.UnixNano()
+		}
+	}
+	return s.publish(ctx, msg)
+}
+
+// Delete an item from the local cache and from the caches of all peers.
+func (s *Syncer) Delete(ctx context.Context, k string) error {
+	s.c.Delete(k)
+	return s.publish(ctx, message{Op: opDelete, Key: k})
+}
+
+func (s *Syncer) publish(ctx context.Context, msg message) error {
+	msg.Node = s.node
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, s.channel, b).Err()
+}
+
+// Subscribe to the channel and apply the changes published by peers until ctx
+// is done. Run returns an error if the subscription could not be established,
+// and nil once ctx is done.
+func (s *Syncer) Run(ctx context.Context) error {
+	sub := s.client.Subscribe(ctx, s.channel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := s.handle(m.Payload); err != nil {
+				s.onError(err)
+			}
+		}
+	}
+}
+
+func (s *Syncer) handle(payload string) error {
+	var msg message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return err
+	}
+	if msg.Node == s.node {
+		return nil
+	}
+	if msg.Op != opSet || s.codec == nil || msg.Value == nil {
+		s.c.Delete(msg.Key)
+		return nil
+	}
+	x, err := s.codec.Unmarshal(msg.Value)
+	if err != nil {
+		s.c.Delete(msg.Key)
+		return err
+	}
+	d := cache.NoExpiration
+	if msg.Expiration > 0 {
+		d = time.Until(time.Unix(0, msg.Expiration))
+		if d <= 0 {
+			s.c.Delete(msg.Key)
+			return nil
+		}
+	}
+	s.c.Set(msg.Key, x, d)
+	return nil
+}
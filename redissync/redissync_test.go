@@ -0,0 +1,139 @@
+package redissync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	cache "github.com/lkwd/go-cache"
+)
+
+type fakeClient struct {
+	redis.UniversalClient
+	published []string
+}
+
+func (f *fakeClient) Publish(ctx context.Context, channel string, msg interface{}) *redis.IntCmd {
+	f.published = append(f.published, string(msg.([]byte)))
+	return &redis.IntCmd{}
+}
+
+type intCodec struct{}
+
+func (intCodec) Marshal(v interface{}) ([]byte, error) {
+	i, ok := v.(int)
+	if !ok {
+		return nil, fmt.Errorf("not an int: %v", v)
+	}
+	return []byte(strconv.Itoa(i)), nil
+}
+
+func (intCodec) Unmarshal(data []byte) (interface{}, error) {
+	return strconv.Atoi(string(data))
+}
+
+func TestInvalidate(t *testing.T) {
+	client := &fakeClient{}
+	local := cache.New()
+	a := New(local, client, "ch", WithNodeID("a"))
+	remote := cache.New()
+	b := New(remote, client, "ch", WithNodeID("b"))
+
+	remote.Set("foo", 1, cache.DefaultExpiration)
+	if err := a.Set(context.Background(), "foo", 2, cache.DefaultExpiration); err != nil {
+		t.Fatal(err)
+	}
+	if x, found := local.Get("foo"); !found || x != 2 {
+		t.Error("Set did not update the local cache:", x, found)
+	}
+	if len(client.published) != 1 {
+		t.Fatal("Expected one published message, got", len(client.published))
+	}
+
+	// A node ignores its own messages.
+	if err := a.handle(client.published[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := local.Get("foo"); !found {
+		t.Error("Node applied its own message")
+	}
+
+	if err := b.handle(client.published[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := remote.Get("foo"); found {
+		t.Error("Set did not invalidate the key on a peer")
+	}
+}
+
+func TestCodec(t *testing.T) {
+	client := &fakeClient{}
+	a := New(cache.New(), client, "ch", WithCodec(intCodec{}))
+	remote := cache.New()
+	b := New(remote, client, "ch", WithCodec(intCodec{}))
+	if a.NodeID() == b.NodeID() {
+		t.Fatal("Generated node IDs are not unique")
+	}
+
+	ctx := context.Background()
+	a.Set(ctx, "foo", 42, time.Hour)
+	a.Set(ctx, "bar", 1, cache.NoExpiration)
+	a.Delete(ctx, "bar")
+	for _, m := range client.published {
+		if err := b.handle(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	x, exp, found := remote.GetWithExpiration("foo")
+	if !found || x != 42 {
+		t.Error("Set did not ship the value to a peer:", x, found)
+	}
+	if until := time.Until(exp); until < 59*time.Minute || until > time.Hour {
+		t.Error("Expiration was not shipped to a peer:", exp)
+	}
+	if _, found := remote.Get("bar"); found {
+		t.Error("Delete was not applied on a peer")
+	}
+}
+
+func TestCodecErrorInvalidates(t *testing.T) {
+	client := &fakeClient{}
+	a := New(cache.New(), client, "ch", WithCodec(intCodec{}), WithNodeID("a"))
+	remote := cache.New()
+	b := New(remote, client, "ch", WithCodec(intCodec{}), WithNodeID("b"))
+
+	remote.Set("foo", 1, cache.DefaultExpiration)
+	if err := a.Set(context.Background(), "foo", "not an int", cache.DefaultExpiration); err == nil {
+		t.Fatal("Set of a value the codec can't marshal returned no error")
+	}
+	for _, m := range client.published {
+		if err := b.handle(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, found := remote.Get("foo"); found {
+		t.Error("A failed marshal left the old value on a peer")
+	}
+}
+
+func TestBadMessage(t *testing.T) {
+	remote := cache.New()
+	remote.Set("foo", 1, cache.DefaultExpiration)
+	b := New(remote, &fakeClient{}, "ch", WithCodec(intCodec{}))
+	if err := b.handle("not json"); err == nil {
+		t.Error("Expected an error for a malformed message")
+	}
+	msg, _ := json.Marshal(message{Node: "a", Op: opSet, Key: "foo", Value: []byte("x")})
+	if err := b.handle(string(msg)); err == nil {
+		t.Error("Expected an error for an undecodable value")
+	}
+	if _, found := remote.Get("foo"); found {
+		t.Error("Key was not invalidated after an undecodable value")
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The fraction of an item's lifetime before its expiration during which Get
+// refreshes items set with SetWithRefresh, unless RefreshAhead is given.
+const defaultRefreshAhead = 0.2
+
+// A function recomputing the value of k for SetWithRefresh.
+type RefreshFunc func(k string) (interface{}, error)
+
+type refresher struct {
+	fn       RefreshFunc
+	d        time.Duration
+	version  uint64 // of the item fn refreshes
+	at       int64  // when Get starts refreshing the item
+	inflight bool
+}
+
+// The refresh functions of items set with SetWithRefresh, by key. The zero
+// value is ready to use.
+type refreshTable struct {
+	used uint32
+	mu   sync.Mutex
+	keys map[string]*refresher
+}
+
+func (t *refreshTable) inUse() bool {
+	return atomic.LoadUint32(&t.used) == 1
+}
+
+// Refresh items set with SetWithRefresh when they are retrieved during the
+// given fraction of their lifetime before they expire, e.g. during the last
+// 10% of it for 0.1. The default is 0.2.
+func RefreshAhead(fraction float64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.RefreshAhead = fraction
+		return nil
+	}
+}
+
+// Add an item to the cache, replacing any existing item, like Set, and keep it
+// fresh with refresh: when the item is retrieved shortly before it expires
+// (see RefreshAhead), refresh is called in a new goroutine, and the value it
+// returns replaces the item with the same duration and refresh function. The
+// caller that triggered the refresh gets the current value without waiting.
+// Only one refresh of an item runs at a time. If refresh returns an error,
+// the item is left as it is, and the next retrieval tries again.
+//
+// Items that are never retrieved are not refreshed, and expire as usual, so
+// only the keys in use are kept warm. The refresh function is dropped when
+// the item is replaced by any other method, or deleted. SetWithRefresh is
+// like Set if d is NoExpiration, or if it is DefaultExpiration and the cache
+// has no default expiration.
+func (c *cache) SetWithRefresh(k string, x interface{}, d time.Duration, refresh RefreshFunc) {
+	if c.isClosed() {
+		return
+	}
+	if d == DefaultExpiration {
+		d = c.defaultExpiration(k)
+	}
+	c.mu.Lock()
+	c.set(k, x, d)
+	item, found := c.getItem(k)
+	c.mu.Unlock()
+	c.refreshWith(k, item, found, d, refresh)
+}
+
+// Register refresh as the refresh function of item, just set under k with the
+// duration d.
+func (c *cache) refreshWith(k string, item Item, found bool, d time.Duration, refresh RefreshFunc) {
+	if !found || item.Expiration == 0 || refresh == nil {
+		return
+	}
+	ahead := c.RefreshAhead
+	if ahead == 0 {
+		ahead = defaultRefreshAhead
+	}
+	t := &c.refreshes
+	t.mu.Lock()
+	if t.keys == nil {
+		t.keys = make(map[string]*refresher)
+		atomic.StoreUint32(&t.used, 1)
+	}
+	t.keys[k] = &refresher{
+		fn:      refresh,
+		d:       d,
+		version: item.Version,
+		at:      item.Expiration - int64(float64(d)*ahead),
+	}
+	t.mu.Unlock()
+}
+
+// Start refreshing item, retrieved under k at now, if it is due.
+func (c *cache) refreshAhead(k string, item Item, now int64) {
+	t := &c.refreshes
+	t.mu.Lock()
+	r, found := t.keys[k]
+	if !found || r.version != item.Version || now < r.at || r.inflight {
+		t.mu.Unlock()
+		return
+	}
+	r.inflight = true
+	t.mu.Unlock()
+	go func() {
+		x, err := r.fn(k)
+		if err != nil {
+			c.debug("cache: refresh failed", "key", k, "error", err)
+			t.mu.Lock()
+			r.inflight = false
+			t.mu.Unlock()
+			return
+		}
+		c.mu.Lock()
+		// Don't overwrite an item that was changed during the refresh.
+		cur, found := c.getItem(k)
+		if found && cur.Version == r.version {
+			c.set(k, x, r.d)
+			cur, found = c.getItem(k)
+		} else {
+			found = false
+		}
+		c.mu.Unlock()
+		c.refreshWith(k, cur, found, r.d, r.fn)
+	}()
+}
+
+// Forget the refresh functions of items that have been replaced or deleted.
+func (t *refreshTable) prune(c *cache) {
+	if !t.inUse() {
+		return
+	}
+	t.mu.Lock()
+	for k, r := range t.keys {
+		if v, found := c.getItem(k); !found || v.Version != r.version {
+			delete(t.keys, k)
+		}
+	}
+	t.mu.Unlock()
+}
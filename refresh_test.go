@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetWithRefresh(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), RefreshAhead(0.5))
+	var calls int32
+	done := make(chan struct{}, 1)
+	tc.SetWithRefresh("a", 0, 10*time.Second, func(k string) (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		done <- struct{}{}
+		return int(n), nil
+	})
+
+	clock.Advance(4 * time.Second)
+	if x, _ := tc.Get("a"); x != 0 {
+		t.Fatalf("got %v; want 0", x)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("refreshed %d times before the refresh point", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	if x, _ := tc.Get("a"); x != 0 {
+		t.Errorf("got %v; want the current value while refreshing", x)
+	}
+	<-done
+	waitFor(t, func() bool {
+		x, _, _ := tc.GetWithExpiration("a")
+		return x == 1
+	})
+	_, exp, _ := tc.GetWithExpiration("a")
+	if want := clock.Now().Add(10 * time.Second); !exp.Equal(want) {
+		t.Errorf("refreshed item expires at %v; want %v", exp, want)
+	}
+
+	// Replacing the item drops the refresh function.
+	tc.Set("a", 5, 10*time.Second)
+	clock.Advance(9 * time.Second)
+	tc.Get("a")
+	select {
+	case <-done:
+		t.Error("item replaced with Set was refreshed")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSetWithRefreshError(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	done := make(chan struct{}, 2)
+	tc.SetWithRefresh("a", 1, 10*time.Second, func(k string) (interface{}, error) {
+		done <- struct{}{}
+		return nil, errors.New("origin down")
+	})
+	clock.Advance(9 * time.Second)
+	tc.Get("a")
+	<-done
+	if x, found := tc.Get("a"); !found || x != 1 {
+		t.Errorf("got %v, %v after a failed refresh; want 1", x, found)
+	}
+	// The second Get tried again.
+	<-done
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+)
+
+// Add members to the set stored under k, a map[string]struct{}, like Redis'
+// SADD. If k is not in the cache (or has expired), it is set to a new set
+// with the expiration d; otherwise its expiration is unchanged, so e.g. a set
+// of the IDs seen within the last hour can be kept by creating one per hour.
+// Returns the number of members that were not in the set yet, or an error if
+// the value of k is not a set.
+//
+// Like hashes (see HSet), sets are never modified in place, but adding
+// members that are all in the set already does not copy it.
+func (c *cache) SAdd(k string, d time.Duration, members ...string) (int, error) {
+	n := 0
+	err := c.modify(k, d, func(old interface{}, found bool) (interface{}, bool, error) {
+		s, err := setOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		var ns map[string]struct{}
+		for _, m := range members {
+			if _, ok := s[m]; ok {
+				continue
+			}
+			if ns == nil {
+				ns = make(map[string]struct{}, len(s)+len(members))
+				for m := range s {
+					ns[m] = struct{}{}
+				}
+			}
+			if _, ok := ns[m]; !ok {
+				ns[m] = struct{}{}
+				n++
+			}
+		}
+		if ns == nil {
+			if found {
+				return nil, false, errUnchanged
+			}
+			ns = map[string]struct{}{}
+		}
+		return ns, false, nil
+	})
+	return n, err
+}
+
+// Remove members from the set stored under k, deleting k if no members are
+// left, like Redis' SREM. Returns the number of members removed, or an error
+// if the value of k is not a set.
+func (c *cache) SRem(k string, members ...string) (int, error) {
+	n := 0
+	err := c.modify(k, DefaultExpiration, func(old interface{}, found bool) (interface{}, bool, error) {
+		if !found {
+			return nil, false, errUnchanged
+		}
+		s, err := setOf(k, old, found)
+		if err != nil {
+			return nil, false, err
+		}
+		ns := make(map[string]struct{}, len(s))
+		for m := range s {
+			ns[m] = struct{}{}
+		}
+		for _, m := range members {
+			if _, ok := ns[m]; ok {
+				delete(ns, m)
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, false, errUnchanged
+		}
+		return ns, len(ns) == 0, nil
+	})
+	return n, err
+}
+
+// Returns the members of the set stored under k, in no particular order, or
+// nil if k is not in the cache or is not a set.
+func (c *cache) SMembers(k string) []string {
+	item, found := c.lookup(k)
+	if !found {
+		return nil
+	}
+	s, ok := item.Object.(map[string]struct{})
+	if !ok {
+		return nil
+	}
+	members := make([]string, 0, len(s))
+	for m := range s {
+		members = append(members, m)
+	}
+	return members
+}
+
+// Reports whether member is in the set stored under k. A k that is not a set
+// is treated as an empty set.
+func (c *cache) SIsMember(k, member string) bool {
+	item, found := c.lookup(k)
+	if !found {
+		return false
+	}
+	s, ok := item.Object.(map[string]struct{})
+	if !ok {
+		return false
+	}
+	_, ok = s[member]
+	return ok
+}
+
+// Returns x, the value of k, as a set, or an empty set if k was not found.
+func setOf(k string, x interface{}, found bool) (map[string]struct{}, error) {
+	if !found {
+		return nil, nil
+	}
+	s, ok := x.(map[string]struct{})
+	if !ok {
+		return nil, fmt.Errorf("The value for %s is not a map[string]struct{}", k)
+	}
+	return s, nil
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	if n, err := tc.SAdd("seen", time.Hour, "a", "b", "a"); n != 2 || err != nil {
+		t.Errorf("SAdd returned %d, %v; want 2", n, err)
+	}
+	if n, _ := tc.SAdd("seen", time.Minute, "b"); n != 0 {
+		t.Errorf("SAdd added %d existing members", n)
+	}
+	if !tc.SIsMember("seen", "a") || tc.SIsMember("seen", "c") {
+		t.Error("wrong membership")
+	}
+	members := tc.SMembers("seen")
+	sort.Strings(members)
+	if len(members) != 2 || members[0] != "a" || members[1] != "b" {
+		t.Errorf("got %v; want [a b]", members)
+	}
+
+	if n, err := tc.SRem("seen", "a", "c"); n != 1 || err != nil {
+		t.Errorf("SRem returned %d, %v; want 1", n, err)
+	}
+	clock.Advance(2 * time.Hour)
+	if tc.SIsMember("seen", "b") {
+		t.Error("member of an expired set was found")
+	}
+
+	tc.SAdd("s", DefaultExpiration, "x")
+	tc.SRem("s", "x")
+	if _, found := tc.Get("s"); found {
+		t.Error("empty set was not deleted")
+	}
+	tc.Set("str", "string", DefaultExpiration)
+	if _, err := tc.SAdd("str", DefaultExpiration, "x"); err == nil {
+		t.Error("SAdd on a string succeeded")
+	}
+}
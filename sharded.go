@@ -29,6 +29,7 @@ type shardedCache struct {
 	m       uint32
 	cs      []*cache
 	janitor *shardedJanitor
+	hasher  func(string) uint64
 }
 
 // djb2 with better shuffling. 5x faster than FNV with the hash.Hash overhead.
@@ -64,6 +65,9 @@ func djb33(seed uint32, k string) uint32 {
 }
 
 func (sc *shardedCache) bucket(k string) *cache {
+	if sc.hasher != nil {
+		return sc.cs[sc.hasher(k)%uint64(sc.m)]
+	}
 	return sc.cs[djb33(sc.seed, k)%sc.m]
 }
 
@@ -149,6 +153,17 @@ func Shards(shards int) CacheOption {
 	}
 }
 
+// Use h to choose the shard of each key, instead of the default seeded hash,
+// e.g. to keep the keys of a tenant together, or to force collisions in
+// tests. Keys are assigned to shard h(k) % Shards. Only sharded caches use
+// it: New returns an error if it is given.
+func Hasher(h func(k string) uint64) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Hasher = h
+		return nil
+	}
+}
+
 func stopShardedJanitor(sc *unexportedShardedCache) {
 	sc.janitor.stop <- true
 }
@@ -173,9 +188,10 @@ func newShardedCache(opts *CacheOptions) *shardedCache {
 		seed = uint32(rnd.Uint64())
 	}
 	sc := &shardedCache{
-		seed: seed,
-		m:    uint32(opts.Shards),
-		cs:   make([]*cache, opts.Shards),
+		seed:   seed,
+		m:      uint32(opts.Shards),
+		cs:     make([]*cache, opts.Shards),
+		hasher: opts.Hasher,
 	}
 	for i := 0; i < opts.Shards; i++ {
 		items := sync.Map{}
@@ -194,6 +210,9 @@ func unexportedNewSharded(options ...CacheOption) *unexportedShardedCache {
 			return nil
 		}
 	}
+	if err := opts.validate(); err != nil || opts.Shards < 1 {
+		return nil
+	}
 
 	sc := newShardedCache(opts)
 	SC := &unexportedShardedCache{sc}
@@ -83,3 +83,38 @@ func benchmarkShardedCacheGetManyConcurrent(b *testing.B, exp time.Duration) {
 	b.StartTimer()
 	wg.Wait()
 }
+
+func TestShardedCacheZeroShards(t *testing.T) {
+	if tc := unexportedNewSharded(Expiration(DefaultExpiration)); tc != nil {
+		t.Error("Created a sharded cache without shards")
+	}
+}
+
+func TestShardedCacheHasher(t *testing.T) {
+	tc := unexportedNewSharded(Expiration(DefaultExpiration), Shards(4), Hasher(func(k string) uint64 {
+		n, _ := strconv.Atoi(k)
+		return uint64(n)
+	}))
+	for i := 0; i < 8; i++ {
+		tc.Set(strconv.Itoa(i), i, DefaultExpiration)
+	}
+	for i, items := range tc.Items() {
+		if len(items) != 2 {
+			t.Errorf("shard %d has %d items; want 2", i, len(items))
+		}
+		for k := range items {
+			if n, _ := strconv.Atoi(k); n%4 != i {
+				t.Errorf("key %s is in shard %d", k, i)
+			}
+		}
+	}
+	if x, found := tc.Get("5"); !found || x.(int) != 5 {
+		t.Errorf("got %v, %v; want 5", x, found)
+	}
+}
+
+func TestHasherRejectedByNew(t *testing.T) {
+	if _, err := NewWithError(Hasher(func(string) uint64 { return 0 })); err == nil {
+		t.Error("New accepted a Hasher it does not use")
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// Values implementing Sized report their own size in bytes, which is used
+// instead of the cache's estimate when enforcing MaxBytes.
+type Sized interface {
+	CacheSize() int64
+}
+
+// Approximate per-item overhead of the Item struct and the sync.Map entry
+// holding it.
+const itemOverhead = int64(unsafe.Sizeof(Item{})) + 64
+
+// Returns a rough estimate of the number of bytes used by an item with key k
+// and value x. Values implementing Sized are asked for their size, strings and
+// byte slices are counted by length, and anything else is measured with
+// reflection (see valueSize).
+func estimateSize(k string, x interface{}) int64 {
+	n := itemOverhead + int64(len(k))
+	switch v := x.(type) {
+	case nil:
+	case Sized:
+		n += v.CacheSize()
+	case string:
+		n += int64(len(v))
+	case []byte:
+		n += int64(len(v))
+	default:
+		n += valueSize(reflect.ValueOf(x), maxSizeDepth)
+	}
+	return n
+}
+
+// How many pointers, slices, maps and interfaces valueSize follows before it
+// stops counting what they refer to. This also stops it at cycles.
+const maxSizeDepth = 8
+
+// Whether each type (a reflect.Type) holds no pointers, strings, slices, maps
+// or interfaces, so that the size of its values is simply the size of the type.
+var flatTypes sync.Map
+
+func isFlat(t reflect.Type) bool {
+	if v, ok := flatTypes.Load(t); ok {
+		return v.(bool)
+	}
+	var flat bool
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32,
+		reflect.Float64, reflect.Complex64, reflect.Complex128:
+		flat = true
+	case reflect.Array:
+		flat = isFlat(t.Elem())
+	case reflect.Struct:
+		flat = true
+		for i := 0; i < t.NumField() && flat; i++ {
+			flat = isFlat(t.Field(i).Type)
+		}
+	}
+	flatTypes.Store(t, flat)
+	return flat
+}
+
+// Returns the estimated number of bytes used by v, including the strings,
+// slices, maps and pointed-to values it refers to, up to depth levels deep.
+// Memory shared between several values is counted once for each. Channels and
+// functions are counted by the size of their type only.
+func valueSize(v reflect.Value, depth int) int64 {
+	t := v.Type()
+	n := int64(t.Size())
+	if isFlat(t) || depth <= 0 {
+		return n
+	}
+	switch t.Kind() {
+	case reflect.String:
+		n += int64(v.Len())
+	case reflect.Slice:
+		if isFlat(t.Elem()) {
+			return n + int64(v.Cap())*int64(t.Elem().Size())
+		}
+		for i := 0; i < v.Len(); i++ {
+			n += valueSize(v.Index(i), depth-1)
+		}
+	case reflect.Array:
+		n = 0
+		for i := 0; i < v.Len(); i++ {
+			n += valueSize(v.Index(i), depth)
+		}
+	case reflect.Struct:
+		n = 0
+		for i := 0; i < v.NumField(); i++ {
+			n += valueSize(v.Field(i), depth)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			n += valueSize(iter.Key(), depth-1) + valueSize(iter.Value(), depth-1)
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			n += valueSize(v.Elem(), depth-1)
+		}
+	}
+	return n
+}
+
+// Returns the estimated number of bytes used by all items in the cache,
+// including items that have expired but have not yet been cleaned up. Values
+// implementing Sized report their own size; the size of other values is
+// estimated as for MaxBytes. This walks every item, so it is best suited to
+// occasional reporting and capacity planning.
+func (c *cache) EstimatedSize() int64 {
+	return c.estimatedSize()
+}
+
+// Returns the estimated number of bytes used by all items in the cache,
+// including items that have expired but have not yet been cleaned up.
+func (c *cache) estimatedSize() int64 {
+	var n int64
+	c.items.Range(func(key, value interface{}) bool {
+		n += estimateSize(key.(string), value.(Item).Object)
+		return true
+	})
+	return n
+}
+
+// Delete the least recently used items until the estimated size of the
+// unexpired items in the cache is at most max bytes.
+func (c *cache) deleteLRUBytes(max int64) ([]KeyValue, []string) {
+	return c.deleteLRUWeight(max, func(k string, v Item) int64 {
+		return estimateSize(k, v.Object)
+	})
+}
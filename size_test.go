@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+type sizedValue int64
+
+func (s sizedValue) CacheSize() int64 {
+	return int64(s)
+}
+
+func TestEstimateSize(t *testing.T) {
+	if n := estimateSize("k", strings.Repeat("x", 1000)); n != itemOverhead+1+1000 {
+		t.Error("Wrong estimate for string:", n)
+	}
+	if n := estimateSize("k", make([]byte, 500)); n != itemOverhead+1+500 {
+		t.Error("Wrong estimate for []byte:", n)
+	}
+	if n := estimateSize("k", sizedValue(12345)); n != itemOverhead+1+12345 {
+		t.Error("Sized value was not asked for its size:", n)
+	}
+	if n := estimateSize("k", int64(1)); n != itemOverhead+1+8 {
+		t.Error("Wrong estimate for int64:", n)
+	}
+
+	type node struct {
+		Name string
+		Next *node
+	}
+	var (
+		nodeSize = int64(unsafe.Sizeof(node{}))
+		list     = &node{Name: "abc", Next: &node{Name: "de"}}
+	)
+	if n := estimateSize("k", list); n != itemOverhead+1+8+2*nodeSize+5 {
+		t.Error("Wrong estimate for linked list:", n)
+	}
+	list.Next.Next = list
+	if n := estimateSize("k", list); n <= itemOverhead+1+8+2*nodeSize+5 {
+		t.Error("Wrong estimate for cyclic list:", n)
+	}
+	m := map[string][]string{"a": {"xyz"}}
+	if n := estimateSize("k", m); n != itemOverhead+1+8+16+1+24+16+3 {
+		t.Error("Wrong estimate for map:", n)
+	}
+}
+
+func TestEstimatedSize(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	if n := tc.EstimatedSize(); n != 0 {
+		t.Error("Empty cache has estimated size", n)
+	}
+	tc.Set("a", strings.Repeat("x", 100), DefaultExpiration)
+	tc.Set("b", sizedValue(50), DefaultExpiration)
+	if n := tc.EstimatedSize(); n != 2*itemOverhead+2+150 {
+		t.Error("Wrong estimated size:", n)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), MaxBytes(2*(itemOverhead+1+1000)))
+	tc.Set("a", sizedValue(1000), DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("b", sizedValue(1000), DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Set("c", sizedValue(1000), DefaultExpiration)
+	<-time.After(1 * time.Millisecond)
+	tc.Get("a")
+
+	tc.DeleteLRU()
+	if n := tc.ItemCount(); n != 2 {
+		t.Error("ItemCount is not 2:", n)
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("b was found, but it should have been evicted")
+	}
+	if _, found := tc.Get("a"); !found {
+		t.Error("a was not found")
+	}
+}
@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// Snapshot files start with snapshotMagic and a big-endian uint16 format
+// version. The codec's output follows in chunks, each preceded by its length
+// and CRC32 as big-endian uint32s, and terminated by an empty chunk. A
+// big-endian uint64 holding the number of items written ends the file.
+//
+// The count and checksums can't be written in a leading header without
+// buffering the whole snapshot, so they are spread over the chunks and
+// trailer instead.
+const (
+	snapshotMagic   = "GOCACHE\x00"
+	snapshotVersion = 1
+	// Chunks are flushed once they reach this size.
+	snapshotChunkSize = 64 << 10
+	// Larger chunk lengths are taken as corruption, to avoid allocating
+	// huge buffers for garbage.
+	snapshotMaxChunk = 16 << 20
+)
+
+// A SnapshotError is returned by Load when the snapshot is not one written
+// by Save, is truncated, or fails its checksum.
+type SnapshotError struct {
+	Reason string
+}
+
+func (e *SnapshotError) Error() string {
+	return "Invalid snapshot: " + e.Reason
+}
+
+func writeSnapshotHeader(w io.Writer) error {
+	var b [len(snapshotMagic) + 2]byte
+	copy(b[:], snapshotMagic)
+	binary.BigEndian.PutUint16(b[len(snapshotMagic):], snapshotVersion)
+	_, err := w.Write(b[:])
+	return err
+}
+
+func readSnapshotHeader(r io.Reader) error {
+	var b [len(snapshotMagic) + 2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return &SnapshotError{"missing header"}
+		}
+		return err
+	}
+	if string(b[:len(snapshotMagic)]) != snapshotMagic {
+		return &SnapshotError{"bad magic number"}
+	}
+	if v := binary.BigEndian.Uint16(b[len(snapshotMagic):]); v > snapshotVersion {
+		return &SnapshotError{"unsupported format version"}
+	}
+	return nil
+}
+
+type chunkWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= snapshotChunkSize {
+		if err := cw.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (cw *chunkWriter) flush() error {
+	var h [8]byte
+	binary.BigEndian.PutUint32(h[:4], uint32(len(cw.buf)))
+	binary.BigEndian.PutUint32(h[4:], crc32.ChecksumIEEE(cw.buf))
+	if _, err := cw.w.Write(h[:]); err != nil {
+		return err
+	}
+	if _, err := cw.w.Write(cw.buf); err != nil {
+		return err
+	}
+	cw.buf = cw.buf[:0]
+	return nil
+}
+
+// Flush any buffered data and write the terminating empty chunk, followed
+// by the trailer.
+func (cw *chunkWriter) Close(count uint64) error {
+	if len(cw.buf) > 0 {
+		if err := cw.flush(); err != nil {
+			return err
+		}
+	}
+	if err := cw.flush(); err != nil {
+		return err
+	}
+	var t [8]byte
+	binary.BigEndian.PutUint64(t[:], count)
+	_, err := cw.w.Write(t[:])
+	return err
+}
+
+// A chunkReader returns the data of verified chunks, and io.EOF once the
+// terminating chunk has been read. Any other error is kept in err, so that
+// it can be reported even if the decoder reading from the chunkReader
+// replaces it with one of its own.
+type chunkReader struct {
+	r    io.Reader
+	buf  []byte
+	done bool
+	err  error
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	if cr.err != nil {
+		return 0, cr.err
+	}
+	for len(cr.buf) == 0 {
+		if cr.done {
+			return 0, io.EOF
+		}
+		if cr.err = cr.next(); cr.err != nil {
+			return 0, cr.err
+		}
+	}
+	n := copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+func (cr *chunkReader) next() error {
+	var h [8]byte
+	if _, err := io.ReadFull(cr.r, h[:]); err != nil {
+		return truncated(err)
+	}
+	n := binary.BigEndian.Uint32(h[:4])
+	if n == 0 {
+		cr.done = true
+		return nil
+	}
+	if n > snapshotMaxChunk {
+		return &SnapshotError{"bad chunk length"}
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(cr.r, buf); err != nil {
+		return truncated(err)
+	}
+	if crc32.ChecksumIEEE(buf) != binary.BigEndian.Uint32(h[4:]) {
+		return &SnapshotError{"checksum mismatch"}
+	}
+	cr.buf = buf
+	return nil
+}
+
+// Read the trailer, which must follow the terminating chunk, and check that
+// it matches the number of items read.
+func (cr *chunkReader) Close(count uint64) error {
+	if !cr.done {
+		return &SnapshotError{"data after the last item"}
+	}
+	var t [8]byte
+	if _, err := io.ReadFull(cr.r, t[:]); err != nil {
+		return truncated(err)
+	}
+	if binary.BigEndian.Uint64(t[:]) != count {
+		return &SnapshotError{"item count mismatch"}
+	}
+	return nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &SnapshotError{"truncated"}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func savedSnapshot(t *testing.T, n int) []byte {
+	tc := New(Expiration(DefaultExpiration))
+	for i := 0; i < n; i++ {
+		tc.Set(strconv.Itoa(i), strconv.Itoa(i), DefaultExpiration)
+	}
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	b := savedSnapshot(t, 10000)
+	if err := New().Load(bytes.NewReader(b)); err != nil {
+		t.Fatal("Couldn't load an intact snapshot:", err)
+	}
+
+	damaged := func(fn func([]byte) []byte) []byte {
+		return fn(append([]byte(nil), b...))
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		reason string
+	}{
+		{"empty", nil, "missing header"},
+		{"magic", damaged(func(d []byte) []byte { d[0] = 'X'; return d }), "bad magic number"},
+		{"version", damaged(func(d []byte) []byte { d[9] = 99; return d }), "unsupported format version"},
+		{"flipped bit", damaged(func(d []byte) []byte { d[len(d)/2] ^= 1; return d }), "checksum mismatch"},
+		{"truncated chunk", b[:len(b)/2], "truncated"},
+		{"no trailer", b[:len(b)-8], "truncated"},
+		{"wrong count", damaged(func(d []byte) []byte { d[len(d)-1]++; return d }), "item count mismatch"},
+	}
+	for _, tt := range tests {
+		err := New().Load(bytes.NewReader(tt.data))
+		serr, ok := err.(*SnapshotError)
+		if !ok {
+			t.Errorf("%s: expected a *SnapshotError, got %v", tt.name, err)
+		} else if serr.Reason != tt.reason {
+			t.Errorf("%s: got reason %q, want %q", tt.name, serr.Reason, tt.reason)
+		}
+	}
+}
+
+func TestLoadAppliesVerifiedChunks(t *testing.T) {
+	b := savedSnapshot(t, 10000)
+	tc := New()
+	if err := tc.Load(bytes.NewReader(b[:len(b)/2])); err == nil {
+		t.Fatal("Loaded a truncated snapshot without an error")
+	}
+	if n := tc.ItemCount(); n == 0 || n >= 10000 {
+		t.Errorf("%d items loaded from the intact chunks of a truncated snapshot", n)
+	}
+}
+
+func TestLoadWithPolicy(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", "saved", time.Hour)
+	tc.Set("b", "saved", time.Minute)
+	tc.Set("c", "saved", DefaultExpiration)
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	tests := []struct {
+		policy  LoadPolicy
+		a, b, c string
+	}{
+		{SkipExisting, "local", "local", "local"},
+		{Overwrite, "saved", "saved", "saved"},
+		{NewestWins, "saved", "local", "local"},
+	}
+	for _, tt := range tests {
+		oc := New(Expiration(DefaultExpiration))
+		oc.Set("a", "local", time.Minute)
+		oc.Set("b", "local", time.Hour)
+		oc.Set("c", "local", DefaultExpiration)
+		if err := oc.LoadWithPolicy(bytes.NewReader(b), tt.policy); err != nil {
+			t.Fatal(err)
+		}
+		for k, want := range map[string]string{"a": tt.a, "b": tt.b, "c": tt.c} {
+			if x, _ := oc.Get(k); x != want {
+				t.Errorf("Policy %d: %s is %v, want %s", tt.policy, k, x, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package cache
+
+// Like Get, but also returns items that have expired and have not been
+// deleted yet (by the janitor, DeleteExpired or eviction), with stale set to
+// true. This lets callers fall back to the last known value when recomputing
+// it fails, e.g. because the origin is down. Retrieving a stale item counts
+// as a miss, and does not make it any less likely to be deleted.
+func (c *cache) GetStale(k string) (value interface{}, stale bool, found bool) {
+	if item, ok := c.lookup(k); ok {
+		return c.copyOut(item.Object), false, true
+	}
+	if c.isClosed() {
+		return nil, false, false
+	}
+	item, ok := c.getItem(k)
+	if !ok {
+		return nil, false, false
+	}
+	return c.copyOut(item.Object), c.expired(item), true
+}
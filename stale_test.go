@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetStale(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.Set("a", 1, time.Second)
+	if x, stale, found := tc.GetStale("a"); x != 1 || stale || !found {
+		t.Errorf("got %v, %v, %v; want 1, false, true", x, stale, found)
+	}
+	clock.Advance(2 * time.Second)
+	if _, found := tc.Get("a"); found {
+		t.Error("Get returned an expired item")
+	}
+	if x, stale, found := tc.GetStale("a"); x != 1 || !stale || !found {
+		t.Errorf("got %v, %v, %v; want 1, true, true", x, stale, found)
+	}
+	tc.DeleteExpired()
+	if _, _, found := tc.GetStale("a"); found {
+		t.Error("GetStale returned a deleted item")
+	}
+	if s := tc.Stats(); s.Hits != 1 || s.Misses != 3 {
+		t.Errorf("got %d hits and %d misses; want 1 and 3", s.Hits, s.Misses)
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds counters describing the activity of a cache since it was
+// created.
+type Stats struct {
+	// Number of lookups that found an unexpired item.
+	Hits uint64
+	// Number of lookups that did not find an item, or found an expired one.
+	Misses uint64
+	// Number of items removed by DeleteExpired or because the cache was
+	// over its size limit. Items removed with Delete are not counted.
+	Evictions uint64
+	// How long the most recent janitor run took.
+	JanitorRunDuration time.Duration
+	// Number of eviction callbacks discarded because the queue of
+	// AsyncCallbacks was full.
+	DroppedCallbacks uint64
+	// Number of hits and misses, and the ratio of hits to lookups, over the
+	// window set with HitRatioWindow. All are 0 if it is not set, or there
+	// were no lookups in the window.
+	WindowHits     uint64
+	WindowMisses   uint64
+	WindowHitRatio float64
+}
+
+// The counters are only ever modified using sync/atomic, and are kept at the
+// start of the cache struct so that they are 64-bit aligned on 32-bit
+// platforms.
+type cacheStats struct {
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	janitorRun int64
+
+	droppedCallbacks uint64
+}
+
+func (s *cacheStats) hit() {
+	atomic.AddUint64(&s.hits, 1)
+}
+
+func (s *cacheStats) miss() {
+	atomic.AddUint64(&s.misses, 1)
+}
+
+func (s *cacheStats) evicted(n int) {
+	if n > 0 {
+		atomic.AddUint64(&s.evictions, uint64(n))
+	}
+}
+
+// Returns a copy of the cache's counters.
+func (c *cache) Stats() Stats {
+	s := Stats{
+		Hits:               atomic.LoadUint64(&c.stats.hits),
+		Misses:             atomic.LoadUint64(&c.stats.misses),
+		Evictions:          atomic.LoadUint64(&c.stats.evictions),
+		JanitorRunDuration: time.Duration(atomic.LoadInt64(&c.stats.janitorRun)),
+		DroppedCallbacks:   atomic.LoadUint64(&c.stats.droppedCallbacks),
+	}
+	if c.window != nil {
+		s.WindowHits, s.WindowMisses = c.window.counts(c.now().UnixNano())
+		if n := s.WindowHits + s.WindowMisses; n > 0 {
+			s.WindowHitRatio = float64(s.WindowHits) / float64(n)
+		}
+	}
+	return s
+}
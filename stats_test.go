@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, 1*time.Millisecond)
+
+	tc.Get("a")
+	tc.Get("a")
+	tc.Get("c")
+	<-time.After(5 * time.Millisecond)
+	tc.Get("b")
+
+	s := tc.Stats()
+	if s.Hits != 2 {
+		t.Error("Hits is not 2:", s.Hits)
+	}
+	if s.Misses != 2 {
+		t.Error("Misses is not 2:", s.Misses)
+	}
+
+	tc.DeleteExpired()
+	if s = tc.Stats(); s.Evictions != 1 {
+		t.Error("Evictions is not 1:", s.Evictions)
+	}
+}
+
+func TestStatsDeleteLRU(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CacheSize(1))
+	tc.Set("foo", 0, DefaultExpiration)
+	tc.Set("bar", 1, DefaultExpiration)
+	tc.Set("baz", 2, DefaultExpiration)
+	tc.DeleteLRU()
+	if s := tc.Stats(); s.Evictions != 2 {
+		t.Error("Evictions is not 2:", s.Evictions)
+	}
+}
+
+func TestStatsJanitorRunDuration(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), CleanupInterval(1*time.Millisecond))
+	tc.Set("foo", "bar", DefaultExpiration)
+	<-time.After(20 * time.Millisecond)
+	if s := tc.Stats(); s.JanitorRunDuration <= 0 {
+		t.Error("JanitorRunDuration was not recorded:", s.JanitorRunDuration)
+	}
+}
+
+func TestStatsHitRatioWindow(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock), HitRatioWindow(time.Minute))
+	tc.Set("a", 1, DefaultExpiration)
+	for i := 0; i < 4; i++ {
+		tc.Get("b")
+	}
+	clock.Advance(40 * time.Second)
+	for i := 0; i < 3; i++ {
+		tc.Get("a")
+	}
+	tc.Get("b")
+	if s := tc.Stats(); s.WindowHits != 3 || s.WindowMisses != 5 {
+		t.Errorf("window has %d hits and %d misses; want 3 and 5", s.WindowHits, s.WindowMisses)
+	}
+	clock.Advance(30 * time.Second)
+	s := tc.Stats()
+	if s.WindowHits != 3 || s.WindowMisses != 1 || s.WindowHitRatio != 0.75 {
+		t.Errorf("window has %d hits, %d misses and ratio %v; want 3, 1 and 0.75", s.WindowHits, s.WindowMisses, s.WindowHitRatio)
+	}
+	if s.Hits != 3 || s.Misses != 5 {
+		t.Errorf("lifetime counters are %d and %d; want 3 and 5", s.Hits, s.Misses)
+	}
+	clock.Advance(time.Hour)
+	if s := tc.Stats(); s.WindowHits != 0 || s.WindowMisses != 0 || s.WindowHitRatio != 0 {
+		t.Errorf("idle window is not empty: %+v", s)
+	}
+}
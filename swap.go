@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"time"
+)
+
+// Set k to x with the expiration d, like Set, and return the value it
+// replaced, so that resources tied to it (file handles, buffers, ...) can be
+// released. existed is false if there was no item with key k, or it had
+// expired. If x is rejected because of MaxKeyLength or MaxValueBytes, the
+// item is left unchanged, and Swap returns nil and false.
+//
+// Reading the old value and storing the new one is atomic with respect to
+// other calls of Swap, SwapIfExists and SetIfVersion, but not to the plain
+// Set methods.
+func (c *cache) Swap(k string, x interface{}, d time.Duration) (old interface{}, existed bool) {
+	return c.swap(k, x, d, false)
+}
+
+// Like Swap, but only replaces an existing, unexpired item, like Replace.
+func (c *cache) SwapIfExists(k string, x interface{}, d time.Duration) (old interface{}, existed bool) {
+	return c.swap(k, x, d, true)
+}
+
+func (c *cache) swap(k string, x interface{}, d time.Duration, existing bool) (interface{}, bool) {
+	if c.isClosed() || c.checkSize(k, x) != nil {
+		return nil, false
+	}
+	if !existing {
+		// Eviction callbacks and events are delivered without holding c.mu.
+		c.makeRoom(k)
+	}
+	x = c.copyIn(x)
+	c.mu.Lock()
+	v, found := c.getItem(k)
+	found = found && !c.expired(v)
+	if existing && !found {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.put(k, x, d)
+	c.mu.Unlock()
+	c.notify(EventSet, k, x)
+	if !found {
+		return nil, false
+	}
+	return v.Object, true
+}
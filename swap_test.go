@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSwap(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	if old, existed := tc.Swap("a", 1, time.Second); existed || old != nil {
+		t.Errorf("got %v, %v for a new key; want nil, false", old, existed)
+	}
+	if old, existed := tc.Swap("a", 2, time.Second); !existed || old != 1 {
+		t.Errorf("got %v, %v; want 1, true", old, existed)
+	}
+	clock.Advance(2 * time.Second)
+	if old, existed := tc.Swap("a", 3, DefaultExpiration); existed {
+		t.Errorf("got %v for an expired item", old)
+	}
+	if x, _ := tc.Get("a"); x != 3 {
+		t.Errorf("got %v; want 3", x)
+	}
+}
+
+func TestSwapIfExists(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	if _, existed := tc.SwapIfExists("a", 1, DefaultExpiration); existed {
+		t.Error("missing item existed")
+	}
+	if _, found := tc.Get("a"); found {
+		t.Error("SwapIfExists added a missing item")
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	if old, existed := tc.SwapIfExists("a", 2, DefaultExpiration); !existed || old != 1 {
+		t.Errorf("got %v, %v; want 1, true", old, existed)
+	}
+	if x, _ := tc.Get("a"); x != 2 {
+		t.Errorf("got %v; want 2", x)
+	}
+}
+
+func TestSwapEvictionCallback(t *testing.T) {
+	var tc *Cache
+	called := false
+	tc = New(Expiration(DefaultExpiration), HardCacheSize(1), EvictionCallback(func(k string, _ interface{}) {
+		// Would deadlock if the callback ran while Swap held the lock.
+		tc.SwapIfExists("other", 1, DefaultExpiration)
+		called = true
+	}))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Swap("b", 2, DefaultExpiration)
+	if !called {
+		t.Error("eviction callback not called")
+	}
+}
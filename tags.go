@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An index from tags to the keys of the items carrying them. Entries are
+// removed when tagged items are deleted, but not when a tagged item is
+// overwritten by one without the same tag, so InvalidateTag checks each
+// item's tags before deleting it.
+type tagIndex struct {
+	used uint32
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+}
+
+func (t *tagIndex) inUse() bool {
+	return atomic.LoadUint32(&t.used) == 1
+}
+
+func (t *tagIndex) add(k string, tags []string) {
+	t.mu.Lock()
+	if t.keys == nil {
+		t.keys = make(map[string]map[string]struct{})
+		atomic.StoreUint32(&t.used, 1)
+	}
+	for _, tag := range tags {
+		ks, ok := t.keys[tag]
+		if !ok {
+			ks = make(map[string]struct{})
+			t.keys[tag] = ks
+		}
+		ks[k] = struct{}{}
+	}
+	t.mu.Unlock()
+}
+
+func (t *tagIndex) remove(k string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	t.mu.Lock()
+	for _, tag := range tags {
+		if ks, ok := t.keys[tag]; ok {
+			delete(ks, k)
+			if len(ks) == 0 {
+				delete(t.keys, tag)
+			}
+		}
+	}
+	t.mu.Unlock()
+}
+
+// Removes tag from the index, returning the keys that carried it.
+func (t *tagIndex) take(tag string) map[string]struct{} {
+	t.mu.Lock()
+	ks := t.keys[tag]
+	delete(t.keys, tag)
+	t.mu.Unlock()
+	return ks
+}
+
+func (t *tagIndex) reset() {
+	t.mu.Lock()
+	if t.keys != nil {
+		t.keys = make(map[string]map[string]struct{})
+	}
+	t.mu.Unlock()
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, v := range tags {
+		if v == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Add an item to the cache, replacing any existing item, and associate it
+// with the given tags. All items carrying a tag can then be deleted at once
+// with InvalidateTag. The tags are dropped if the item is replaced using a
+// method other than SetWithTags.
+func (c *cache) SetWithTags(k string, x interface{}, d time.Duration, tags ...string) {
+	c.setItem(k, x, d, Item{Tags: append([]string(nil), tags...)})
+}
+
+// Delete every item carrying the given tag, calling the eviction callback for
+// each of them. Returns the number of items deleted.
+func (c *cache) InvalidateTag(tag string) int {
+	var (
+		evictedItems []KeyValue
+		removed      int
+	)
+	for k := range c.tags.take(tag) {
+		v, found := c.getItem(k)
+		if !found || !hasTag(v.Tags, tag) {
+			continue
+		}
+		ov, evicted := c.delete(k, EventDelete)
+		if evicted {
+			evictedItems = append(evictedItems, KeyValue{k, ov, EventDelete})
+		}
+		removed++
+	}
+	c.evictMany(evictedItems)
+	return removed
+}
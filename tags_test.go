@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	var evicted []string
+	tc := New(Expiration(DefaultExpiration), EvictionCallback(func(k string, v interface{}) {
+		evicted = append(evicted, k)
+	}))
+	tc.SetWithTags("user:1", 1, DefaultExpiration, "users", "team:a")
+	tc.SetWithTags("user:2", 2, DefaultExpiration, "users", "team:b")
+	tc.SetWithTags("team:a", 3, DefaultExpiration, "team:a")
+	tc.Set("other", 4, DefaultExpiration)
+
+	if n := tc.InvalidateTag("team:a"); n != 2 {
+		t.Error("InvalidateTag did not delete 2 items:", n)
+	}
+	if len(evicted) != 2 {
+		t.Error("Eviction callback was not called for every deleted item:", evicted)
+	}
+	if _, found := tc.Get("user:2"); !found {
+		t.Error("user:2 was deleted")
+	}
+	if n := tc.InvalidateTag("team:a"); n != 0 {
+		t.Error("Invalidating a tag twice deleted items:", n)
+	}
+
+	// Replacing an item without its tags removes it from the tag
+	tc.Set("user:2", 5, DefaultExpiration)
+	if n := tc.InvalidateTag("users"); n != 0 {
+		t.Error("InvalidateTag deleted an item that no longer carries the tag:", n)
+	}
+	if _, found := tc.Get("user:2"); !found {
+		t.Error("user:2 was deleted")
+	}
+}
+
+func TestTagIndexCleanup(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.SetWithTags("foo", 1, DefaultExpiration, "a", "b")
+	tc.Delete("foo")
+	tc.tags.mu.Lock()
+	n := len(tc.tags.keys)
+	tc.tags.mu.Unlock()
+	if n != 0 {
+		t.Error("Tag index was not cleaned up on Delete:", n)
+	}
+
+	tc.SetWithTags("foo", 1, DefaultExpiration, "a")
+	tc.Flush()
+	if n := tc.InvalidateTag("a"); n != 0 {
+		t.Error("Tag index was not reset on Flush:", n)
+	}
+}
+
+func TestInvalidateTagAfterLoad(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.SetWithTags("a", 1, DefaultExpiration, "t")
+	tc.SetWithTags("b", 2, DefaultExpiration, "t", "u")
+	buf := &bytes.Buffer{}
+	if err := tc.Save(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lc := New(Expiration(DefaultExpiration))
+	if err := lc.Load(buf); err != nil {
+		t.Fatal(err)
+	}
+	if n := lc.InvalidateTag("t"); n != 2 {
+		t.Errorf("InvalidateTag deleted %d loaded items; want 2", n)
+	}
+
+	ic := New(Expiration(DefaultExpiration), InitialItems(tc.Items()))
+	if n := ic.InvalidateTag("u"); n != 1 {
+		t.Errorf("InvalidateTag deleted %d initial items; want 1", n)
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock is the source of the current time for a cache, and of the tickers
+// driving its janitor. It can be replaced (see WithClock) to make tests of
+// expiration deterministic, e.g. with a FakeClock.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// A Ticker delivers ticks of a clock at intervals, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Use c instead of the system clock for expiration, access times and the
+// janitor. Note that the Expired method of an Item always uses the system
+// clock.
+func WithClock(c Clock) CacheOption {
+	return func(m *CacheOptions) error {
+		m.Clock = c
+		return nil
+	}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+func (c *cache) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Returns the current time according to the cache's clock (see WithClock),
+// which is the time its expirations are measured against.
+func (c *cache) Now() time.Time {
+	return c.now()
+}
+
+func (c *cache) newTicker(d time.Duration) Ticker {
+	if c.Clock != nil {
+		return c.Clock.NewTicker(d)
+	}
+	return realTicker{time.NewTicker(d)}
+}
+
+// Returns true if the item has expired according to the cache's clock.
+func (c *cache) expired(item Item) bool {
+	if item.Expiration == 0 {
+		return false
+	}
+	return c.now().UnixNano() > item.Expiration
+}
+
+// A FakeClock is a Clock whose time only changes when it is advanced
+// explicitly. It is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// Returns a FakeClock set to t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{
+		c:     make(chan time.Time, 1),
+		d:     d,
+		next:  f.now.Add(d),
+		clock: f,
+	}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Move the clock forward by d, firing every ticker that becomes due. Like
+// those of time.Ticker, ticks are dropped if the receiver falls behind.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.tickers {
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+}
+
+type fakeTicker struct {
+	c     chan time.Time
+	d     time.Duration
+	next  time.Time
+	clock *FakeClock
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, v := range f.tickers {
+		if v == t {
+			f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+			break
+		}
+	}
+}
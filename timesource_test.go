@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	fc := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(1*time.Minute), WithClock(fc))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, 2*time.Minute)
+
+	fc.Advance(59 * time.Second)
+	if _, found := tc.Get("a"); !found {
+		t.Error("a expired early")
+	}
+	fc.Advance(2 * time.Second)
+	if _, found := tc.Get("a"); found {
+		t.Error("a was found, but it should have expired")
+	}
+	if _, exp, found := tc.GetWithExpiration("b"); !found || !exp.Equal(time.Unix(1120, 0)) {
+		t.Error("b has the wrong expiration:", exp)
+	}
+	if _, err := tc.IncrementInt("a", 1); err == nil {
+		t.Error("Incremented a after it expired")
+	}
+}
+
+func TestFakeClockJanitor(t *testing.T) {
+	fc := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(1*time.Minute), CleanupInterval(1*time.Minute), WithClock(fc))
+	defer tc.Close()
+	tc.Set("a", 1, DefaultExpiration)
+
+	fc.Advance(30 * time.Second)
+	<-time.After(5 * time.Millisecond)
+	if n := tc.ItemCount(); n != 1 {
+		t.Error("Janitor ran before the first tick")
+	}
+
+	fc.Advance(61 * time.Second)
+	for i := 0; i < 100 && tc.ItemCount() != 0; i++ {
+		<-time.After(1 * time.Millisecond)
+	}
+	if n := tc.ItemCount(); n != 0 {
+		t.Error("Janitor did not remove the expired item:", n)
+	}
+}
+
+func TestFakeClockTicker(t *testing.T) {
+	fc := NewFakeClock(time.Unix(0, 0))
+	tk := fc.NewTicker(10 * time.Second)
+	fc.Advance(5 * time.Second)
+	select {
+	case <-tk.C():
+		t.Error("Ticker fired early")
+	default:
+	}
+	fc.Advance(5 * time.Second)
+	select {
+	case now := <-tk.C():
+		if !now.Equal(time.Unix(10, 0)) {
+			t.Error("Tick has the wrong time:", now)
+		}
+	default:
+		t.Error("Ticker did not fire")
+	}
+	tk.Stop()
+	fc.Advance(10 * time.Second)
+	select {
+	case <-tk.C():
+		t.Error("Ticker fired after Stop")
+	default:
+	}
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// A PrefixExpiration gives the items whose keys start with Prefix their own
+// default expiration.
+type PrefixExpiration struct {
+	Prefix     string
+	Expiration time.Duration
+}
+
+// Use d instead of the cache's default expiration for items whose keys start
+// with prefix and are set with DefaultExpiration, e.g.
+// ExpirationFor("session:", 30*time.Minute) and ExpirationFor("geo:",
+// 24*time.Hour), so that one cache can hold several kinds of data without
+// every caller hardcoding their durations. d may be NoExpiration. If several
+// prefixes match a key, the longest one wins. Items set with an explicit
+// duration are not affected.
+func ExpirationFor(prefix string, d time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.PrefixExpirations = append(m.PrefixExpirations, PrefixExpiration{prefix, d})
+		return nil
+	}
+}
+
+// Returns the duration DefaultExpiration stands for when setting k.
+func (c *cache) defaultExpiration(k string) time.Duration {
+	d, longest := c.Expiration, -1
+	for _, r := range c.PrefixExpirations {
+		if len(r.Prefix) > longest && strings.HasPrefix(k, r.Prefix) {
+			d, longest = r.Expiration, len(r.Prefix)
+		}
+	}
+	return d
+}
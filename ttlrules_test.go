@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpirationFor(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	tc := New(Expiration(time.Hour), WithClock(clock),
+		ExpirationFor("session:", 30*time.Minute),
+		ExpirationFor("session:admin:", time.Minute),
+		ExpirationFor("geo:", NoExpiration))
+	tc.Set("session:1", 1, DefaultExpiration)
+	tc.Set("session:admin:1", 1, DefaultExpiration)
+	tc.SetMulti(map[string]interface{}{"geo:1": 1, "other": 1}, DefaultExpiration)
+	tc.Set("session:2", 1, 2*time.Hour)
+
+	want := map[string]time.Duration{
+		"session:1":       30 * time.Minute,
+		"session:admin:1": time.Minute,
+		"geo:1":           0,
+		"other":           time.Hour,
+		"session:2":       2 * time.Hour,
+	}
+	for k, d := range want {
+		_, exp, found := tc.GetWithExpiration(k)
+		if !found {
+			t.Errorf("%s not found", k)
+			continue
+		}
+		if d == 0 {
+			if !exp.IsZero() {
+				t.Errorf("%s expires at %v; want never", k, exp)
+			}
+		} else if !exp.Equal(clock.Now().Add(d)) {
+			t.Errorf("%s expires in %v; want %v", k, exp.Sub(clock.Now()), d)
+		}
+	}
+
+	if _, err := NewWithError(ExpirationFor("a:", 0)); err == nil {
+		t.Error("zero expiration rule was accepted")
+	}
+}
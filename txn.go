@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrConflict is returned by Txn if an item read by the transaction was
+// changed before it could commit.
+var ErrConflict = errors.New("cache: transaction conflict")
+
+// A Txn reads and writes several items of a cache, applying the writes all
+// together, or not at all; see Cache.Txn. A Txn must only be used by the
+// function it was passed to.
+type Txn struct {
+	c      *cache
+	reads  map[string]uint64 // the version read, 0 if missing
+	writes map[string]txnWrite
+}
+
+type txnWrite struct {
+	x      interface{}
+	d      time.Duration
+	delete bool
+}
+
+// Run fn in a transaction, and commit the writes it made with tx.Set and
+// tx.Delete if it returns nil. The writes are invisible until then, to other
+// goroutines and to snapshots alike, and are discarded if fn returns an
+// error, which Txn returns. They are applied together: Get, Has, Peek and
+// the other readers of single items see all of them or none, as do snapshots
+// taken with AtomicSnapshots, and other transactions cannot interleave with
+// them. (A goroutine reading several keys one by one may still read some
+// before a commit and some after it; read them in a Txn to detect that.)
+//
+// If any item read with tx.Get was changed by the time the transaction
+// commits, no writes are applied and Txn returns ErrConflict; callers
+// typically retry. Like SetIfVersion, this detects changes made by other
+// transactions and by SetIfVersion, Swap and the plain Set methods, but the
+// check and the commit are only atomic with respect to the former. If any
+// write would be rejected by MaxKeyLength or MaxValueBytes, Txn returns
+// ErrTooLarge without applying any of them.
+func (c *cache) Txn(fn func(tx *Txn) error) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	tx := &Txn{
+		c:      c,
+		reads:  make(map[string]uint64),
+		writes: make(map[string]txnWrite),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// Get the value of k as the transaction sees it: the value it set, or, if it
+// hasn't written k, the value in the cache.
+func (tx *Txn) Get(k string) (interface{}, bool) {
+	if w, found := tx.writes[k]; found {
+		if w.delete {
+			return nil, false
+		}
+		return w.x, true
+	}
+	item, found := tx.c.lookup(k)
+	if !found {
+		tx.read(k, 0)
+		return nil, false
+	}
+	tx.read(k, item.Version)
+	return tx.c.copyOut(item.Object), true
+}
+
+// Remember the first version of k read by the transaction.
+func (tx *Txn) read(k string, version uint64) {
+	if _, found := tx.reads[k]; !found {
+		tx.reads[k] = version
+	}
+}
+
+// Set k to x with the expiration d when the transaction commits.
+func (tx *Txn) Set(k string, x interface{}, d time.Duration) {
+	tx.writes[k] = txnWrite{x: x, d: d}
+}
+
+// Delete k when the transaction commits.
+func (tx *Txn) Delete(k string) {
+	tx.writes[k] = txnWrite{delete: true}
+}
+
+func (tx *Txn) commit() error {
+	c := tx.c
+	if len(tx.writes) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(tx.writes))
+	for k, w := range tx.writes {
+		if w.delete {
+			continue
+		}
+		if err := c.checkSize(k, w.x); err != nil {
+			return err
+		}
+		values[k] = c.copyIn(w.x)
+	}
+	c.mu.Lock()
+	reserved := false
+	for {
+		if c.isClosed() {
+			c.mu.Unlock()
+			return ErrClosed
+		}
+		for k, version := range tx.reads {
+			var cur uint64
+			if v, found := c.getItem(k); found && !c.expired(v) {
+				cur = v.Version
+			}
+			if cur != version {
+				c.mu.Unlock()
+				return ErrConflict
+			}
+		}
+		added := tx.added()
+		if reserved || added == 0 || atomic.LoadInt64(&c.count)+int64(added) <= int64(c.HardCacheSize) {
+			break
+		}
+		// Make room for all the new keys at once, without holding c.mu, so
+		// that eviction callbacks and events aren't delivered under it, and
+		// check the reads again.
+		c.mu.Unlock()
+		c.evictMany(c.reserve(added))
+		reserved = true
+		c.mu.Lock()
+	}
+
+	now := c.now()
+	lru := c.lru()
+	var (
+		deleted  []KeyValue
+		replaced []interface{}
+	)
+	// Readers wait for txnMu (see loadItem), so they see all the writes or
+	// none of them.
+	atomic.StoreUint32(&c.txns, 1)
+	c.txnMu.Lock()
+	c.beginWrite()
+	for k, w := range tx.writes {
+		if w.delete {
+			if v, found := c.removeItem(k); found {
+				deleted = append(deleted, KeyValue{k, v, EventDelete})
+			}
+			continue
+		}
+		d := w.d
+		if d == DefaultExpiration {
+			d = c.defaultExpiration(k)
+		}
+		item := Item{
+			Object:  values[k],
+			Version: c.nextVersion(),
+		}
+		if d > 0 {
+			item.Expiration = now.Add(d).UnixNano()
+		}
+		if lru {
+			item.Accessed = now.UnixNano()
+			item.Created = now.UnixNano()
+		}
+		if old, loaded := c.storeItem(k, item); loaded && c.DisposeValues {
+			replaced = append(replaced, old, item.Object)
+		}
+	}
+	c.endWrite()
+	c.txnMu.Unlock()
+	c.mu.Unlock()
+
+	for i := 0; i < len(replaced); i += 2 {
+		disposeReplaced(replaced[i], replaced[i+1])
+	}
+	callback := c.hasEvictionCallback() && !c.EvictionsOnly
+	var evicted []KeyValue
+	for _, kv := range deleted {
+		v := kv.Value.(Item)
+		if c.DisposeValues {
+			dispose(v.Object)
+		}
+		c.tags.remove(kv.Key, v.Tags)
+		c.notify(EventDelete, kv.Key, v.Object)
+		if callback {
+			evicted = append(evicted, KeyValue{kv.Key, v.Object, EventDelete})
+		}
+	}
+	for k, w := range tx.writes {
+		if !w.delete {
+			c.notify(EventSet, k, w.x)
+		}
+	}
+	c.evictMany(evicted)
+	return nil
+}
+
+// Returns the number of keys the transaction would add to a cache with a
+// HardCacheSize, or 0 if it has none.
+func (tx *Txn) added() int {
+	if tx.c.HardCacheSize <= 0 {
+		return 0
+	}
+	n := 0
+	for k, w := range tx.writes {
+		if _, found := tx.c.items.Load(k); !found && !w.delete {
+			n++
+		}
+	}
+	return n
+}
+
+// Load the item stored under k. Once a transaction has committed, readers
+// wait for any commit in progress, so that they never see part of one; until
+// then, this is a plain Load.
+func (c *cache) loadItem(k string) (interface{}, bool) {
+	if atomic.LoadUint32(&c.txns) == 0 {
+		v, found := c.items.Load(k)
+		// If no commit had started by the time k was loaded, v predates
+		// all of them.
+		if atomic.LoadUint32(&c.txns) == 0 {
+			return v, found
+		}
+	}
+	c.txnMu.RLock()
+	v, found := c.items.Load(k)
+	c.txnMu.RUnlock()
+	return v, found
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTxn(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+
+	err := tc.Txn(func(tx *Txn) error {
+		a, _ := tx.Get("a")
+		b, _ := tx.Get("b")
+		tx.Set("a", b, DefaultExpiration)
+		tx.Set("b", a, DefaultExpiration)
+		tx.Delete("c")
+		if x, _ := tc.Get("a"); x != 1 {
+			t.Error("write was visible before commit")
+		}
+		if x, _ := tx.Get("a"); x != 2 {
+			t.Errorf("transaction read %v; want its own write 2", x)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := tc.Get("a")
+	b, _ := tc.Get("b")
+	if a != 2 || b != 1 {
+		t.Errorf("got a=%v, b=%v; want 2 and 1", a, b)
+	}
+
+	failed := errors.New("failed")
+	err = tc.Txn(func(tx *Txn) error {
+		tx.Set("a", 3, DefaultExpiration)
+		tx.Delete("b")
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Txn returned %v; want the error of fn", err)
+	}
+	if _, found := tc.Get("b"); !found {
+		t.Error("writes of a failed transaction were applied")
+	}
+}
+
+func TestTxnConflict(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	err := tc.Txn(func(tx *Txn) error {
+		tx.Get("a")
+		tx.Get("missing")
+		tc.Set("a", 5, DefaultExpiration)
+		tx.Set("b", 1, time.Minute)
+		return nil
+	})
+	if err != ErrConflict {
+		t.Errorf("Txn returned %v; want ErrConflict", err)
+	}
+	if _, found := tc.Get("b"); found {
+		t.Error("writes of a conflicting transaction were applied")
+	}
+}
+
+func TestTxnDeleteCallback(t *testing.T) {
+	var deleted []string
+	tc := New(Expiration(DefaultExpiration), EvictionCallback(func(k string, _ interface{}) {
+		deleted = append(deleted, k)
+	}))
+	tc.SetWithTags("a", 1, DefaultExpiration, "t")
+	tc.Txn(func(tx *Txn) error {
+		tx.Delete("a")
+		return nil
+	})
+	if len(deleted) != 1 || deleted[0] != "a" {
+		t.Errorf("eviction callback called for %v; want [a]", deleted)
+	}
+	if n := tc.InvalidateTag("t"); n != 0 {
+		t.Errorf("deleted item was still tagged")
+	}
+}
+
+func TestTxnHardCacheSize(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration), HardCacheSize(3))
+	for _, k := range []string{"a", "b", "c"} {
+		tc.Set(k, k, DefaultExpiration)
+	}
+	err := tc.Txn(func(tx *Txn) error {
+		tx.Get("a")
+		tc.Set("a", "a2", DefaultExpiration)
+		tx.Set("d", 1, DefaultExpiration)
+		return nil
+	})
+	if err != ErrConflict {
+		t.Fatalf("Txn returned %v; want ErrConflict", err)
+	}
+	if n := tc.ItemCount(); n != 3 {
+		t.Errorf("conflicting transaction evicted items: %d left; want 3", n)
+	}
+
+	err = tc.Txn(func(tx *Txn) error {
+		tx.Set("d", 1, DefaultExpiration)
+		tx.Set("e", 2, DefaultExpiration)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tc.ItemCount(); n != 3 {
+		t.Errorf("%d items in the cache; want 3", n)
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrVersionMismatch is returned by SetIfVersion if the item has been changed
+// since the given version was read.
+var ErrVersionMismatch = errors.New("cache: version mismatch")
+
+// Returns a new version, greater than all the versions given out before. The
+// version counter follows cacheStats in the cache struct, which keeps it
+// 64-bit aligned.
+func (c *cache) nextVersion() uint64 {
+	return atomic.AddUint64(&c.version, 1)
+}
+
+// Like Get, but also returns the version of the item. Versions are assigned
+// from a counter that increases every time an item is set or modified in the
+// cache (including when it is loaded from a snapshot), so they can be used
+// with SetIfVersion to detect concurrent changes. Atomic updates of a Counter
+// do not change its version.
+func (c *cache) GetWithVersion(k string) (interface{}, uint64, bool) {
+	item, found := c.lookup(k)
+	if !found {
+		return nil, 0, false
+	}
+	return c.copyOut(item.Object), item.Version, true
+}
+
+// Set k to x with the expiration d if its current version is version, as
+// returned by GetWithVersion. A version of 0 means that k must not be in the
+// cache (or must have expired.) Returns ErrVersionMismatch, and leaves the
+// item unchanged, otherwise.
+//
+// The check and the update are atomic with respect to other calls of
+// SetIfVersion, but not to the plain Set methods, which may still change the
+// item in between.
+func (c *cache) SetIfVersion(k string, x interface{}, version uint64, d time.Duration) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	if err := c.checkSize(k, x); err != nil {
+		return err
+	}
+	if version == 0 {
+		// Only a missing item can be created. Eviction callbacks and events
+		// are delivered without holding c.mu.
+		c.makeRoom(k)
+	}
+	x = c.copyIn(x)
+	c.mu.Lock()
+	var cur uint64
+	if v, found := c.getItem(k); found && !c.expired(v) {
+		cur = v.Version
+	}
+	if cur != version {
+		c.mu.Unlock()
+		return ErrVersionMismatch
+	}
+	c.put(k, x, d)
+	c.mu.Unlock()
+	c.notify(EventSet, k, x)
+	return nil
+}
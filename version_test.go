@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetWithVersion(t *testing.T) {
+	tc := New(Expiration(DefaultExpiration))
+	tc.Set("a", 1, DefaultExpiration)
+	x, v1, found := tc.GetWithVersion("a")
+	if !found || x != 1 || v1 == 0 {
+		t.Fatal("GetWithVersion returned", x, v1, found)
+	}
+	tc.Get("a")
+	if _, v, _ := tc.GetWithVersion("a"); v != v1 {
+		t.Error("Get changed the version from", v1, "to", v)
+	}
+	tc.Set("a", 2, DefaultExpiration)
+	_, v2, _ := tc.GetWithVersion("a")
+	if v2 <= v1 {
+		t.Error("version", v2, "after Set is not greater than", v1)
+	}
+	tc.IncrementInt("a", 1)
+	if _, v3, _ := tc.GetWithVersion("a"); v3 <= v2 {
+		t.Error("version", v3, "after IncrementInt is not greater than", v2)
+	}
+	if _, _, found := tc.GetWithVersion("b"); found {
+		t.Error("b was found")
+	}
+}
+
+func TestSetIfVersion(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	if err := tc.SetIfVersion("a", 1, 0, time.Second); err != nil {
+		t.Fatal("SetIfVersion of a new item failed:", err)
+	}
+	if err := tc.SetIfVersion("a", 2, 0, time.Second); err != ErrVersionMismatch {
+		t.Error("SetIfVersion with version 0 of an existing item returned", err)
+	}
+	_, v, _ := tc.GetWithVersion("a")
+	tc.Set("a", 3, time.Second)
+	if err := tc.SetIfVersion("a", 4, v, time.Second); err != ErrVersionMismatch {
+		t.Error("SetIfVersion with a stale version returned", err)
+	}
+	if x, _ := tc.Get("a"); x != 3 {
+		t.Error("a is", x, "instead of 3")
+	}
+	_, v, _ = tc.GetWithVersion("a")
+	if err := tc.SetIfVersion("a", 5, v, time.Second); err != nil {
+		t.Error("SetIfVersion with the current version failed:", err)
+	}
+
+	clock.Advance(2 * time.Second)
+	if err := tc.SetIfVersion("a", 6, 0, time.Second); err != nil {
+		t.Error("SetIfVersion with version 0 of an expired item failed:", err)
+	}
+}
+
+func TestSetIfVersionEvictionCallback(t *testing.T) {
+	var tc *Cache
+	called := false
+	tc = New(Expiration(DefaultExpiration), HardCacheSize(1), EvictionCallback(func(k string, _ interface{}) {
+		// Would deadlock if the callback ran while SetIfVersion held the
+		// lock.
+		tc.SetIfVersion("other", 1, 1, DefaultExpiration)
+		called = true
+	}))
+	tc.Set("a", 1, DefaultExpiration)
+	if err := tc.SetIfVersion("b", 2, 0, DefaultExpiration); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("eviction callback not called")
+	}
+}
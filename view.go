@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"time"
+)
+
+// A Snapshot is a read-only copy of the items in a cache, taken by
+// Cache.Snapshot. It never changes, so it can be read without any locking,
+// e.g. by a long analytics scan, without contending with (or observing) the
+// changes made to the cache in the meantime. It is safe for concurrent use.
+//
+// A Snapshot is not copy-on-write: it holds a map of its own with an entry
+// for every item, so it costs as much memory as the cache's index of its
+// items, until it is no longer referenced.
+type Snapshot struct {
+	items map[string]Item
+	now   int64
+}
+
+// Returns a Snapshot of the unexpired items in the cache. Like Items, it is
+// built in a single pass over the cache: items set or deleted during the pass
+// may or may not be included (unless AtomicSnapshots is enabled), but every
+// item is included whole, as it was at some point during the pass.
+// Expiration in the snapshot is judged by the time the snapshot was taken.
+//
+// Taking a snapshot copies every item, so it takes time and memory in
+// proportion to the number of items in the cache, and with AtomicSnapshots
+// writers are held off while the items are copied. Prefer Range, Get or Peek
+// for reads that don't need a consistent view of many items.
+//
+// The snapshot shares the values of the items with the cache, so values that
+// are modified in place (rather than replaced with Set) will be seen to change.
+func (c *cache) Snapshot() *Snapshot {
+	now := c.now().UnixNano()
+	m := make(map[string]Item, c.itemCount())
+	c.rangeItems(func(k string, v Item) bool {
+		// "Inlining" of Expired
+		if v.Expiration > 0 && now > v.Expiration {
+			return true
+		}
+		m[k] = v
+		return true
+	})
+	return &Snapshot{items: m, now: now}
+}
+
+// Returns the value of the item with key k, and whether it was found.
+func (s *Snapshot) Get(k string) (interface{}, bool) {
+	v, found := s.items[k]
+	if !found {
+		return nil, false
+	}
+	return v.Object, true
+}
+
+// Returns the item with key k, and whether it was found.
+func (s *Snapshot) GetItem(k string) (Item, bool) {
+	v, found := s.items[k]
+	return v, found
+}
+
+// Calls fn for every item in the snapshot, in no particular order, until fn
+// returns false.
+func (s *Snapshot) Range(fn func(k string, v interface{}, exp time.Time) bool) {
+	for k, v := range s.items {
+		var exp time.Time
+		if v.Expiration > 0 {
+			exp = time.Unix(0, v.Expiration)
+		}
+		if !fn(k, v.Object, exp) {
+			return
+		}
+	}
+}
+
+// Returns a copy of the items in the snapshot.
+func (s *Snapshot) Items() map[string]Item {
+	m := make(map[string]Item, len(s.items))
+	for k, v := range s.items {
+		m[k] = v
+	}
+	return m
+}
+
+// Returns the number of items in the snapshot.
+func (s *Snapshot) Len() int {
+	return len(s.items)
+}
+
+// Returns the time at which the snapshot was taken, according to the cache's
+// clock.
+func (s *Snapshot) Time() time.Time {
+	return time.Unix(0, s.now)
+}
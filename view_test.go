@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	tc := New(Expiration(DefaultExpiration), WithClock(clock))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, time.Second)
+	tc.Set("c", 3, time.Nanosecond)
+	clock.Advance(time.Millisecond)
+
+	s := tc.Snapshot()
+	tc.Set("a", 10, DefaultExpiration)
+	tc.Delete("b")
+	tc.Set("d", 4, DefaultExpiration)
+
+	if x, found := s.Get("a"); !found || x != 1 {
+		t.Error("a is", x, "in the snapshot")
+	}
+	if _, found := s.Get("b"); !found {
+		t.Error("b was deleted from the snapshot")
+	}
+	if _, found := s.Get("c"); found {
+		t.Error("the expired item c is in the snapshot")
+	}
+	if _, found := s.Get("d"); found {
+		t.Error("d was added to the snapshot")
+	}
+	if s.Len() != 2 || len(s.Items()) != 2 {
+		t.Error("the snapshot has", s.Len(), "items instead of 2")
+	}
+	if !s.Time().Equal(time.Unix(0, int64(time.Millisecond))) {
+		t.Error("the snapshot was taken at", s.Time())
+	}
+	if item, _ := s.GetItem("b"); item.Expiration != int64(time.Second) {
+		t.Error("b expires at", item.Expiration)
+	}
+
+	n := 0
+	s.Range(func(string, interface{}, time.Time) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Error("Range did not stop after fn returned false")
+	}
+}
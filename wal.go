@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record every change to the cache in a write-ahead log next to the
+// PersistPath snapshot, which is replayed by New after loading the snapshot.
+// The log is flushed to disk every syncInterval, or after every change if
+// syncInterval is 0, so a crash loses at most syncInterval's worth of writes.
+//
+// The log is compacted whenever a snapshot is written, i.e. every
+// PersistInterval, on Close, and when the cache is created.
+func WAL(syncInterval time.Duration) CacheOption {
+	return func(m *CacheOptions) error {
+		m.WAL = true
+		m.WALSyncInterval = syncInterval
+		return nil
+	}
+}
+
+const (
+	walSet uint8 = iota + 1
+	walDelete
+	walFlush
+)
+
+type walRecord struct {
+	Op   uint8
+	Key  string
+	Item Item
+}
+
+// A log file holds one codec stream, written by a single Encoder, split into
+// frames of one record each. Every frame is preceded by its length and CRC32
+// as big-endian uint32s, so that a record torn by a crash is detected and
+// dropped on replay.
+type wal struct {
+	path   string
+	codec  Codec
+	sync   bool
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	frame  bytes.Buffer
+	enc    Encoder
+	ticker Ticker
+	stop   chan bool
+	exited chan struct{} // closed when Run returns
+}
+
+func (c *cache) walPath() string {
+	return c.PersistPath + ".wal"
+}
+
+// Replay any logs left by a previous process, compact them into a fresh
+// snapshot, and start a new log.
+func openWAL(c *cache) error {
+	l := &wal{
+		path:  c.walPath(),
+		codec: c.codec(),
+		sync:  c.WALSyncInterval == 0,
+	}
+	for _, p := range []string{l.path + ".old", l.path} {
+		if err := c.replayWAL(p); err != nil {
+			return err
+		}
+	}
+	if err := c.saveFileAtomic(c.PersistPath); err != nil {
+		return err
+	}
+	os.Remove(l.path + ".old")
+	if err := l.open(os.O_TRUNC); err != nil {
+		return err
+	}
+	c.wal = l
+	c.events.pin()
+	if c.WALSyncInterval > 0 {
+		l.ticker = c.newTicker(c.WALSyncInterval)
+		l.stop = make(chan bool)
+		l.exited = make(chan struct{})
+		go l.Run()
+	}
+	return nil
+}
+
+// Start writing a new log file. Must be called with l.mu held, or before l
+// is in use.
+func (l *wal) open(flag int) error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|flag, 0644)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.w = bufio.NewWriter(f)
+	l.frame.Reset()
+	l.enc = l.codec.NewEncoder(&l.frame)
+	return nil
+}
+
+func (l *wal) Run() {
+	defer close(l.exited)
+	for {
+		select {
+		case <-l.ticker.C():
+			l.mu.Lock()
+			l.flush()
+			l.mu.Unlock()
+		case <-l.stop:
+			l.ticker.Stop()
+			return
+		}
+	}
+}
+
+// Must be called with l.mu held.
+func (l *wal) flush() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func (l *wal) log(c *cache, typ EventType, k string) {
+	rec := walRecord{Op: walDelete, Key: k}
+	if typ == EventSet {
+		v, found := c.items.Load(k)
+		if !found {
+			return
+		}
+		rec.Op = walSet
+		rec.Item = itemOf(v)
+	}
+	l.append(rec)
+}
+
+func (l *wal) append(rec walRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return
+	}
+	// Errors are not reported to the caller making the change: the cache
+	// itself is still correct. As the encoder's state may no longer match
+	// what was written, logging stops until the next snapshot starts a new
+	// log.
+	var err error
+	func() {
+		defer func() {
+			if x := recover(); x != nil {
+				err = fmt.Errorf("%v", x)
+			}
+		}()
+		err = l.enc.Encode(rec)
+	}()
+	if err != nil {
+		l.flush()
+		l.f.Close()
+		l.f = nil
+		return
+	}
+	var h [8]byte
+	binary.BigEndian.PutUint32(h[:4], uint32(l.frame.Len()))
+	binary.BigEndian.PutUint32(h[4:], crc32.ChecksumIEEE(l.frame.Bytes()))
+	l.w.Write(h[:])
+	l.w.Write(l.frame.Bytes())
+	l.frame.Reset()
+	if l.sync {
+		l.flush()
+	}
+}
+
+// Start a new log before a snapshot is taken, keeping the current one as
+// .old until the snapshot has been written. If an .old log is left over from
+// a failed snapshot, the current log continues instead, as the .old log
+// would otherwise lose the changes it holds.
+func (l *wal) rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := os.Stat(l.path + ".old"); err == nil {
+		return nil
+	}
+	if l.f != nil {
+		if err := l.flush(); err != nil {
+			return err
+		}
+		l.f.Close()
+	}
+	if err := os.Rename(l.path, l.path+".old"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.f = nil
+		return err
+	}
+	return l.open(os.O_TRUNC)
+}
+
+func (l *wal) close() error {
+	if l.stop != nil {
+		close(l.stop)
+		<-l.exited
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.flush()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// Apply the records in the log at path, up to the first torn or corrupt one.
+func (c *cache) replayWAL(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := c.codec().NewDecoder(&frameReader{r: bufio.NewReader(f)})
+	for {
+		var rec walRecord
+		if err := dec.Decode(&rec); err != nil {
+			// The end of the log, or a record torn by a crash.
+			return nil
+		}
+		switch rec.Op {
+		case walSet:
+			rec.Item.Version = c.nextVersion()
+			c.store(rec.Key, rec.Item)
+		case walDelete:
+			c.remove(rec.Key)
+		case walFlush:
+			c.items.Range(func(key, value interface{}) bool {
+				c.remove(key.(string))
+				return true
+			})
+		}
+	}
+}
+
+// A frameReader returns the payloads of a log's frames, and io.EOF at the
+// first missing, torn or corrupt frame.
+type frameReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (fr *frameReader) Read(p []byte) (int, error) {
+	for len(fr.buf) == 0 {
+		var h [8]byte
+		if _, err := io.ReadFull(fr.r, h[:]); err != nil {
+			return 0, io.EOF
+		}
+		n := binary.BigEndian.Uint32(h[:4])
+		if n > snapshotMaxChunk {
+			return 0, io.EOF
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(fr.r, buf); err != nil {
+			return 0, io.EOF
+		}
+		if crc32.ChecksumIEEE(buf) != binary.BigEndian.Uint32(h[4:]) {
+			return 0, io.EOF
+		}
+		fr.buf = buf
+	}
+	n := copy(p, fr.buf)
+	fr.buf = fr.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Simulate a crash by stopping the cache's goroutines and releasing the log
+// without writing a snapshot.
+func crash(tc *Cache) {
+	tc.wal.mu.Lock()
+	tc.wal.flush()
+	tc.wal.f.Close()
+	tc.wal.f = nil
+	tc.wal.mu.Unlock()
+	stopBackground(tc)
+}
+
+func TestWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	tc := New(Expiration(DefaultExpiration), PersistPath(path), WAL(0))
+	if tc == nil {
+		t.Fatal("Couldn't create a cache with a WAL")
+	}
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, time.Hour)
+	tc.Set("c", 3, DefaultExpiration)
+	tc.IncrementInt("a", 10)
+	tc.Delete("c")
+	crash(tc)
+
+	oc := New(Expiration(DefaultExpiration), PersistPath(path), WAL(0))
+	if x, _ := oc.Get("a"); x != 11 {
+		t.Error("a was not replayed:", x)
+	}
+	if _, exp, _ := oc.GetWithExpiration("b"); exp.IsZero() {
+		t.Error("b was replayed without its expiration")
+	}
+	if _, found := oc.Get("c"); found {
+		t.Error("Delete of c was not replayed")
+	}
+
+	// New compacts the log into the snapshot.
+	if fi, err := os.Stat(path + ".wal"); err != nil || fi.Size() != 0 {
+		t.Error("Log was not compacted on startup:", fi, err)
+	}
+
+	oc.Set("d", 4, DefaultExpiration)
+	oc.Flush()
+	oc.Set("e", 5, DefaultExpiration)
+	crash(oc)
+
+	oc = New(Expiration(DefaultExpiration), PersistPath(path), WAL(0))
+	defer oc.Close()
+	if n := oc.ItemCount(); n != 1 {
+		t.Error("Flush was not replayed, items:", oc.Items())
+	}
+}
+
+func TestWALTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	tc := New(PersistPath(path), WAL(0))
+	tc.Set("a", 1, DefaultExpiration)
+	tc.Set("b", 2, DefaultExpiration)
+	crash(tc)
+
+	fi, _ := os.Stat(path + ".wal")
+	os.Truncate(path+".wal", fi.Size()-1)
+	oc := New(PersistPath(path), WAL(0))
+	defer oc.Close()
+	if _, found := oc.Get("a"); !found {
+		t.Error("Record before the torn one was not replayed")
+	}
+	if _, found := oc.Get("b"); found {
+		t.Error("Torn record was replayed")
+	}
+}
+
+func TestWALCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	tc := New(PersistPath(path), WAL(time.Hour))
+	tc.Set("a", 1, DefaultExpiration)
+	if err := tc.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".wal.old"); err == nil {
+		t.Error("Old log was not removed after the snapshot")
+	}
+	tc.Set("b", 2, DefaultExpiration)
+	if err := tc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	oc := New(PersistPath(path), WAL(time.Hour))
+	defer oc.Close()
+	if n := oc.ItemCount(); n != 2 {
+		t.Error("Expected 2 items after a restart, got", n)
+	}
+	if _, err := NewWithError(WAL(0)); err == nil {
+		t.Error("Expected an error for WAL without PersistPath")
+	}
+}